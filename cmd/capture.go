@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/stegmannb/usbtree/internal/capture"
+)
+
+var captureCmd = &cobra.Command{
+	Use:   "capture <bundle.tar.gz|->",
+	Short: "Collect a support bundle for bug reports",
+	Long: `Collect every input the detectors read on this machine into a gzipped
+tar archive: sysfs attributes and descriptors of all USB devices and ports,
+the output of lsusb, lsusb -t and lsusb -v (or system_profiler on macOS),
+udev properties and the kernel version.
+
+The bundle is a fixture root and can be replayed exactly as it was captured:
+
+  usbtree --input bundle.tar.gz -v
+
+With --anonymize, serial numbers and location IDs are replaced by
+pseudonyms throughout the bundle.`,
+	Example: `  usbtree capture bundle.tar.gz
+  sudo usbtree capture --anonymize bundle.tar.gz`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var w io.Writer = os.Stdout
+		if args[0] != "-" {
+			file, err := os.Create(args[0])
+			if err != nil {
+				return err
+			}
+			defer file.Close()
+			w = file
+		}
+
+		opts := capture.Options{
+			Version:    version,
+			Anonymizer: anonymizer,
+		}
+		if err := capture.Write(w, opts); err != nil {
+			return fmt.Errorf("failed to capture: %w", err)
+		}
+
+		if args[0] != "-" {
+			fmt.Fprintf(os.Stderr, "Wrote %s\n", args[0])
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(captureCmd)
+}
@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/stegmannb/usbtree/internal/config"
+)
+
+var (
+	configPath  string
+	profileName string
+)
+
+// unconfigurable are the flags the configuration cannot set.
+var unconfigurable = map[string]bool{"config": true, "profile": true, "help": true, "version": true}
+
+func init() {
+	rootCmd.PersistentFlags().StringVar(&configPath, "config", "", "Configuration file (default $XDG_CONFIG_HOME/usbtree/config.yaml)")
+	rootCmd.PersistentFlags().StringVar(&profileName, "profile", "", "Profile of the configuration file to use")
+}
+
+// loadConfig reads the configuration file named by --config or
+// USBTREE_CONFIG, or the default one if it exists, and sets the flags of
+// cmd that were not given on the command line. Environment variables take
+// precedence over the profile, which takes precedence over the defaults.
+func loadConfig(cmd *cobra.Command) error {
+	path, required := configPath, configPath != ""
+	if !required {
+		path = os.Getenv(config.EnvPrefix + "CONFIG")
+		required = path != ""
+	}
+	if !required {
+		var err error
+		if path, err = config.DefaultPath(); err != nil {
+			return err
+		}
+	}
+
+	var err error
+	if cfg, err = config.Load(path, required); err != nil {
+		return err
+	}
+	known := func(command, flag string) bool {
+		return knownFlag(cmd.Root(), command, flag)
+	}
+	if err := cfg.Check(known); err != nil {
+		return fmt.Errorf("invalid configuration %s: %w", path, err)
+	}
+
+	profile := profileName
+	if profile == "" {
+		profile = os.Getenv(config.EnvPrefix + "PROFILE")
+	}
+	values, err := cfg.Values(cmd.Name(), profile)
+	if err != nil {
+		return fmt.Errorf("invalid --profile: %w", err)
+	}
+
+	cmd.Flags().VisitAll(func(flag *pflag.Flag) {
+		if err != nil || flag.Changed || unconfigurable[flag.Name] {
+			return
+		}
+		value, source, ok := configValue(cmd, flag.Name, values, path)
+		if !ok {
+			return
+		}
+		if setErr := cmd.Flags().Set(flag.Name, value); setErr != nil {
+			err = fmt.Errorf("invalid value %q for --%s from %s: %w", value, flag.Name, source, setErr)
+		}
+	})
+	return err
+}
+
+// configValue looks up the value of a flag in the environment, first for
+// the command and then for every command, and in the configuration file.
+func configValue(cmd *cobra.Command, flag string, values map[string]string, path string) (value, source string, ok bool) {
+	var names []string
+	if cmd.HasParent() {
+		names = append(names, config.EnvName(cmd.Name(), flag))
+	}
+	for _, name := range append(names, config.EnvName("", flag)) {
+		if value, ok := os.LookupEnv(name); ok {
+			return value, name, true
+		}
+	}
+	value, ok = values[flag]
+	return value, path, ok
+}
+
+// knownFlag reports whether a command below root has a flag. With an empty
+// command it reports whether any command has it, with an empty flag whether
+// the command exists.
+func knownFlag(root *cobra.Command, command, flag string) bool {
+	commands := append([]*cobra.Command{root}, root.Commands()...)
+	for _, c := range commands {
+		if command != "" && c.Name() != command {
+			continue
+		}
+		if flag == "" {
+			return true
+		}
+		if unconfigurable[flag] {
+			return false
+		}
+		if c.Flags().Lookup(flag) != nil || c.InheritedFlags().Lookup(flag) != nil {
+			return true
+		}
+	}
+	return false
+}
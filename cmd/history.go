@@ -0,0 +1,165 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/stegmannb/usbtree/internal/history"
+	"github.com/stegmannb/usbtree/internal/models"
+)
+
+var (
+	historySince string
+	historyUntil string
+	historyStats bool
+	historyJSON  bool
+)
+
+var historyCmd = &cobra.Command{
+	Use:   "history [selector]",
+	Short: "Show hotplug events logged by usbtree record",
+	Long: `Show the events usbtree record logged, optionally only those of the devices
+matching a selector (1-2.3, 0403:6001, serial=..., port=...) and within a
+time range.
+
+--since and --until take a duration before now (24h), a date (2024-05-01),
+a local date and time (2024-05-01 08:00) or RFC 3339.
+
+With --stats, summarize every device instead: how often it was attached
+and detached, how often it reconnected after a detach (flaps), and how long
+it was connected within the range.`,
+	Example: `  usbtree history --since 24h
+  usbtree history serial=A50285BI --stats
+  usbtree history 1-2.3 --since "2024-05-01 18:00" --until "2024-05-02 08:00"`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		now := time.Now()
+		var filter history.Filter
+		if len(args) > 0 {
+			sel, err := models.ParseSelector(args[0])
+			if err != nil {
+				return err
+			}
+			filter.Selector = &sel
+		}
+		var err error
+		if historySince != "" {
+			if filter.Since, err = history.ParseTime(historySince, now); err != nil {
+				return fmt.Errorf("invalid --since: %w", err)
+			}
+		}
+		if historyUntil != "" {
+			if filter.Until, err = history.ParseTime(historyUntil, now); err != nil {
+				return fmt.Errorf("invalid --until: %w", err)
+			}
+		}
+
+		path, err := resolveEventLog()
+		if err != nil {
+			return err
+		}
+		records, err := history.ReadLog(path)
+		if err != nil {
+			return err
+		}
+
+		if historyStats {
+			stats, window := history.Summarize(records, filter, now)
+			if historyJSON {
+				return writeHistoryJSON(stats)
+			}
+			printHistoryStats(stats, window)
+			return nil
+		}
+
+		matched := []history.Record{}
+		for _, record := range records {
+			if filter.Matches(record) {
+				matched = append(matched, record)
+			}
+		}
+		if historyJSON {
+			return writeHistoryJSON(matched)
+		}
+		if len(matched) == 0 {
+			fmt.Println("No events found")
+		}
+		for _, record := range matched {
+			fmt.Println(formatRecord(record))
+		}
+		return nil
+	},
+}
+
+func init() {
+	historyCmd.Flags().StringVar(&eventLogPath, "log", "", "Event log to read (default $XDG_STATE_HOME/usbtree/events.jsonl)")
+	historyCmd.Flags().StringVar(&historySince, "since", "", "Show events from this time on")
+	historyCmd.Flags().StringVar(&historyUntil, "until", "", "Show events before this time")
+	historyCmd.Flags().BoolVar(&historyStats, "stats", false, "Show attach, detach and flap counts and uptime per device")
+	historyCmd.Flags().BoolVar(&historyJSON, "json", false, "Output in JSON format")
+	rootCmd.AddCommand(historyCmd)
+}
+
+func writeHistoryJSON(v any) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+// recordName describes the device of a record as the tree does.
+func recordName(record history.Record) string {
+	name := record.Device().GetDisplayName()
+	name += fmt.Sprintf(" [%04x:%04x]", record.VendorID, record.ProductID)
+	if record.Serial != "" {
+		name += " " + record.Serial
+	}
+	return name
+}
+
+// formatRecord formats a record as one line of usbtree record and history.
+func formatRecord(record history.Record) string {
+	line := fmt.Sprintf("%s  %-7s  %-8s  %s", record.Time.Local().Format("2006-01-02 15:04:05"),
+		record.Action, record.PortPath, recordName(record))
+	if record.Speed != "" {
+		line += "  " + record.Speed
+	}
+	if len(record.Drivers) > 0 {
+		line += "  " + strings.Join(record.Drivers, ",")
+	}
+	if len(record.Changes) > 0 {
+		line += "  (changed " + strings.Join(record.Changes, ", ") + ")"
+	}
+	return line
+}
+
+func printHistoryStats(stats []*history.Stats, window time.Duration) {
+	if len(stats) == 0 {
+		fmt.Println("No events found")
+		return
+	}
+	fmt.Printf("%-48s  %-8s  %6s  %6s  %5s  %s\n", "DEVICE", "PORT", "ATTACH", "DETACH", "FLAPS", "UPTIME")
+	for _, s := range stats {
+		uptime := s.Uptime.Round(time.Second).String()
+		if window > 0 {
+			uptime = fmt.Sprintf("%5.1f%% (%s)", 100*float64(s.Uptime)/float64(window), uptime)
+		}
+		state := ""
+		if !s.Connected {
+			state = "  disconnected"
+		}
+		fmt.Printf("%-48s  %-8s  %6d  %6d  %5d  %s%s\n", truncateName(recordName(s.Last), 48), s.Last.PortPath,
+			s.Attaches, s.Detaches, s.Flaps, uptime, state)
+	}
+}
+
+// truncateName shortens a name to width characters.
+func truncateName(name string, width int) string {
+	if runes := []rune(name); len(runes) > width {
+		return string(runes[:width-1]) + "…"
+	}
+	return name
+}
@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/stegmannb/usbtree/internal/models"
+	"github.com/stegmannb/usbtree/internal/usb"
+)
+
+var (
+	autosuspend string
+	wakeup      string
+	powerAll    bool
+)
+
+var powerCmd = &cobra.Command{
+	Use:   "power <selector>",
+	Short: "Show or change runtime power management of devices",
+	Long: `Show the runtime power management state of the devices matched by the
+selector, or change it with --autosuspend and --wakeup.
+
+A selector is a port path (1-2.3), a vendor:product ID (0403:6001), or one
+of port=, id=, serial= or name= followed by a value.
+
+Changing power settings requires write access to sysfs (usually root). A
+selector matching several devices changes them only with --all.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		sel, err := models.ParseSelector(args[0])
+		if err != nil {
+			return err
+		}
+
+		if inputFile != "" && (autosuspend != "" || wakeup != "") {
+			return fmt.Errorf("cannot change power settings of devices read from --input")
+		}
+
+		switch autosuspend {
+		case "", "on", "off":
+		default:
+			if delay, err := strconv.Atoi(autosuspend); err != nil || delay < 0 {
+				return fmt.Errorf("invalid --autosuspend value %q: expected on, off or a delay in milliseconds", autosuspend)
+			}
+		}
+
+		var enableWakeup bool
+		switch wakeup {
+		case "", "on", "off":
+			enableWakeup = wakeup == "on"
+		default:
+			return fmt.Errorf("invalid --wakeup value %q: expected on or off", wakeup)
+		}
+
+		topology, err := getTopology()
+		if err != nil {
+			return err
+		}
+
+		matched := topology.Find(sel.Matches)
+		if len(matched) == 0 {
+			return fmt.Errorf("no device matches %s", sel)
+		}
+		if len(matched) > 1 && !powerAll && (autosuspend != "" || wakeup != "") {
+			ports := make([]string, len(matched))
+			for i, device := range matched {
+				ports[i] = device.PortPath
+			}
+			return fmt.Errorf("%s matches %d devices (%s): use --all to change them all", sel, len(matched), strings.Join(ports, ", "))
+		}
+
+		sysfs := usb.NewSysfs(usb.DefaultSysfsRoot)
+		for _, device := range matched {
+			if autosuspend != "" {
+				if err := sysfs.SetAutosuspend(device.PortPath, autosuspend); err != nil {
+					return err
+				}
+			}
+			if wakeup != "" {
+				if err := sysfs.SetWakeup(device.PortPath, enableWakeup); err != nil {
+					return err
+				}
+			}
+
+			power := device.Power
+			if inputFile == "" {
+				if power, err = sysfs.ReadPowerState(device.PortPath); err != nil {
+					return err
+				}
+			}
+			if power == nil {
+				return fmt.Errorf("no power state known for device %s", device.PortPath)
+			}
+			printPowerState(device, power)
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	powerCmd.Flags().StringVar(&autosuspend, "autosuspend", "", "Set autosuspend: on, off or a delay in milliseconds")
+	powerCmd.Flags().StringVar(&wakeup, "wakeup", "", "Set remote wakeup: on or off")
+	powerCmd.Flags().BoolVar(&powerAll, "all", false, "Change every matching device, not just a single match")
+	rootCmd.AddCommand(powerCmd)
+}
+
+func printPowerState(device *models.USBDevice, power *models.PowerState) {
+	fmt.Printf("%s %s [%s]\n", device.PortPath, device.GetDisplayName(), device.GetIDString())
+	fmt.Printf("  control:              %s\n", power.Control)
+	fmt.Printf("  runtime_status:       %s\n", power.RuntimeStatus)
+	fmt.Printf("  autosuspend_delay_ms: %d\n", power.AutosuspendDelayMs)
+	fmt.Printf("  runtime_active_time:  %d ms\n", power.ActiveTimeMs)
+	fmt.Printf("  suspended_time:       %d ms\n", power.SuspendedTimeMs)
+	fmt.Printf("  wakeup:               %s\n", power.Wakeup)
+	fmt.Printf("  persist:              %t\n", power.Persist)
+}
@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/stegmannb/usbtree/internal/history"
+	"github.com/stegmannb/usbtree/internal/hotplug"
+)
+
+var (
+	eventLogPath   string
+	recordMaxSize  int
+	recordKeep     int
+	recordInterval time.Duration
+)
+
+var recordCmd = &cobra.Command{
+	Use:   "record",
+	Short: "Log hotplug events to a file for usbtree history",
+	Long: `Append every hotplug event to an event log in JSON Lines, one object per
+line with the time, action (add, remove or change), port path, IDs, names,
+serial number, speed and drivers of the device. Devices connected when
+recording starts are logged as present.
+
+The log is rotated to events.jsonl.1, .2 and so on once it reaches
+--max-size, keeping --keep rotated files. It defaults to
+$XDG_STATE_HOME/usbtree/events.jsonl (~/.local/state/usbtree/events.jsonl).
+
+Run it as a service to catch disconnects nobody is watching for, and query
+the log with usbtree history.`,
+	Example: `  usbtree record
+  usbtree record --log /var/log/usbtree/events.jsonl --max-size 50`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path, err := resolveEventLog()
+		if err != nil {
+			return err
+		}
+		log, err := history.OpenLog(path)
+		if err != nil {
+			return err
+		}
+		defer log.Close()
+		log.MaxSize = int64(recordMaxSize) << 20
+		log.Keep = recordKeep
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		fmt.Fprintf(os.Stderr, "Recording to %s\n", path)
+		for update := range hotplug.Watch(ctx, getTopology, hotplug.Options{Interval: recordInterval}) {
+			if update.Err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: %v\n", update.Err)
+				continue
+			}
+			events := update.Events
+			if events == nil {
+				// The first update: log what is connected already
+				events = hotplug.Diff(nil, update.Topology, update.Time)
+				for i := range events {
+					events[i].Action = history.ActionPresent
+				}
+			}
+			for _, event := range events {
+				record := history.NewRecord(event)
+				if err := log.Append(record); err != nil {
+					return err
+				}
+				fmt.Println(formatRecord(record))
+			}
+		}
+		return nil
+	},
+}
+
+func init() {
+	recordCmd.Flags().StringVar(&eventLogPath, "log", "", "Event log to append to (default $XDG_STATE_HOME/usbtree/events.jsonl)")
+	recordCmd.Flags().IntVar(&recordMaxSize, "max-size", history.DefaultMaxSize>>20, "Size in MiB at which the log is rotated")
+	recordCmd.Flags().IntVar(&recordKeep, "keep", history.DefaultKeep, "Number of rotated logs to keep")
+	recordCmd.Flags().DurationVar(&recordInterval, "interval", hotplug.DefaultInterval, "How often to enumerate devices")
+	rootCmd.AddCommand(recordCmd)
+}
+
+// resolveEventLog returns the path given with --log or the default one.
+func resolveEventLog() (string, error) {
+	if eventLogPath != "" {
+		return eventLogPath, nil
+	}
+	return history.DefaultPath()
+}
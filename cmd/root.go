@@ -1,21 +1,45 @@
 package cmd
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"slices"
+	"strings"
 
+	"github.com/fatih/color"
 	"github.com/spf13/cobra"
+	"github.com/stegmannb/usbtree/internal/anonymize"
+	"github.com/stegmannb/usbtree/internal/config"
 	"github.com/stegmannb/usbtree/internal/models"
+	"github.com/stegmannb/usbtree/internal/render"
 	"github.com/stegmannb/usbtree/internal/tree"
 	"github.com/stegmannb/usbtree/internal/usb"
 )
 
 var (
-	jsonOutput bool
-	verbose    bool
-	filter     string
-	version    string = "dev" // Set via ldflags during build
+	jsonOutput      bool
+	jsonFlat        bool
+	outputFormat    string
+	verbose         bool
+	filter          string
+	inputFile       string
+	inputFormat     string
+	anonymizeOutput bool
+	anonymizeSalt   string
+	anonymizeIDs    bool
+	anonymizer      *anonymize.Anonymizer
+	cfg             *config.Config
+	colorMode       string
+	themeName       string
+	treeStyle       string
+	viewDepth       int
+	viewRoot        string
+	hideHubs        bool
+	hideEmptyRoots  bool
+	sortOrder       string
+	version         string = "dev" // Set via ldflags during build
 )
 
 var rootCmd = &cobra.Command{
@@ -24,68 +48,276 @@ var rootCmd = &cobra.Command{
 	Version: version,
 	Long: `USBTree is a cross-platform CLI tool that displays connected USB devices
 in a hierarchical tree structure. It works on both macOS and Linux systems.`,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if err := loadConfig(cmd); err != nil {
+			// The usage does not help with errors in the configuration
+			cmd.SilenceUsage = true
+			return err
+		}
+
+		enabled, err := tree.ColorEnabled(colorMode, !color.NoColor, os.Getenv)
+		if err != nil {
+			return fmt.Errorf("invalid --color: %w", err)
+		}
+		color.NoColor = !enabled
+
+		if !anonymizeOutput && anonymizeSalt == "" && !anonymizeIDs {
+			return nil
+		}
+		anonymizer, err = anonymize.New(anonymizeSalt)
+		if err != nil {
+			return err
+		}
+		anonymizer.IDs = anonymizeIDs
+		return nil
+	},
 	RunE: func(cmd *cobra.Command, args []string) error {
-		detector := usb.NewDetector()
-		
-		devices, err := detector.GetDevices()
+		format, err := resolveFormat()
+		if err != nil {
+			return err
+		}
+		theme, err := tree.LookupTheme(themeName, cfg.Themes)
+		if err != nil {
+			return fmt.Errorf("invalid --theme: %w", err)
+		}
+		style, err := tree.ParseTreeStyle(treeStyle)
+		if err != nil {
+			return fmt.Errorf("invalid --style: %w", err)
+		}
+		view, err := resolveView(format)
 		if err != nil {
-			return fmt.Errorf("failed to get USB devices: %w", err)
+			return err
+		}
+		if !slices.Contains(models.SortOrders, sortOrder) {
+			return fmt.Errorf("invalid --sort %q: expected one of %s", sortOrder, strings.Join(models.SortOrders, ", "))
 		}
 
+		topology, err := getTopology()
+		if err != nil {
+			return err
+		}
+
+		devices := topology.Devices
 		if filter != "" {
-			devices = filterDevices(devices, filter)
+			devices = filterDevices(topology, filter)
+		}
+		devices = view.Apply(devices)
+		if view.Root != nil && len(devices) == 0 {
+			return fmt.Errorf("no device matches --root %s", viewRoot)
+		}
+		if err := models.SortDevices(devices, sortOrder); err != nil {
+			return err
 		}
 
-		if jsonOutput {
-			return outputJSON(devices)
+		if write, ok := lsusbWriters[format]; ok {
+			return write(os.Stdout, devices)
+		}
+		if format != render.FormatTree {
+			return outputReport(format, topology, devices)
 		}
 
 		printer := tree.NewPrinter(verbose)
+		printer.SetTheme(theme)
+		printer.SetStyle(style)
 		printer.Print(devices)
 		
 		return nil
 	},
 }
 
+// exitError makes Execute exit with a code other than 1, for commands
+// whose exit code scripts act on.
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string {
+	return e.err.Error()
+}
+
+func (e *exitError) Unwrap() error {
+	return e.err
+}
+
 func Execute() {
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Fprintln(os.Stderr, err)
+		var exit *exitError
+		if errors.As(err, &exit) {
+			os.Exit(exit.code)
+		}
 		os.Exit(1)
 	}
 }
 
 func init() {
 	rootCmd.Flags().BoolVarP(&jsonOutput, "json", "j", false, "Output in JSON format")
+	rootCmd.Flags().BoolVar(&jsonFlat, "json-flat", false, "Output a flat device list linked by id and parent_id (JSON unless --format is given)")
+	rootCmd.Flags().StringVarP(&outputFormat, "format", "o", render.FormatTree,
+		fmt.Sprintf("Output format (%s)", strings.Join(outputFormats, ", ")))
 	rootCmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "Show detailed device information")
-	rootCmd.Flags().StringVarP(&filter, "filter", "f", "", "Filter devices by vendor name")
+	rootCmd.Flags().StringVarP(&filter, "filter", "f", "", "Filter devices by vendor name, product name or label")
+	rootCmd.Flags().StringVar(&themeName, "theme", "default",
+		fmt.Sprintf("Color theme of the tree (%s, or one from the configuration)", strings.Join(tree.ThemeNames(), ", ")))
+	rootCmd.Flags().StringVar(&treeStyle, "style", tree.StyleUnicode,
+		fmt.Sprintf("How to draw the tree (%s)", strings.Join(tree.TreeStyles, ", ")))
+	rootCmd.Flags().IntVar(&viewDepth, "depth", 0, "Levels of devices to show, 1 for the root hubs only (default all)")
+	rootCmd.Flags().StringVar(&viewRoot, "root", "", "Show only the subtree below a device selector, e.g. a hub's port path")
+	rootCmd.Flags().BoolVar(&hideHubs, "hide-hubs", false, "Leave out hubs below the root hubs and show devices with the hubs they are behind")
+	rootCmd.Flags().BoolVar(&hideEmptyRoots, "hide-empty-root-hubs", false, "Leave out root hubs without devices")
+	rootCmd.Flags().StringVar(&sortOrder, "sort", models.SortPort,
+		fmt.Sprintf("Order of devices on the same hub (%s)", strings.Join(models.SortOrders, ", ")))
+	rootCmd.PersistentFlags().StringVar(&colorMode, "color", tree.ColorAuto,
+		fmt.Sprintf("When to use colors (%s)", strings.Join(tree.ColorModes, ", ")))
+	rootCmd.PersistentFlags().StringVar(&inputFile, "input", "", "Read devices from saved output instead of this machine")
+	rootCmd.PersistentFlags().StringVar(&inputFormat, "input-format", usb.InputAuto,
+		fmt.Sprintf("Format of --input (%s)", strings.Join(usb.InputFormats, ", ")))
+	rootCmd.PersistentFlags().BoolVar(&anonymizeOutput, "anonymize", false, "Replace serial numbers and location IDs with pseudonyms")
+	rootCmd.PersistentFlags().StringVar(&anonymizeSalt, "anonymize-salt", "", "Salt for --anonymize, keeping pseudonyms stable across runs (implies --anonymize)")
+	rootCmd.PersistentFlags().BoolVar(&anonymizeIDs, "anonymize-ids", false, "Also replace vendor and product IDs (implies --anonymize)")
+}
+
+// newDetector returns the detector for this machine, or one reading the
+// file given with --input.
+func newDetector() (usb.Detector, error) {
+	if inputFile == "" {
+		return usb.NewDetector(), nil
+	}
+	if !slices.Contains(usb.InputFormats, inputFormat) {
+		return nil, fmt.Errorf("invalid --input-format %q: expected one of %s", inputFormat, strings.Join(usb.InputFormats, ", "))
+	}
+	return usb.NewFileDetector(inputFile, inputFormat), nil
 }
 
-func filterDevices(devices []*models.USBDevice, filter string) []*models.USBDevice {
+// inputSysfs returns the sysfs to find /dev nodes in: this machine's, or
+// the copy in a capture bundle or fixture root given with --input. It is nil
+// for other input. cleanup removes what was unpacked.
+func inputSysfs() (sysfs *usb.Sysfs, cleanup func(), err error) {
+	if inputFile == "" {
+		return usb.NewSysfs(""), func() {}, nil
+	}
+	return usb.InputSysfs(inputFile, inputFormat)
+}
+
+// getTopology reads the devices from the detector returned by newDetector,
+// labels them from the configuration and anonymizes them when requested.
+func getTopology() (*models.Topology, error) {
+	detector, err := newDetector()
+	if err != nil {
+		return nil, err
+	}
+
+	devices, err := detector.GetDevices()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get USB devices: %w", err)
+	}
+
+	topology := models.NewTopology(devices)
+	topology.Backend = detector.Backend()
+	if cfg != nil {
+		cfg.ApplyLabels(topology)
+	}
+	if anonymizer != nil {
+		anonymizer.Apply(topology)
+	}
+
+	return topology, nil
+}
+
+// filterDevices keeps the root hubs with a device whose vendor name,
+// product name or label equals filter.
+func filterDevices(topology *models.Topology, filter string) []*models.USBDevice {
+	matched := make(map[*models.USBDevice]bool)
+	for _, device := range topology.Find(func(device *models.USBDevice) bool {
+		return device.VendorName == filter || device.ProductName == filter || device.Label == filter
+	}) {
+		matched[topology.Root(device)] = true
+	}
+
 	var filtered []*models.USBDevice
-	for _, device := range devices {
-		if containsFilter(device, filter) {
+	for _, device := range topology.Devices {
+		if matched[device] {
 			filtered = append(filtered, device)
 		}
 	}
 	return filtered
 }
 
-func containsFilter(device *models.USBDevice, filter string) bool {
-	if device.VendorName == filter || device.ProductName == filter {
-		return true
+// lsusbWriters write the formats of lsusb and lsusb -t for scripts parsing
+// lsusb output.
+var lsusbWriters = map[string]func(io.Writer, []*models.USBDevice) error{
+	"lsusb":      usb.WriteLsusb,
+	"lsusb-tree": usb.WriteLsusbTree,
+}
+
+// outputFormats are the values accepted by --format.
+var outputFormats = []string{render.FormatTree, render.FormatJSON, render.FormatYAML, render.FormatXML, render.FormatTOML, render.FormatHTML, render.FormatSVG, "lsusb", "lsusb-tree"}
+
+// resolveFormat combines --format with its --json and --json-flat
+// shorthands, which select JSON unless another report format is given.
+func resolveFormat() (string, error) {
+	if !slices.Contains(outputFormats, outputFormat) {
+		return "", fmt.Errorf("invalid --format %q: expected one of %s", outputFormat, strings.Join(outputFormats, ", "))
+	}
+	if jsonOutput && outputFormat != render.FormatTree && outputFormat != render.FormatJSON {
+		return "", fmt.Errorf("--json conflicts with --format %s", outputFormat)
+	}
+	if _, ok := lsusbWriters[outputFormat]; (ok || outputFormat == render.FormatHTML || outputFormat == render.FormatSVG) && jsonFlat {
+		return "", fmt.Errorf("--json-flat conflicts with --format %s", outputFormat)
 	}
-	
-	for _, child := range device.Children {
-		if containsFilter(child, filter) {
-			return true
+	if (jsonOutput || jsonFlat) && outputFormat == render.FormatTree {
+		return render.FormatJSON, nil
+	}
+	return outputFormat, nil
+}
+
+// resolveView returns the part of the tree --depth, --root, --hide-hubs
+// and --hide-empty-root-hubs select.
+func resolveView(format string) (tree.View, error) {
+	view := tree.View{Depth: viewDepth, HideHubs: hideHubs, HideEmptyRootHubs: hideEmptyRoots}
+	if viewDepth < 0 {
+		return view, fmt.Errorf("invalid --depth %d: must not be negative", viewDepth)
+	}
+	if hideHubs && format != render.FormatTree && format != render.FormatHTML {
+		return view, fmt.Errorf("--hide-hubs conflicts with --format %s", format)
+	}
+	if viewRoot != "" {
+		sel, err := models.ParseSelector(viewRoot)
+		if err != nil {
+			return view, fmt.Errorf("invalid --root: %w", err)
 		}
+		view.Root = &sel
 	}
-	
-	return false
+	return view, nil
 }
 
-func outputJSON(devices []*models.USBDevice) error {
-	encoder := json.NewEncoder(os.Stdout)
-	encoder.SetIndent("", "  ")
-	return encoder.Encode(devices)
-}
\ No newline at end of file
+// outputReport writes devices, the possibly filtered root hubs of topology,
+// as a versioned report in the given format.
+func outputReport(format string, topology *models.Topology, devices []*models.USBDevice) error {
+	// The host name identifies the machine as much as a serial number does
+	hostname, _ := os.Hostname()
+	if anonymizer != nil {
+		hostname = ""
+	}
+	info := models.NewReportInfo(topology.Backend, hostname)
+
+	if devices == nil {
+		devices = []*models.USBDevice{}
+	}
+
+	switch format {
+	case render.FormatHTML:
+		return render.WriteHTML(os.Stdout, info, &models.Topology{Devices: devices})
+	case render.FormatSVG:
+		return render.WriteSVG(os.Stdout, &models.Topology{Devices: devices})
+	}
+	if jsonFlat {
+		flat := models.Flatten(&models.Topology{Devices: devices})
+		if flat == nil {
+			flat = []models.FlatDevice{}
+		}
+		return render.Write(os.Stdout, format, models.FlatReport{ReportInfo: info, Devices: flat})
+	}
+	return render.Write(os.Stdout, format, models.Report{ReportInfo: info, Devices: devices})
+}
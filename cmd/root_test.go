@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"strings"
+	"testing"
+)
+
+// runRoot runs usbtree with args on the captured lsusb -t output and returns
+// what it writes to stdout.
+func runRoot(t *testing.T, args ...string) string {
+	t.Helper()
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+
+	rootCmd.SetArgs(append([]string{"--input", "../internal/usb/testdata/lsusb-t.txt"}, args...))
+	runErr := rootCmd.Execute()
+	w.Close()
+
+	var buf bytes.Buffer
+	io.Copy(&buf, r)
+	if runErr != nil {
+		t.Fatalf("usbtree %s: %v", strings.Join(args, " "), runErr)
+	}
+	return buf.String()
+}
+
+func TestRoot_HTML(t *testing.T) {
+	output := runRoot(t, "--format", "html")
+	if strings.Contains(output, `href="#"`) {
+		t.Error("Expected every link to point to a device")
+	}
+	if !strings.Contains(output, `<tr><th>Connected to</th><td><a href="#device-`) {
+		t.Error("Expected links to the hubs devices are connected to")
+	}
+
+	output = runRoot(t, "--format", "html", "--hide-hubs")
+	if !strings.Contains(output, "<tr><th>Via</th><td>Unknown Device</td></tr>") {
+		t.Errorf("Expected the hidden hub in the report:\n%s", output)
+	}
+}
@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/stegmannb/usbtree/internal/models"
+)
+
+var schemaFlat bool
+
+var schemaCmd = &cobra.Command{
+	Use:   "schema",
+	Short: "Print the JSON Schema of the --json output",
+	Long: `Print the JSON Schema describing the output of --json, or of --json-flat
+with --flat. The schema is generated from the same types the output is
+written from.
+
+The schema_version field of the output is only increased when fields are
+renamed or removed or change their meaning. New fields may be added at any
+time.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		schema, err := models.ReportSchema(schemaFlat)
+		if err != nil {
+			return err
+		}
+		_, err = os.Stdout.Write(schema)
+		return err
+	},
+}
+
+func init() {
+	schemaCmd.Flags().BoolVar(&schemaFlat, "flat", false, "Print the schema of --json-flat")
+	rootCmd.AddCommand(schemaCmd)
+}
@@ -0,0 +1,161 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/stegmannb/usbtree/internal/api"
+	"github.com/stegmannb/usbtree/internal/hotplug"
+	"github.com/stegmannb/usbtree/internal/metrics"
+	"github.com/stegmannb/usbtree/internal/models"
+	"github.com/stegmannb/usbtree/internal/usb"
+)
+
+var (
+	serveMetrics  string
+	serveListen   string
+	serveInterval time.Duration
+)
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve devices over HTTP as JSON or Prometheus metrics",
+	Long: `Serve the connected devices over HTTP, as a JSON API with --listen and as
+Prometheus metrics with --metrics. Both may be given, also with the same
+address.
+
+The JSON API (--listen unix:/run/usbtree.sock or --listen :8080):
+
+  GET /devices             the report of usbtree --json (?flat=true for
+                           --json-flat)
+  GET /devices/{portpath}  one device with the devices below it
+  GET /events              hotplug events as server-sent events
+  GET /health              whether devices were enumerated successfully
+
+Prometheus metrics on /metrics (--metrics :9108):
+
+  usb_device_present               1 while a device is connected, 0 once a
+                                   device seen before is gone
+  usb_device_speed_bps             negotiated link speed
+  usb_device_max_power_milliamps   declared maximum power
+  usb_hub_port_over_current_total  over-current conditions per hub port
+                                   (Linux)
+  usb_device_connect_events_total  devices attached and detached since start
+
+The device tree is cached and enumerated again on kernel uevents (Linux)
+and every --interval.`,
+	Example: `  usbtree serve --listen unix:/run/usbtree.sock
+  usbtree serve --listen localhost:8080 --metrics localhost:8080
+  usbtree serve --metrics :9108 --interval 10s`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if serveMetrics == "" && serveListen == "" {
+			return fmt.Errorf("--listen or --metrics is required")
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		hostname, _ := os.Hostname()
+		if anonymizer != nil {
+			hostname = ""
+		}
+		apiServer := api.NewServer(hostname)
+
+		var overCurrent func(*models.USBDevice) map[int]int
+		if inputFile == "" {
+			sysfs := usb.NewSysfs("")
+			overCurrent = func(hub *models.USBDevice) map[int]int {
+				return sysfs.OverCurrentCounts(hub.PortPath)
+			}
+		}
+		exporter := metrics.NewExporter(overCurrent)
+
+		// One server per address, with the endpoints of every flag naming it
+		muxes := make(map[string]*http.ServeMux)
+		var addresses []string
+		mux := func(address string) *http.ServeMux {
+			if muxes[address] == nil {
+				muxes[address] = http.NewServeMux()
+				addresses = append(addresses, address)
+			}
+			return muxes[address]
+		}
+		if serveListen != "" {
+			mux(serveListen).Handle("/", apiServer.Handler())
+		}
+		if serveMetrics != "" {
+			mux(serveMetrics).Handle("GET /metrics", exporter)
+		}
+
+		var listeners []net.Listener
+		for _, address := range addresses {
+			listener, err := api.Listen(address)
+			if err != nil {
+				for _, l := range listeners {
+					l.Close()
+				}
+				return err
+			}
+			listeners = append(listeners, listener)
+		}
+
+		updates := hotplug.Watch(ctx, getTopology, hotplug.Options{Interval: serveInterval})
+		go func() {
+			for update := range updates {
+				if update.Err != nil {
+					fmt.Fprintf(os.Stderr, "Warning: %v\n", update.Err)
+				}
+				apiServer.Update(update)
+				exporter.Update(update)
+			}
+		}()
+
+		errs := make(chan error, len(listeners))
+		var servers []*http.Server
+		for i, listener := range listeners {
+			server := &http.Server{
+				Handler:           muxes[addresses[i]],
+				ReadHeaderTimeout: 10 * time.Second,
+				// Ends event streams on shutdown
+				BaseContext: func(net.Listener) context.Context { return ctx },
+			}
+			servers = append(servers, server)
+			fmt.Fprintf(os.Stderr, "Listening on %s\n", addresses[i])
+			go func() {
+				errs <- server.Serve(listener)
+			}()
+		}
+
+		var err error
+		select {
+		case <-ctx.Done():
+		case err = <-errs:
+		}
+
+		shutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		for _, server := range servers {
+			server.Shutdown(shutdown)
+		}
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+		return nil
+	},
+}
+
+func init() {
+	serveCmd.Flags().StringVar(&serveListen, "listen", "", "Address to serve the JSON API on: unix:/path/to.sock or host:port")
+	serveCmd.Flags().StringVar(&serveMetrics, "metrics", "", "Address to serve Prometheus metrics on, e.g. :9108")
+	serveCmd.Flags().DurationVar(&serveInterval, "interval", hotplug.DefaultInterval, "How often to enumerate devices")
+	rootCmd.AddCommand(serveCmd)
+}
@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/stegmannb/usbtree/internal/hotplug"
+	"github.com/stegmannb/usbtree/internal/models"
+	"github.com/stegmannb/usbtree/internal/tui"
+)
+
+var tuiInterval time.Duration
+
+var tuiCmd = &cobra.Command{
+	Use:   "tui",
+	Short: "Browse the device tree interactively",
+	Long: `Browse the device tree in a full-screen terminal interface. The tree is on
+the left and the details of the selected device, including its /dev nodes,
+on the right.
+
+The tree updates live: attached devices are highlighted in green, changed
+ones in yellow, and detached ones stay in red for a few seconds. On Linux
+updates follow kernel uevents; elsewhere devices are enumerated every
+--interval.
+
+Keys:
+  up/down, j/k      move the cursor
+  left/right, h/l   collapse or expand a hub
+  enter, space      toggle a hub
+  /                 search; n and N jump to the next and previous match
+  f                 filter the tree to matching devices and their hubs
+  esc               clear search and filter
+  q                 quit
+
+Search and filter accept device selectors (1-2.3, 0403:6001, serial=...)
+or any part of a name, ID, port path or serial number.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		sysfs, cleanup, err := inputSysfs()
+		if err != nil {
+			return err
+		}
+		defer cleanup()
+
+		var opts tui.Options
+		if sysfs != nil {
+			opts.DevNodes = func(device *models.USBDevice) []string {
+				return sysfs.DevNodes(device.PortPath)
+			}
+		}
+
+		updates := hotplug.Watch(ctx, getTopology, hotplug.Options{Interval: tuiInterval})
+		return tui.Run(ctx, os.Stdin, os.Stdout, updates, opts)
+	},
+}
+
+func init() {
+	tuiCmd.Flags().DurationVar(&tuiInterval, "interval", hotplug.DefaultInterval, "How often to enumerate devices")
+	rootCmd.AddCommand(tuiCmd)
+}
@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/stegmannb/usbtree/internal/models"
+	"github.com/stegmannb/usbtree/internal/udev"
+)
+
+var (
+	udevOptions   udev.RuleOptions
+	udevRulesFile string
+)
+
+var udevRuleCmd = &cobra.Command{
+	Use:   "udev-rule [selector]",
+	Short: "Generate udev rules for devices or check an existing rules file",
+	Long: `Generate a udev rule matching the devices selected by the selector on
+vendor ID, product ID and serial number. The output can be saved directly
+to a file in /etc/udev/rules.d.
+
+With --check, read an existing rules file instead and report which of its
+rules match which of the connected devices.`,
+	Example: `  usbtree udev-rule 0403:6001 --subsystem tty --symlink dut3-console --group dialout
+  usbtree udev-rule 1-2.3 --port --uaccess
+  usbtree udev-rule --check /etc/udev/rules.d/99-lab.rules`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if udevRulesFile == "" && len(args) == 0 {
+			return fmt.Errorf("a device selector or --check is required")
+		}
+
+		topology, err := getTopology()
+		if err != nil {
+			return err
+		}
+
+		if udevRulesFile != "" {
+			return checkUdevRules(topology, udevRulesFile)
+		}
+
+		sel, err := models.ParseSelector(args[0])
+		if err != nil {
+			return err
+		}
+
+		matched := topology.Find(sel.Matches)
+		if len(matched) == 0 {
+			return fmt.Errorf("no device matches %s", sel)
+		}
+
+		for i, device := range matched {
+			rule, err := udev.GenerateRule(device, udevOptions)
+			if err != nil {
+				return err
+			}
+			if i > 0 {
+				fmt.Println()
+			}
+			fmt.Print(rule)
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	flags := udevRuleCmd.Flags()
+	flags.StringVar(&udevOptions.Subsystem, "subsystem", "usb", "Subsystem to match, e.g. tty for serial adapters")
+	flags.BoolVar(&udevOptions.MatchPort, "port", false, "Also match the physical port path (KERNELS==)")
+	flags.StringVar(&udevOptions.Symlink, "symlink", "", "Symlink to create below /dev")
+	flags.StringVar(&udevOptions.Mode, "mode", "", "Permissions of the device node, e.g. 0660")
+	flags.StringVar(&udevOptions.Group, "group", "", "Group owning the device node")
+	flags.BoolVar(&udevOptions.Uaccess, "uaccess", false, "Grant access to the logged-in user (TAG+=\"uaccess\")")
+	flags.BoolVar(&udevOptions.IgnoreModemManager, "mm-ignore", false, "Keep ModemManager away from the device (ENV{ID_MM_DEVICE_IGNORE})")
+	flags.StringVar(&udevRulesFile, "check", "", "Check an existing rules file against the connected devices")
+	rootCmd.AddCommand(udevRuleCmd)
+}
+
+func checkUdevRules(topology *models.Topology, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	rules, err := udev.ParseRules(file)
+	if err != nil {
+		return fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	results := udev.CheckRules(rules, topology)
+	if len(results) == 0 {
+		fmt.Printf("%s: no rules match on USB device attributes\n", path)
+		return nil
+	}
+
+	for _, result := range results {
+		fmt.Printf("%s:%d: %s\n", path, result.Rule.Line, result.Rule.Text)
+		if len(result.Devices) == 0 {
+			fmt.Println("  matches no connected device")
+			continue
+		}
+		for _, device := range result.Devices {
+			fmt.Printf("  matches %s %s [%s]\n", device.PortPath, device.GetDisplayName(), device.GetIDString())
+		}
+	}
+
+	return nil
+}
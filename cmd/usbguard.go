@@ -0,0 +1,125 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/stegmannb/usbtree/internal/models"
+	"github.com/stegmannb/usbtree/internal/usb"
+	"github.com/stegmannb/usbtree/internal/usbguard"
+)
+
+var (
+	usbguardNoHash         bool
+	usbguardNoPort         bool
+	usbguardRulesFile      string
+	usbguardImplicitPolicy string
+)
+
+var usbguardPolicyCmd = &cobra.Command{
+	Use:   "usbguard-policy [selector]",
+	Short: "Generate a USBGuard policy or check one against the connected devices",
+	Long: `Generate USBGuard allow rules for the connected devices, or only for the
+devices matched by the selector. Rules identify devices by ID, serial number,
+name, descriptor hash, port and interface classes.
+
+With --check, read an existing rules.conf instead and explain for every
+connected device whether USBGuard would allow or block it and why.`,
+	Example: `  usbtree usbguard-policy > /etc/usbguard/rules.conf
+  usbtree usbguard-policy 1-2 --no-port
+  usbtree usbguard-policy --check /etc/usbguard/rules.conf`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		topology, err := getTopology()
+		if err != nil {
+			return err
+		}
+
+		// Descriptors and hashes are only available for local devices
+		var src usbguard.AttributeSource
+		if inputFile == "" {
+			src = usb.NewSysfs(usb.DefaultSysfsRoot)
+			if anonymizer != nil {
+				src = anonymizedSource{src}
+			}
+		}
+
+		if usbguardRulesFile != "" {
+			return checkUSBGuardPolicy(topology, src, usbguardRulesFile)
+		}
+
+		opts := usbguard.Options{Hash: !usbguardNoHash, ViaPort: !usbguardNoPort}
+
+		if len(args) == 0 {
+			for _, rule := range usbguard.GeneratePolicy(topology, src, opts) {
+				fmt.Println(rule)
+			}
+			return nil
+		}
+
+		sel, err := models.ParseSelector(args[0])
+		if err != nil {
+			return err
+		}
+
+		matched := topology.Find(sel.Matches)
+		if len(matched) == 0 {
+			return fmt.Errorf("no device matches %s", sel)
+		}
+
+		for _, device := range matched {
+			fmt.Println(usbguard.FormatRule(usbguard.Attributes(device, src), opts))
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	flags := usbguardPolicyCmd.Flags()
+	flags.BoolVar(&usbguardNoHash, "no-hash", false, "Do not include descriptor hashes in generated rules")
+	flags.BoolVar(&usbguardNoPort, "no-port", false, "Do not include via-port in generated rules")
+	flags.StringVar(&usbguardRulesFile, "check", "", "Explain how an existing rules.conf treats the connected devices")
+	flags.StringVar(&usbguardImplicitPolicy, "implicit-policy", "block", "Target applied when no rule matches (block or reject)")
+	rootCmd.AddCommand(usbguardPolicyCmd)
+}
+
+func checkUSBGuardPolicy(topology *models.Topology, src usbguard.AttributeSource, path string) error {
+	if usbguardImplicitPolicy != "block" && usbguardImplicitPolicy != "reject" {
+		return fmt.Errorf("invalid --implicit-policy %q: expected block or reject", usbguardImplicitPolicy)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	rules, err := usbguard.ParsePolicy(file)
+	if err != nil {
+		return fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	for _, device := range topology.All() {
+		decision := usbguard.Decide(rules, usbguard.Attributes(device, src), usbguardImplicitPolicy)
+		fmt.Printf("%s %s [%s]: %s\n", device.PortPath, device.GetDisplayName(), device.GetIDString(), decision.Explain())
+	}
+
+	return nil
+}
+
+// anonymizedSource hides serial numbers read from sysfs behind the same
+// pseudonyms the device models carry. Hashes are computed over the pseudonym
+// and so do not match the real device either.
+type anonymizedSource struct {
+	usbguard.AttributeSource
+}
+
+func (s anonymizedSource) ReadAttr(portPath, attr string) (string, error) {
+	value, err := s.AttributeSource.ReadAttr(portPath, attr)
+	if err == nil && attr == "serial" {
+		value = anonymizer.Serial(value)
+	}
+	return value, err
+}
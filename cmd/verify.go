@@ -0,0 +1,127 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/stegmannb/usbtree/internal/verify"
+)
+
+// Exit codes of usbtree verify, as of diff(1).
+const (
+	exitMismatch = 1
+	exitTrouble  = 2
+)
+
+var (
+	verifyJSON   bool
+	verifyStrict bool
+)
+
+var verifyCmd = &cobra.Command{
+	Use:   "verify <spec>",
+	Short: "Check the device tree against an expected topology",
+	Long: `Compare the connected devices, or those read with --input, with an expected
+topology in YAML or JSON and report every device that is missing, different
+or, with strict, not listed.
+
+  strict: true              # also report devices the spec does not list
+  devices:
+    - port: 1-2             # port path at the top level
+      class: Hub
+      children:
+        - port: 1           # port number on the parent hub
+          id: 10c4:ea60
+          speed: Full       # or a rate such as 12M
+    - id: "0403:*"          # anywhere in the tree
+      serial: A50285BI
+    - name: "*Receiver*"    # product or vendor name
+      optional: true
+
+Devices may also match on vendor, product, class and driver. Values accept
+the wildcards * and ?. A device at a port given without wildcards is
+reported as different when its other attributes do not match.
+
+usbtree verify exits 0 when the tree matches, 1 when it does not and 2 when
+the spec or the devices cannot be read.`,
+	Example: `  usbtree verify station.yaml
+  usbtree verify station.yaml --input capture.json --json`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		file, err := os.Open(args[0])
+		if err != nil {
+			return &exitError{code: exitTrouble, err: err}
+		}
+		defer file.Close()
+		spec, err := verify.ParseSpec(file)
+		if err != nil {
+			return &exitError{code: exitTrouble, err: fmt.Errorf("failed to parse %s: %w", args[0], err)}
+		}
+		spec.Strict = spec.Strict || verifyStrict
+
+		topology, err := getTopology()
+		if err != nil {
+			return &exitError{code: exitTrouble, err: err}
+		}
+
+		report := verify.Verify(spec, topology)
+		if verifyJSON {
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			if err := encoder.Encode(report); err != nil {
+				return err
+			}
+		} else {
+			printVerifyReport(report)
+		}
+
+		if !report.OK {
+			return &exitError{code: exitMismatch, err: fmt.Errorf("device tree does not match %s", args[0])}
+		}
+		return nil
+	},
+}
+
+func init() {
+	verifyCmd.Flags().BoolVar(&verifyJSON, "json", false, "Print the report as JSON")
+	verifyCmd.Flags().BoolVar(&verifyStrict, "strict", false, "Also report devices the spec does not list")
+	rootCmd.AddCommand(verifyCmd)
+}
+
+func printVerifyReport(report *verify.Report) {
+	for _, entry := range report.Entries {
+		var what string
+		switch {
+		case entry.Device != nil:
+			what = fmt.Sprintf("%s %s [%s]", entry.Device.PortPath, entry.Device.Name, entry.Device.ID)
+		case entry.Status == verify.StatusOptional:
+			what = entry.Expected + " (not connected)"
+		default:
+			what = entry.Expected
+		}
+		fmt.Printf("%-10s  %-24s  %s\n", entry.Status, entry.Spec, what)
+		for _, diff := range entry.Differences {
+			actual := diff.Actual
+			if actual == "" {
+				actual = "nothing"
+			}
+			fmt.Printf("%38s%s: expected %s, found %s\n", "", diff.Field, diff.Expected, actual)
+		}
+	}
+
+	var counts []string
+	for _, status := range []verify.Status{verify.StatusOK, verify.StatusDifferent, verify.StatusMissing, verify.StatusUnexpected} {
+		if count := report.Count(status); count > 0 {
+			counts = append(counts, fmt.Sprintf("%d %s", count, status))
+		}
+	}
+	if count := report.Count(verify.StatusOptional); count > 0 {
+		counts = append(counts, fmt.Sprintf("%d optional not connected", count))
+	}
+	fmt.Printf("\n%s\n", strings.Join(counts, ", "))
+}
@@ -0,0 +1,140 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/stegmannb/usbtree/internal/hotplug"
+	"github.com/stegmannb/usbtree/internal/models"
+	"github.com/stegmannb/usbtree/internal/usb"
+	"github.com/stegmannb/usbtree/internal/wait"
+)
+
+// exitTimeout is the exit code of usbtree wait when the timeout expires,
+// as of timeout(1).
+const exitTimeout = 124
+
+var (
+	waitFor      string
+	waitTimeout  time.Duration
+	waitInterval time.Duration
+	waitJSON     bool
+	waitDevNode  bool
+)
+
+var waitCmd = &cobra.Command{
+	Use:   "wait --for <condition> <selector>",
+	Short: "Wait until a device is present, absent, bound or fast enough",
+	Long: `Wait until a device matching the selector reaches a state, for scripts
+that must not use a device before it is ready. Conditions:
+
+  present     a matching device is connected
+  absent      no matching device is connected
+  bound       a matching device has a driver bound to every interface
+  speed>=N    a matching device is connected at N or faster, e.g. 480M or 5G
+
+The condition is checked right away and again on every hotplug event. On
+Linux events follow kernel uevents; elsewhere devices are enumerated every
+--interval.
+
+usbtree wait exits 0 once the condition holds and 124 when --timeout
+expires first. With --json it prints the matching device, with --dev-node
+its /dev nodes: those of its drivers, such as /dev/ttyUSB0, or its usbfs
+node where no driver created one. Combine --dev-node with --for bound so
+the driver's nodes exist.`,
+	Example: `  usbtree wait --for present 0403:6001
+  usbtree wait --for bound serial=A50285BI --dev-node --timeout 1m
+  usbtree wait --for absent 1-2.3
+  usbtree wait --for speed>=5G name=SSD --json`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cond, err := wait.ParseCondition(waitFor)
+		if err != nil {
+			return err
+		}
+		sel, err := models.ParseSelector(args[0])
+		if err != nil {
+			return err
+		}
+		if waitJSON && waitDevNode {
+			return fmt.Errorf("--json conflicts with --dev-node")
+		}
+		if cond.State == wait.StateAbsent && (waitJSON || waitDevNode) {
+			return fmt.Errorf("--for absent has no device to print")
+		}
+		sysfs, cleanup, err := inputSysfs()
+		if err != nil {
+			return err
+		}
+		defer cleanup()
+		if waitDevNode && sysfs == nil {
+			return fmt.Errorf("--dev-node needs a capture bundle or fixture root as --input")
+		}
+		cmd.SilenceUsage = true
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		if waitTimeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, waitTimeout)
+			defer cancel()
+		}
+
+		updates := hotplug.Watch(ctx, getTopology, hotplug.Options{Interval: waitInterval})
+		device, err := wait.Until(ctx, updates, sel, cond)
+		if errors.Is(err, context.DeadlineExceeded) {
+			return &exitError{code: exitTimeout, err: fmt.Errorf("timed out after %s waiting for %s to be %s", waitTimeout, sel, cond)}
+		}
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case waitJSON:
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(device)
+		case waitDevNode:
+			nodes := devNodes(sysfs, device)
+			if len(nodes) == 0 {
+				return fmt.Errorf("no /dev node found for %s", device.GetDisplayName())
+			}
+			fmt.Println(strings.Join(nodes, "\n"))
+		}
+		return nil
+	},
+}
+
+func init() {
+	flags := waitCmd.Flags()
+	flags.StringVar(&waitFor, "for", string(wait.StatePresent), "Condition to wait for: present, absent, bound or speed>=N")
+	flags.DurationVar(&waitTimeout, "timeout", 30*time.Second, "How long to wait, 0 to wait forever")
+	flags.DurationVar(&waitInterval, "interval", hotplug.DefaultInterval, "How often to enumerate devices")
+	flags.BoolVar(&waitJSON, "json", false, "Print the matching device as JSON")
+	flags.BoolVar(&waitDevNode, "dev-node", false, "Print the /dev nodes of the matching device")
+	rootCmd.AddCommand(waitCmd)
+}
+
+// devNodes returns the /dev nodes the drivers of a device created, or its
+// usbfs node under /dev/bus/usb if they created none.
+func devNodes(sysfs *usb.Sysfs, device *models.USBDevice) []string {
+	all := sysfs.DevNodes(device.PortPath)
+	var drivers []string
+	for _, node := range all {
+		if !strings.HasPrefix(node, "/dev/bus/usb/") {
+			drivers = append(drivers, node)
+		}
+	}
+	if len(drivers) > 0 {
+		return drivers
+	}
+	return all
+}
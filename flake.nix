@@ -18,7 +18,7 @@
 
           src = ./.;
 
-          vendorHash = "sha256-R8PGiiPyDzHWXgIFeJXQ28dJd0jDr8FZM7HajodDv0Y=";
+          vendorHash = "sha256-1/jmJ179MdelnNA4tZ2OVi48I/2sktDOHx7nTpGGKCA=";
 
           # No longer need libusb
           nativeBuildInputs = [ ];
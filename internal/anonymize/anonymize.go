@@ -0,0 +1,127 @@
+// Package anonymize replaces hardware identifiers with stable pseudonyms, so
+// output can be shared without exposing serial numbers.
+package anonymize
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/stegmannb/usbtree/internal/models"
+)
+
+// SerialPrefix starts every serial number pseudonym.
+const SerialPrefix = "anon-"
+
+// Anonymizer derives pseudonyms from identifiers with a keyed hash. The same
+// identifier always gets the same pseudonym from the same salt.
+type Anonymizer struct {
+	salt []byte
+
+	// IDs also replaces vendor and product IDs. Names are kept, so the tree
+	// stays readable.
+	IDs bool
+}
+
+// New returns an Anonymizer using salt. With an empty salt a random one is
+// generated, so pseudonyms are only stable within one run.
+func New(salt string) (*Anonymizer, error) {
+	if salt != "" {
+		return &Anonymizer{salt: []byte(salt)}, nil
+	}
+
+	random := make([]byte, 16)
+	if _, err := rand.Read(random); err != nil {
+		return nil, fmt.Errorf("failed to generate salt: %w", err)
+	}
+	return &Anonymizer{salt: random}, nil
+}
+
+// sum hashes value in the namespace kind, so a serial and a location ID with
+// the same value get unrelated pseudonyms.
+func (a *Anonymizer) sum(kind, value string) []byte {
+	mac := hmac.New(sha256.New, a.salt)
+	mac.Write([]byte(kind))
+	mac.Write([]byte{0})
+	mac.Write([]byte(value))
+	return mac.Sum(nil)
+}
+
+// Serial returns the pseudonym of a serial number. Empty serials stay empty.
+func (a *Anonymizer) Serial(serial string) string {
+	if serial == "" {
+		return ""
+	}
+	return SerialPrefix + hex.EncodeToString(a.sum("serial", serial)[:6])
+}
+
+// LocationID returns the pseudonym of a macOS location ID in the same
+// 0x-prefixed 32-bit notation.
+func (a *Anonymizer) LocationID(id string) string {
+	if id == "" {
+		return ""
+	}
+	return fmt.Sprintf("0x%08x", binary.BigEndian.Uint32(a.sum("location", id)))
+}
+
+// VendorID returns a pseudonymous vendor ID.
+func (a *Anonymizer) VendorID(vendor uint16) uint16 {
+	return binary.BigEndian.Uint16(a.sum("vendor", fmt.Sprintf("%04x", vendor)))
+}
+
+// ProductID returns a pseudonymous product ID. Product IDs are only unique
+// per vendor, so the vendor is part of the hash.
+func (a *Anonymizer) ProductID(vendor, product uint16) uint16 {
+	return binary.BigEndian.Uint16(a.sum("product", fmt.Sprintf("%04x:%04x", vendor, product)))
+}
+
+// Apply replaces the identifiers of all devices in the topology in place.
+func (a *Anonymizer) Apply(topology *models.Topology) {
+	topology.Walk(func(device *models.USBDevice) error {
+		device.Serial = a.Serial(device.Serial)
+		device.LocationID = a.LocationID(device.LocationID)
+		if a.IDs {
+			device.VendorID, device.ProductID = a.VendorID(device.VendorID), a.ProductID(device.VendorID, device.ProductID)
+		}
+		return nil
+	})
+}
+
+// Replacer returns a replacer substituting every given serial number and
+// location ID in free text, such as saved tool output.
+func (a *Anonymizer) Replacer(serials, locationIDs []string) *strings.Replacer {
+	pseudonyms := make(map[string]string)
+	for _, id := range locationIDs {
+		if id != "" {
+			pseudonyms[id] = a.LocationID(id)
+		}
+	}
+	for _, serial := range serials {
+		if serial != "" {
+			pseudonyms[serial] = a.Serial(serial)
+		}
+	}
+
+	// Longest first, so identifiers containing others are replaced whole
+	originals := make([]string, 0, len(pseudonyms))
+	for original := range pseudonyms {
+		originals = append(originals, original)
+	}
+	sort.Slice(originals, func(i, j int) bool {
+		if len(originals[i]) != len(originals[j]) {
+			return len(originals[i]) > len(originals[j])
+		}
+		return originals[i] < originals[j]
+	})
+
+	var pairs []string
+	for _, original := range originals {
+		pairs = append(pairs, original, pseudonyms[original])
+	}
+	return strings.NewReplacer(pairs...)
+}
@@ -0,0 +1,109 @@
+package anonymize
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stegmannb/usbtree/internal/models"
+)
+
+func newTestAnonymizer(t *testing.T, salt string) *Anonymizer {
+	t.Helper()
+	a, err := New(salt)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	return a
+}
+
+func TestSerial(t *testing.T) {
+	a := newTestAnonymizer(t, "salt")
+
+	pseudonym := a.Serial("A50285BI")
+	if !strings.HasPrefix(pseudonym, SerialPrefix) || len(pseudonym) != len(SerialPrefix)+12 {
+		t.Errorf("Unexpected pseudonym %q", pseudonym)
+	}
+	if strings.Contains(pseudonym, "A50285BI") {
+		t.Error("Pseudonym contains the serial")
+	}
+	if a.Serial("A50285BI") != pseudonym {
+		t.Error("Expected the same serial to get the same pseudonym")
+	}
+	if newTestAnonymizer(t, "salt").Serial("A50285BI") != pseudonym {
+		t.Error("Expected the same salt to give the same pseudonym")
+	}
+	if newTestAnonymizer(t, "other").Serial("A50285BI") == pseudonym {
+		t.Error("Expected a different salt to give a different pseudonym")
+	}
+	if a.Serial("A50285BJ") == pseudonym {
+		t.Error("Expected different serials to get different pseudonyms")
+	}
+	if a.Serial("") != "" {
+		t.Error("Expected empty serial to stay empty")
+	}
+}
+
+func TestNew_RandomSalt(t *testing.T) {
+	if newTestAnonymizer(t, "").Serial("A50285BI") == newTestAnonymizer(t, "").Serial("A50285BI") {
+		t.Error("Expected random salts to give different pseudonyms")
+	}
+}
+
+func TestLocationID(t *testing.T) {
+	a := newTestAnonymizer(t, "salt")
+
+	pseudonym := a.LocationID("0x01200000")
+	if !strings.HasPrefix(pseudonym, "0x") || len(pseudonym) != 10 || pseudonym == "0x01200000" {
+		t.Errorf("Unexpected location ID pseudonym %q", pseudonym)
+	}
+	if a.LocationID("0x01200000") != pseudonym {
+		t.Error("Expected stable location ID pseudonym")
+	}
+}
+
+func TestApply(t *testing.T) {
+	ftdi := &models.USBDevice{VendorID: 0x0403, ProductID: 0x6001, ProductName: "FT232R", Serial: "A50285BI", LocationID: "0x01200000"}
+	other := &models.USBDevice{VendorID: 0x0403, ProductID: 0x6001, Serial: "A50285BI"}
+	topology := models.NewTopology([]*models.USBDevice{{VendorID: 0x1d6b, ProductID: 0x0002, Children: []*models.USBDevice{ftdi, other}}})
+
+	a := newTestAnonymizer(t, "salt")
+	a.Apply(topology)
+
+	if ftdi.Serial != a.Serial("A50285BI") || ftdi.LocationID != a.LocationID("0x01200000") {
+		t.Errorf("Expected identifiers to be replaced, got %+v", ftdi)
+	}
+	if other.Serial != ftdi.Serial {
+		t.Error("Expected the same serial to get the same pseudonym across devices")
+	}
+	if ftdi.GetIDString() != "0403:6001" || ftdi.ProductName != "FT232R" {
+		t.Errorf("Expected IDs and names to be kept, got %+v", ftdi)
+	}
+
+	a.IDs = true
+	a.Apply(topology)
+	if ftdi.GetIDString() == "0403:6001" {
+		t.Error("Expected vendor and product ID to be replaced")
+	}
+	if ftdi.GetIDString() != other.GetIDString() {
+		t.Error("Expected the same IDs to get the same pseudonyms")
+	}
+}
+
+func TestReplacer(t *testing.T) {
+	a := newTestAnonymizer(t, "salt")
+	replacer := a.Replacer([]string{"A502", "A50285BI", ""}, []string{"0x01200000"})
+
+	text := `iSerial 3 A50285BI
+ID_SERIAL_SHORT=A502
+"location_id" : "0x01200000 / 4"`
+	replaced := replacer.Replace(text)
+
+	for _, want := range []string{a.Serial("A50285BI"), a.Serial("A502"), a.LocationID("0x01200000")} {
+		if !strings.Contains(replaced, want) {
+			t.Errorf("Expected %s in:\n%s", want, replaced)
+		}
+	}
+	if strings.Contains(replaced, "A502") || strings.Contains(replaced, "0x01200000") {
+		t.Errorf("Identifier left in:\n%s", replaced)
+	}
+}
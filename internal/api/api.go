@@ -0,0 +1,267 @@
+// Package api serves the device tree as JSON over HTTP for usbtree serve
+// --listen. The tree is cached between enumerations by hotplug.Watch, which
+// refreshes it on uevents, so requests never wait for lsusb.
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/stegmannb/usbtree/internal/hotplug"
+	"github.com/stegmannb/usbtree/internal/models"
+)
+
+// Health is the body of GET /health.
+type Health struct {
+	// Status is ok once devices were enumerated, starting before that and
+	// error when the last enumeration failed.
+	Status    string     `json:"status"`
+	Devices   int        `json:"devices"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+	Error     string     `json:"error,omitempty"`
+}
+
+// Health statuses.
+const (
+	StatusOK       = "ok"
+	StatusStarting = "starting"
+	StatusError    = "error"
+)
+
+// errorBody is the body of every error response.
+type errorBody struct {
+	Error string `json:"error"`
+}
+
+// keepAlive is how often an idle event stream sends a comment, so proxies
+// do not close it.
+const keepAlive = 30 * time.Second
+
+// subscriberBuffer is how many events a slow event stream may lag behind
+// before it is closed.
+const subscriberBuffer = 64
+
+// Server answers requests from the latest update it was given.
+type Server struct {
+	hostname string
+
+	mu          sync.RWMutex
+	topology    *models.Topology
+	updated     time.Time
+	err         error
+	subscribers map[chan hotplug.Event]struct{}
+}
+
+// NewServer returns a server without devices. hostname is reported in
+// GET /devices and may be empty.
+func NewServer(hostname string) *Server {
+	return &Server{hostname: hostname, subscribers: make(map[chan hotplug.Event]struct{})}
+}
+
+// Update replaces the cached tree and sends the events of the update to
+// all event streams.
+func (s *Server) Update(update hotplug.Update) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.err = update.Err
+	if update.Err != nil {
+		return
+	}
+	s.topology = update.Topology
+	s.updated = update.Time
+
+	for _, event := range update.Events {
+		for subscriber := range s.subscribers {
+			select {
+			case subscriber <- event:
+			default:
+				// A client that does not keep up is dropped rather than
+				// holding up the others; it can reconnect and resync
+				delete(s.subscribers, subscriber)
+				close(subscriber)
+			}
+		}
+	}
+}
+
+// Handler routes the API endpoints.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /devices", s.handleDevices)
+	mux.HandleFunc("GET /devices/{portpath}", s.handleDevice)
+	mux.HandleFunc("GET /events", s.handleEvents)
+	mux.HandleFunc("GET /health", s.handleHealth)
+	return mux
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, format string, args ...any) {
+	writeJSON(w, status, errorBody{Error: fmt.Sprintf(format, args...)})
+}
+
+// current returns the cached tree and when it was enumerated, or writes an
+// error and returns nil before the first enumeration.
+func (s *Server) current(w http.ResponseWriter) (*models.Topology, time.Time) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.topology == nil {
+		writeError(w, http.StatusServiceUnavailable, "devices not enumerated yet")
+	}
+	return s.topology, s.updated
+}
+
+// handleDevices serves the tree as the versioned report of usbtree --json,
+// or of --json-flat with ?flat=true.
+func (s *Server) handleDevices(w http.ResponseWriter, r *http.Request) {
+	topology, updated := s.current(w)
+	if topology == nil {
+		return
+	}
+
+	info := models.NewReportInfo(topology.Backend, s.hostname)
+	info.GeneratedAt = updated.UTC()
+
+	if r.URL.Query().Get("flat") == "true" {
+		flat := models.Flatten(topology)
+		if flat == nil {
+			flat = []models.FlatDevice{}
+		}
+		writeJSON(w, http.StatusOK, models.FlatReport{ReportInfo: info, Devices: flat})
+		return
+	}
+	devices := topology.Devices
+	if devices == nil {
+		devices = []*models.USBDevice{}
+	}
+	writeJSON(w, http.StatusOK, models.Report{ReportInfo: info, Devices: devices})
+}
+
+// handleDevice serves one device with the devices below it.
+func (s *Server) handleDevice(w http.ResponseWriter, r *http.Request) {
+	topology, _ := s.current(w)
+	if topology == nil {
+		return
+	}
+
+	portPath := r.PathValue("portpath")
+	device := topology.FindByPortPath(portPath)
+	if device == nil {
+		writeError(w, http.StatusNotFound, "no device at port path %s", portPath)
+		return
+	}
+	writeJSON(w, http.StatusOK, device)
+}
+
+// handleEvents streams hotplug events as server-sent events named after
+// their action, with the event as JSON data.
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "streaming not supported")
+		return
+	}
+
+	events := make(chan hotplug.Event, subscriberBuffer)
+	s.mu.Lock()
+	s.subscribers[events] = struct{}{}
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		if _, ok := s.subscribers[events]; ok {
+			delete(s.subscribers, events)
+			close(events)
+		}
+		s.mu.Unlock()
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, ": connected\n\n")
+	flusher.Flush()
+
+	ticker := time.NewTicker(keepAlive)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			data, err := json.Marshal(event)
+			if err != nil {
+				return
+			}
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Action, data)
+		}
+		flusher.Flush()
+	}
+}
+
+// handleHealth reports whether the cached tree is current, with 503 when
+// it is not.
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	health := Health{Status: StatusOK}
+	if s.topology != nil {
+		health.Devices = len(s.topology.All())
+		updated := s.updated.UTC()
+		health.UpdatedAt = &updated
+	}
+	switch {
+	case s.err != nil:
+		health.Status, health.Error = StatusError, s.err.Error()
+	case s.topology == nil:
+		health.Status = StatusStarting
+	}
+	s.mu.RUnlock()
+
+	status := http.StatusOK
+	if health.Status != StatusOK {
+		status = http.StatusServiceUnavailable
+	}
+	writeJSON(w, status, health)
+}
+
+// Listen listens on an address as accepted by --listen: unix:/path for a
+// Unix socket, replacing a stale socket file, or host:port for TCP.
+func Listen(address string) (net.Listener, error) {
+	network, addr := SplitAddress(address)
+	if network == "unix" {
+		if info, err := os.Lstat(addr); err == nil && info.Mode()&os.ModeSocket != 0 {
+			os.Remove(addr)
+		}
+	}
+	listener, err := net.Listen(network, addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", address, err)
+	}
+	return listener, nil
+}
+
+// SplitAddress returns the network and address of an address as accepted
+// by --listen.
+func SplitAddress(address string) (network, addr string) {
+	if path, found := strings.CutPrefix(address, "unix:"); found {
+		return "unix", path
+	}
+	return "tcp", address
+}
@@ -0,0 +1,145 @@
+package api
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stegmannb/usbtree/internal/hotplug"
+	"github.com/stegmannb/usbtree/internal/models"
+)
+
+// benchTopology is the tree the server is given: a root hub read by the
+// sysfs backend, with a serial adapter at 1-2 if attached.
+func benchTopology(attached bool) *models.Topology {
+	root := &models.USBDevice{VendorID: 0x1d6b, ProductID: 0x0002, Class: "Hub", PortPath: "usb1"}
+	if attached {
+		root.AddChild(&models.USBDevice{VendorID: 0x0403, ProductID: 0x6001, Serial: "A50285BI", PortPath: "1-2"})
+	}
+	topology := models.NewTopology([]*models.USBDevice{root})
+	topology.Backend = "sysfs"
+	return topology
+}
+
+func get(t *testing.T, server *httptest.Server, path string, status int, v any) {
+	t.Helper()
+	resp, err := http.Get(server.URL + path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != status {
+		t.Errorf("GET %s: expected status %d, got %d", path, status, resp.StatusCode)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		t.Fatalf("GET %s: invalid JSON: %v", path, err)
+	}
+}
+
+func TestServer(t *testing.T) {
+	s := NewServer("bench-1")
+	server := httptest.NewServer(s.Handler())
+	defer server.Close()
+
+	var health Health
+	get(t, server, "/health", http.StatusServiceUnavailable, &health)
+	if health.Status != StatusStarting {
+		t.Errorf("Expected starting, got %+v", health)
+	}
+	var body errorBody
+	get(t, server, "/devices", http.StatusServiceUnavailable, &body)
+
+	updated := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	s.Update(hotplug.Update{Time: updated, Topology: benchTopology(true)})
+
+	var report models.Report
+	get(t, server, "/devices", http.StatusOK, &report)
+	if report.Hostname != "bench-1" || report.Backend != "sysfs" || !report.GeneratedAt.Equal(updated) ||
+		len(report.Devices) != 1 || report.Devices[0].Children[0].Serial != "A50285BI" {
+		t.Errorf("Unexpected report %+v", report)
+	}
+	var flat models.FlatReport
+	get(t, server, "/devices?flat=true", http.StatusOK, &flat)
+	if len(flat.Devices) != 2 || *flat.Devices[1].ParentID != 1 {
+		t.Errorf("Unexpected flat report %+v", flat)
+	}
+
+	var device models.USBDevice
+	get(t, server, "/devices/1-2", http.StatusOK, &device)
+	if device.Serial != "A50285BI" {
+		t.Errorf("Unexpected device %+v", device)
+	}
+	get(t, server, "/devices/1-3", http.StatusNotFound, &body)
+	if body.Error != "no device at port path 1-3" {
+		t.Errorf("Unexpected error %q", body.Error)
+	}
+
+	get(t, server, "/health", http.StatusOK, &health)
+	if health.Status != StatusOK || health.Devices != 2 || !health.UpdatedAt.Equal(updated) {
+		t.Errorf("Unexpected health %+v", health)
+	}
+
+	// A failed enumeration keeps the cached tree but is reported
+	s.Update(hotplug.Update{Err: errors.New("lsusb failed")})
+	get(t, server, "/health", http.StatusServiceUnavailable, &health)
+	if health.Status != StatusError || health.Error != "lsusb failed" || health.Devices != 2 {
+		t.Errorf("Unexpected health %+v", health)
+	}
+	get(t, server, "/devices/1-2", http.StatusOK, &device)
+}
+
+func TestServer_Events(t *testing.T) {
+	s := NewServer("")
+	s.Update(hotplug.Update{Topology: benchTopology(false)})
+	server := httptest.NewServer(s.Handler())
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/events")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Errorf("Unexpected content type %q", resp.Header.Get("Content-Type"))
+	}
+
+	reader := bufio.NewReader(resp.Body)
+	// The stream is subscribed once the first comment arrives
+	if line, _ := reader.ReadString('\n'); line != ": connected\n" {
+		t.Fatalf("Unexpected first line %q", line)
+	}
+
+	before, after := benchTopology(false), benchTopology(true)
+	s.Update(hotplug.Update{Topology: after, Events: hotplug.Diff(before, after, time.Now())})
+
+	var lines []string
+	for len(lines) < 2 {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if line != "\n" {
+			lines = append(lines, strings.TrimSuffix(line, "\n"))
+		}
+	}
+	if lines[0] != "event: add" || !strings.HasPrefix(lines[1], `data: {"time":`) || !strings.Contains(lines[1], `"port_path":"1-2"`) {
+		t.Errorf("Unexpected event %q", lines)
+	}
+}
+
+func TestSplitAddress(t *testing.T) {
+	for address, expected := range map[string]string{
+		"unix:/run/usbtree.sock": "unix /run/usbtree.sock",
+		":8080":                  "tcp :8080",
+		"localhost:8080":         "tcp localhost:8080",
+	} {
+		if network, addr := SplitAddress(address); network+" "+addr != expected {
+			t.Errorf("SplitAddress(%q) = %s %s, expected %s", address, network, addr, expected)
+		}
+	}
+}
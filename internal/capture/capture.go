@@ -0,0 +1,340 @@
+package capture
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/stegmannb/usbtree/internal/anonymize"
+	"github.com/stegmannb/usbtree/internal/usb"
+)
+
+// FormatVersion is bumped whenever the bundle layout changes incompatibly.
+const FormatVersion = 1
+
+const (
+	manifestFile = "manifest.json"
+	udevDir      = "udev"
+
+	// Larger sysfs files are not attributes the detectors read
+	maxAttributeSize = 64 << 10
+)
+
+// Options controls what a capture collects.
+type Options struct {
+	SysfsRoot string // defaults to usb.DefaultSysfsRoot
+	Version   string // usbtree version recorded in the manifest
+
+	// Anonymizer, when set, replaces serial numbers and macOS location IDs
+	// throughout the bundle.
+	Anonymizer *anonymize.Anonymizer
+
+	// Run executes a command and returns its standard output. It defaults to
+	// os/exec and is replaced in tests.
+	Run func(name string, args ...string) ([]byte, error)
+}
+
+// Manifest describes the machine and the capture. It is stored as
+// manifest.json at the root of the bundle.
+type Manifest struct {
+	FormatVersion  int       `json:"format_version"`
+	UsbtreeVersion string    `json:"usbtree_version"`
+	CreatedAt      time.Time `json:"created_at"`
+	OS             string    `json:"os"`
+	Kernel         string    `json:"kernel,omitempty"`
+	Anonymized     bool      `json:"anonymized"`
+	Files          []string  `json:"files"`
+}
+
+type entry struct {
+	name     string
+	data     []byte
+	linkname string // set for symlinks
+}
+
+type collector struct {
+	opts        Options
+	entries     []entry
+	serials     []string
+	locationIDs []string
+}
+
+// Write collects every input the detectors read on this machine and writes
+// it as a gzipped tar to w. Unpacked, the archive is a fixture root that
+// usb.NewFixtureDetector replays.
+func Write(w io.Writer, opts Options) error {
+	if opts.SysfsRoot == "" {
+		opts.SysfsRoot = usb.DefaultSysfsRoot
+	}
+	if opts.Run == nil {
+		opts.Run = runCommand
+	}
+
+	c := &collector{opts: opts}
+	if err := c.collectSysfs(); err != nil {
+		return err
+	}
+	c.collectCommands()
+	c.collectUdev()
+
+	manifest := Manifest{
+		FormatVersion:  FormatVersion,
+		UsbtreeVersion: opts.Version,
+		CreatedAt:      time.Now().UTC(),
+		OS:             runtime.GOOS,
+		Anonymized:     opts.Anonymizer != nil,
+	}
+	if kernel, err := opts.Run("uname", "-r"); err == nil {
+		manifest.Kernel = strings.TrimSpace(string(kernel))
+	}
+
+	if opts.Anonymizer != nil {
+		c.anonymize()
+	}
+
+	sort.Slice(c.entries, func(i, j int) bool {
+		return c.entries[i].name < c.entries[j].name
+	})
+	for _, e := range c.entries {
+		manifest.Files = append(manifest.Files, e.name)
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	c.entries = append([]entry{{name: manifestFile, data: append(data, '\n')}}, c.entries...)
+
+	return c.writeArchive(w, manifest.CreatedAt)
+}
+
+func runCommand(name string, args ...string) ([]byte, error) {
+	output, err := exec.Command(name, args...).Output()
+	// lsusb -v exits nonzero when it cannot open some devices but still
+	// prints everything else
+	if err != nil && len(output) > 0 {
+		return output, nil
+	}
+	return output, err
+}
+
+func (c *collector) add(name string, data []byte) {
+	c.entries = append(c.entries, entry{name: name, data: data})
+}
+
+// collectSysfs copies the attribute files of every USB device and interface
+// along with their power/ directory, the hub port directories and the
+// uevents of the class devices bound to the interfaces.
+func (c *collector) collectSysfs() error {
+	entries, err := os.ReadDir(c.opts.SysfsRoot)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", c.opts.SysfsRoot, err)
+	}
+
+	for _, e := range entries {
+		// Entries are symlinks into /sys/devices; reading through them
+		// copies the device directory itself
+		c.collectDir(filepath.Join(c.opts.SysfsRoot, e.Name()), path.Join(usb.FixtureSysfsDir, e.Name()), 0)
+	}
+
+	return nil
+}
+
+var portDirRe = regexp.MustCompile(`-port\d+$`)
+
+func (c *collector) collectDir(dir, name string, depth int) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+
+	for _, e := range entries {
+		source := filepath.Join(dir, e.Name())
+		target := path.Join(name, e.Name())
+
+		switch {
+		case e.Type()&os.ModeSymlink != 0:
+			// Only the bound driver matters; other links lead out of the USB tree
+			if e.Name() != "driver" {
+				continue
+			}
+			if link, err := os.Readlink(source); err == nil {
+				c.entries = append(c.entries, entry{name: target, linkname: link})
+			}
+		case e.IsDir():
+			if depth < 2 && (e.Name() == "power" || portDirRe.MatchString(e.Name())) {
+				c.collectDir(source, target, depth+1)
+			} else if depth == 0 && strings.Contains(path.Base(name), ":") {
+				// Class devices bound to an interface, e.g. tty/ttyUSB0
+				c.collectUevents(source, target, 1)
+			}
+		case e.Type().IsRegular():
+			info, err := e.Info()
+			if err != nil || info.Size() > maxAttributeSize {
+				continue
+			}
+			// Write-only and privileged attributes fail to read and are skipped
+			data, err := os.ReadFile(source)
+			if err != nil {
+				continue
+			}
+			c.add(target, data)
+			if e.Name() == "serial" && depth == 0 {
+				c.serials = append(c.serials, strings.TrimSpace(string(data)))
+			}
+		}
+	}
+}
+
+// collectUevents copies the uevent files of the class devices below an
+// interface, which name their /dev nodes, as deep as usb.Sysfs.DevNodes
+// looks for them.
+func (c *collector) collectUevents(dir, name string, depth int) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	for _, e := range entries {
+		source := filepath.Join(dir, e.Name())
+		switch {
+		case e.IsDir() && depth < usb.MaxDevNodeDepth:
+			c.collectUevents(source, path.Join(name, e.Name()), depth+1)
+		case e.Name() == "uevent" && e.Type().IsRegular():
+			if data, err := os.ReadFile(source); err == nil {
+				c.add(path.Join(name, e.Name()), data)
+			}
+		}
+	}
+}
+
+// collectCommands saves the output of every tool a detector may run. Tools
+// missing on this machine are skipped.
+func (c *collector) collectCommands() {
+	commands := []struct {
+		file string
+		name string
+		args []string
+	}{
+		{usb.FixtureLsusb, "lsusb", nil},
+		{usb.FixtureLsusbTree, "lsusb", []string{"-t"}},
+		{usb.FixtureLsusbVerbose, "lsusb", []string{"-v"}},
+	}
+	if runtime.GOOS == "darwin" {
+		commands = append(commands, struct {
+			file string
+			name string
+			args []string
+		}{usb.FixtureSystemProfiler, "system_profiler", []string{"SPUSBDataType", "-json"}})
+	}
+
+	for _, command := range commands {
+		output, err := c.opts.Run(command.name, command.args...)
+		if err != nil {
+			continue
+		}
+		c.add(command.file, output)
+		if command.file == usb.FixtureSystemProfiler {
+			c.serials = append(c.serials, systemProfilerValues(output, systemProfilerSerialRe)...)
+			c.locationIDs = append(c.locationIDs, systemProfilerValues(output, systemProfilerLocationRe)...)
+		}
+	}
+}
+
+var (
+	systemProfilerSerialRe   = regexp.MustCompile(`"serial_num"\s*:\s*"([^"]*)"`)
+	systemProfilerLocationRe = regexp.MustCompile(`"location_id"\s*:\s*"(0x[0-9a-fA-F]+)`)
+)
+
+func systemProfilerValues(output []byte, re *regexp.Regexp) []string {
+	var values []string
+	for _, match := range re.FindAllSubmatch(output, -1) {
+		values = append(values, string(match[1]))
+	}
+	return values
+}
+
+// collectUdev saves the udev properties of every device, which carry the
+// identifiers udev rules match on.
+func (c *collector) collectUdev() {
+	entries, err := os.ReadDir(c.opts.SysfsRoot)
+	if err != nil {
+		return
+	}
+
+	for _, e := range entries {
+		// Interfaces (1-2:1.0) share the properties of their device
+		if strings.Contains(e.Name(), ":") {
+			continue
+		}
+		output, err := c.opts.Run("udevadm", "info", "--query=property", "--path="+filepath.Join(c.opts.SysfsRoot, e.Name()))
+		if err != nil {
+			continue
+		}
+		c.add(path.Join(udevDir, e.Name()+".txt"), output)
+	}
+}
+
+// anonymize replaces every serial number found in sysfs or system_profiler
+// output, and every macOS location ID, with its pseudonym. The same serial
+// gets the same pseudonym throughout the bundle.
+func (c *collector) anonymize() {
+	replacer := c.opts.Anonymizer.Replacer(c.serials, c.locationIDs)
+	for i, e := range c.entries {
+		if e.linkname != "" {
+			continue
+		}
+		// Of the sysfs attributes only serial holds a serial number; numeric
+		// attributes could contain short serials by coincidence
+		if strings.HasPrefix(e.name, usb.FixtureSysfsDir+"/") && path.Base(e.name) != "serial" {
+			continue
+		}
+		c.entries[i].data = []byte(replacer.Replace(string(e.data)))
+	}
+}
+
+func (c *collector) writeArchive(w io.Writer, modTime time.Time) error {
+	gz := gzip.NewWriter(w)
+	archive := tar.NewWriter(gz)
+
+	for _, e := range c.entries {
+		header := &tar.Header{
+			Name:    e.name,
+			Mode:    0644,
+			ModTime: modTime,
+		}
+		if e.linkname != "" {
+			header.Typeflag = tar.TypeSymlink
+			header.Linkname = e.linkname
+			header.Mode = 0777
+		} else {
+			header.Typeflag = tar.TypeReg
+			header.Size = int64(len(e.data))
+		}
+
+		if err := archive.WriteHeader(header); err != nil {
+			return err
+		}
+		if _, err := archive.Write(e.data); err != nil {
+			return err
+		}
+	}
+
+	if err := archive.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
@@ -0,0 +1,277 @@
+package capture
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stegmannb/usbtree/internal/anonymize"
+	"github.com/stegmannb/usbtree/internal/usb"
+)
+
+const (
+	testLsusb = `Bus 001 Device 002: ID 0403:6001 Future Technology Devices International, Ltd FT232 Serial (UART) IC
+Bus 001 Device 001: ID 1d6b:0002 Linux Foundation 2.0 root hub
+`
+	testLsusbTree = `/:  Bus 001.Port 001: Dev 001, Class=root_hub, Driver=xhci_hcd/12p, 480M
+    |__ Port 002: Dev 002, If 0, Class=Vendor Specific Class, Driver=ftdi_sio, 12M
+`
+	testLsusbVerbose = `Bus 001 Device 002: ID 0403:6001 Future Technology Devices International, Ltd FT232 Serial (UART) IC
+Device Descriptor:
+  iSerial                 3 A50285BI
+`
+)
+
+// newFakeSysfs lays out a sysfs tree like the kernel's: device directories
+// below devices/ and symlinks to them in bus/usb/devices.
+func newFakeSysfs(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+
+	files := map[string]string{
+		"devices/usb1/idVendor":                                     "1d6b",
+		"devices/usb1/serial":                                       "0000:00:14.0",
+		"devices/usb1/1-0:1.0/bInterfaceClass":                      "09",
+		"devices/usb1/1-0:1.0/usb1-port2/over_current_count":        "0",
+		"devices/usb1/1-0:1.0/usb1-port2/power/pm_qos_no_power_off": "0",
+		"devices/usb1/1-2/idVendor":                                 "0403",
+		"devices/usb1/1-2/serial":                                   "A50285BI",
+		"devices/usb1/1-2/descriptors":                              "\x12\x01\x00\x02",
+		"devices/usb1/1-2/power/control":                            "auto",
+		"devices/usb1/1-2/power/runtime_status":                     "active",
+		"devices/usb1/1-2/ep_00/type":                               "Control",
+		"devices/usb1/1-2/1-2:1.0/bInterfaceNumber":                 "00",
+		"devices/usb1/1-2/1-2:1.0/bInterfaceClass":                  "ff",
+		"devices/usb1/1-2/uevent":                                   "DEVNAME=bus/usb/001/002",
+		"devices/usb1/1-2/1-2:1.0/ep_81/type":                       "Bulk",
+		"devices/usb1/1-2/1-2:1.0/ttyUSB0/tty/ttyUSB0/uevent":       "MAJOR=188\nMINOR=0\nDEVNAME=ttyUSB0",
+		"bus/usb/drivers/ftdi_sio/bind":                             "",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	links := map[string]string{
+		"bus/usb/devices/usb1":            "../../../devices/usb1",
+		"bus/usb/devices/1-0:1.0":         "../../../devices/usb1/1-0:1.0",
+		"bus/usb/devices/1-2":             "../../../devices/usb1/1-2",
+		"bus/usb/devices/1-2:1.0":         "../../../devices/usb1/1-2/1-2:1.0",
+		"devices/usb1/1-2/1-2:1.0/driver": "../../../../bus/usb/drivers/ftdi_sio",
+		"devices/usb1/1-2/subsystem":      "../../../bus/usb",
+	}
+	for name, target := range links {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Symlink(target, path); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return filepath.Join(root, "bus", "usb", "devices")
+}
+
+func fakeRun(name string, args ...string) ([]byte, error) {
+	command := strings.Join(append([]string{name}, args...), " ")
+	switch {
+	case command == "lsusb":
+		return []byte(testLsusb), nil
+	case command == "lsusb -t":
+		return []byte(testLsusbTree), nil
+	case command == "lsusb -v":
+		return []byte(testLsusbVerbose), nil
+	case command == "uname -r":
+		return []byte("6.8.0-test\n"), nil
+	case strings.HasPrefix(command, "udevadm info") && strings.HasSuffix(command, "/1-2"):
+		return []byte("ID_VENDOR_ID=0403\nID_SERIAL_SHORT=A50285BI\n"), nil
+	default:
+		return nil, fmt.Errorf("command not found: %s", name)
+	}
+}
+
+// readArchive returns the regular files of a bundle by name and its
+// symlinks by name with their targets.
+func readArchive(t *testing.T, data []byte) (files, links map[string]string) {
+	t.Helper()
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("Bundle is not gzipped: %v", err)
+	}
+	archive := tar.NewReader(gz)
+
+	files = make(map[string]string)
+	links = make(map[string]string)
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			return files, links
+		}
+		if err != nil {
+			t.Fatalf("Failed to read bundle: %v", err)
+		}
+		if header.Typeflag == tar.TypeSymlink {
+			links[header.Name] = header.Linkname
+			continue
+		}
+		content, _ := io.ReadAll(archive)
+		files[header.Name] = string(content)
+	}
+}
+
+func TestWrite(t *testing.T) {
+	var buf bytes.Buffer
+	err := Write(&buf, Options{SysfsRoot: newFakeSysfs(t), Version: "test", Run: fakeRun})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	files, links := readArchive(t, buf.Bytes())
+
+	expected := []string{
+		"manifest.json",
+		"sys/bus/usb/devices/usb1/idVendor",
+		"sys/bus/usb/devices/1-0:1.0/usb1-port2/over_current_count",
+		"sys/bus/usb/devices/1-0:1.0/usb1-port2/power/pm_qos_no_power_off",
+		"sys/bus/usb/devices/1-2/descriptors",
+		"sys/bus/usb/devices/1-2/power/control",
+		"sys/bus/usb/devices/1-2:1.0/bInterfaceClass",
+		"sys/bus/usb/devices/1-2:1.0/ttyUSB0/tty/ttyUSB0/uevent",
+		"commands/lsusb.txt",
+		"commands/lsusb-t.txt",
+		"commands/lsusb-v.txt",
+		"udev/1-2.txt",
+	}
+	for _, name := range expected {
+		if _, ok := files[name]; !ok {
+			t.Errorf("Expected %s in bundle", name)
+		}
+	}
+
+	for _, name := range []string{
+		"sys/bus/usb/devices/1-2/ep_00/type",
+		"sys/bus/usb/devices/1-2:1.0/ep_81/type",
+		"udev/1-2:1.0.txt",
+	} {
+		if _, ok := files[name]; ok {
+			t.Errorf("Did not expect %s in bundle", name)
+		}
+	}
+
+	if target := links["sys/bus/usb/devices/1-2:1.0/driver"]; !strings.HasSuffix(target, "/ftdi_sio") {
+		t.Errorf("Expected driver link to be kept, got %q", target)
+	}
+	if _, ok := links["sys/bus/usb/devices/1-2/subsystem"]; ok {
+		t.Error("Did not expect links other than driver in bundle")
+	}
+
+	var manifest Manifest
+	if err := json.Unmarshal([]byte(files["manifest.json"]), &manifest); err != nil {
+		t.Fatalf("Invalid manifest: %v", err)
+	}
+	if manifest.FormatVersion != FormatVersion || manifest.Kernel != "6.8.0-test" || manifest.UsbtreeVersion != "test" || manifest.Anonymized {
+		t.Errorf("Unexpected manifest: %+v", manifest)
+	}
+	if len(manifest.Files) != len(files)+len(links)-1 {
+		t.Errorf("Expected manifest to list %d files, got %d", len(files)+len(links)-1, len(manifest.Files))
+	}
+}
+
+func TestWrite_Anonymize(t *testing.T) {
+	anonymizer, err := anonymize.New("test-salt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	err = Write(&buf, Options{SysfsRoot: newFakeSysfs(t), Anonymizer: anonymizer, Run: fakeRun})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	files, _ := readArchive(t, buf.Bytes())
+	for name, content := range files {
+		if strings.Contains(content, "A50285BI") {
+			t.Errorf("Serial number leaked in %s", name)
+		}
+	}
+
+	pseudonym := anonymizer.Serial("A50285BI")
+	if serial := strings.TrimSpace(files["sys/bus/usb/devices/1-2/serial"]); serial != pseudonym {
+		t.Fatalf("Expected pseudonym %s in serial attribute, got %q", pseudonym, serial)
+	}
+	for _, name := range []string{"commands/lsusb-v.txt", "udev/1-2.txt"} {
+		if !strings.Contains(files[name], pseudonym) {
+			t.Errorf("Expected the same pseudonym %s in %s, got:\n%s", pseudonym, name, files[name])
+		}
+	}
+
+	if !strings.Contains(files["manifest.json"], `"anonymized": true`) {
+		t.Error("Expected manifest to record anonymization")
+	}
+}
+
+func TestWrite_Replay(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, Options{SysfsRoot: newFakeSysfs(t), Run: fakeRun}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	bundle := filepath.Join(t.TempDir(), "bundle.tar.gz")
+	if err := os.WriteFile(bundle, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	devices, err := usb.NewFileDetector(bundle, usb.InputAuto).GetDevices()
+	if err != nil {
+		t.Fatalf("Failed to replay bundle: %v", err)
+	}
+
+	if len(devices) != 1 || len(devices[0].Children) != 1 {
+		t.Fatalf("Expected root hub with one child, got %+v", devices)
+	}
+
+	ftdi := devices[0].Children[0]
+	if ftdi.PortPath != "1-2" || ftdi.Power == nil || ftdi.Power.Control != "auto" {
+		t.Errorf("Expected sysfs state to be replayed, got %+v", ftdi)
+	}
+	if len(ftdi.Interfaces) != 1 || ftdi.Interfaces[0].Driver != "ftdi_sio" {
+		t.Errorf("Expected interface with driver to be replayed, got %+v", ftdi.Interfaces)
+	}
+
+	// The /dev nodes are found in the bundle as on the machine
+	sysfs, cleanup, err := usb.InputSysfs(bundle, usb.InputAuto)
+	if err != nil {
+		t.Fatalf("Failed to unpack bundle: %v", err)
+	}
+	defer cleanup()
+	if nodes := sysfs.DevNodes("1-2"); strings.Join(nodes, " ") != "/dev/bus/usb/001/002 /dev/ttyUSB0" {
+		t.Errorf("Expected the device nodes to be replayed, got %v", nodes)
+	}
+
+	// The same bundle unpacked is a fixture root
+	dir := t.TempDir()
+	if err := usb.ExtractBundle(bytes.NewReader(buf.Bytes()), dir); err != nil {
+		t.Fatalf("Failed to extract bundle: %v", err)
+	}
+	devices, err = usb.NewFileDetector(dir, usb.InputAuto).GetDevices()
+	if err != nil {
+		t.Fatalf("Failed to replay fixture root: %v", err)
+	}
+	if len(devices) != 1 || devices[0].Children[0].Power == nil {
+		t.Errorf("Expected the extracted fixture to replay the same tree, got %+v", devices)
+	}
+}
@@ -0,0 +1,256 @@
+// Package config reads the usbtree configuration file, which sets default
+// flag values, named profiles of them, human-friendly device labels and
+// color themes.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/stegmannb/usbtree/internal/models"
+	"github.com/stegmannb/usbtree/internal/tree"
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the content of the configuration file.
+type Config struct {
+	// Defaults applies to every invocation.
+	Defaults Settings `yaml:"defaults"`
+	// Profiles are selected with --profile and override Defaults.
+	Profiles map[string]Settings `yaml:"profiles"`
+	// Profile is the profile used when --profile is not given.
+	Profile string      `yaml:"profile"`
+	Labels  []LabelRule `yaml:"labels"`
+	// Themes are user themes for --theme. A user theme replaces the
+	// built-in theme of the same name.
+	Themes map[string]tree.ThemeSpec `yaml:"themes"`
+}
+
+// Settings maps flag names to values, such as verbose: true or format:
+// json, which apply to every command with that flag. A command name maps
+// to the settings of that command only, such as serve: {listen: :8080}.
+type Settings map[string]any
+
+// EnvPrefix starts the environment variables that set flags: USBTREE_FORMAT
+// sets --format of every command, USBTREE_SERVE_LISTEN --listen of serve.
+const EnvPrefix = "USBTREE_"
+
+// EnvName returns the environment variable of a flag, optionally of one
+// command only.
+func EnvName(command, flag string) string {
+	name := flag
+	if command != "" {
+		name = command + "_" + flag
+	}
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+// LabelRule labels the devices a selector matches.
+type LabelRule struct {
+	// Match is a device selector, e.g. serial=A50285BI, 1-2.3 or 0403:6001.
+	Match string `yaml:"match"`
+	Label string `yaml:"label"`
+	// Color is the color of the label in the tree, e.g. cyan or hi-red.
+	Color string `yaml:"color"`
+	Note  string `yaml:"note"`
+
+	selector models.Selector
+}
+
+// DefaultPath returns the configuration file below $XDG_CONFIG_HOME, which
+// defaults to ~/.config.
+func DefaultPath() (string, error) {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to find the configuration: %w", err)
+		}
+		dir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(dir, "usbtree", "config.yaml"), nil
+}
+
+// Load reads the configuration file at path. A missing file is an empty
+// configuration unless required is set.
+func Load(path string, required bool) (*Config, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) && !required {
+		return &Config{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read configuration: %w", err)
+	}
+	defer file.Close()
+
+	config, err := Parse(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return config, nil
+}
+
+// Parse reads a configuration in YAML, rejecting unknown keys.
+func Parse(r io.Reader) (*Config, error) {
+	decoder := yaml.NewDecoder(r)
+	decoder.KnownFields(true)
+
+	var config Config
+	if err := decoder.Decode(&config); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	for i := range config.Labels {
+		rule := &config.Labels[i]
+		sel, err := models.ParseSelector(rule.Match)
+		if err != nil {
+			return nil, fmt.Errorf("labels[%d]: %w", i, err)
+		}
+		rule.selector = sel
+		if rule.Label == "" && rule.Note == "" {
+			return nil, fmt.Errorf("labels[%d]: no label or note for %s", i, rule.Match)
+		}
+		if rule.Color != "" {
+			if _, err := tree.ParseColor(rule.Color); err != nil {
+				return nil, fmt.Errorf("labels[%d]: %w", i, err)
+			}
+		}
+	}
+	for _, name := range sortedKeys(config.Themes) {
+		if _, err := config.Themes[name].Theme(); err != nil {
+			return nil, fmt.Errorf("themes.%s: %w", name, err)
+		}
+	}
+	return &config, nil
+}
+
+// Values returns the flag values the configuration sets for a command with
+// the given profile, or the default profile if it is empty. Settings of the
+// command override those of every command, and the profile overrides the
+// defaults.
+func (c *Config) Values(command, profile string) (map[string]string, error) {
+	if profile == "" {
+		profile = c.Profile
+	}
+	values := make(map[string]string)
+	c.Defaults.apply(values, command)
+	if profile != "" {
+		settings, ok := c.Profiles[profile]
+		if !ok {
+			return nil, fmt.Errorf("unknown profile %q", profile)
+		}
+		settings.apply(values, command)
+	}
+	return values, nil
+}
+
+// nested returns the settings of a command, which YAML decodes either as
+// Settings or as a plain map.
+func nested(value any) (Settings, bool) {
+	switch value := value.(type) {
+	case Settings:
+		return value, true
+	case map[string]any:
+		return value, true
+	}
+	return nil, false
+}
+
+func (s Settings) apply(values map[string]string, command string) {
+	for key, value := range s {
+		if _, ok := nested(value); !ok {
+			values[key] = settingString(value)
+		}
+	}
+	if settings, ok := nested(s[command]); ok {
+		for key, value := range settings {
+			values[key] = settingString(value)
+		}
+	}
+}
+
+// settingString formats a setting as it would be given on the command
+// line, with lists separated by commas.
+func settingString(value any) string {
+	switch value := value.(type) {
+	case nil:
+		return ""
+	case []any:
+		parts := make([]string, len(value))
+		for i, part := range value {
+			parts[i] = settingString(part)
+		}
+		return strings.Join(parts, ",")
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+// Check returns an error naming the first setting that known rejects. known
+// is asked whether a command has a flag; with an empty command whether any
+// command has it, and with an empty flag whether the command exists.
+func (c *Config) Check(known func(command, flag string) bool) error {
+	check := func(where string, settings Settings) error {
+		for _, key := range sortedKeys(settings) {
+			commandSettings, ok := nested(settings[key])
+			if !ok {
+				if !known("", key) {
+					return fmt.Errorf("%s: unknown flag %q", where, key)
+				}
+				continue
+			}
+			if !known(key, "") {
+				return fmt.Errorf("%s: unknown command %q", where, key)
+			}
+			for _, flag := range sortedKeys(commandSettings) {
+				if _, ok := nested(commandSettings[flag]); ok || !known(key, flag) {
+					return fmt.Errorf("%s.%s: unknown flag %q", where, key, flag)
+				}
+			}
+		}
+		return nil
+	}
+
+	if err := check("defaults", c.Defaults); err != nil {
+		return err
+	}
+	for _, name := range sortedKeys(c.Profiles) {
+		if err := check("profiles."+name, c.Profiles[name]); err != nil {
+			return err
+		}
+	}
+	if c.Profile != "" {
+		if _, ok := c.Profiles[c.Profile]; !ok {
+			return fmt.Errorf("profile: unknown profile %q", c.Profile)
+		}
+	}
+	return nil
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// ApplyLabels labels every device of the topology with the first rule that
+// matches it. Labels and notes read along with the devices, for example
+// from saved JSON, are kept for devices no rule matches.
+func (c *Config) ApplyLabels(topology *models.Topology) {
+	for _, device := range topology.All() {
+		for _, rule := range c.Labels {
+			if rule.selector.Matches(device) {
+				device.Label, device.LabelColor, device.Note = rule.Label, rule.Color, rule.Note
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,209 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stegmannb/usbtree/internal/models"
+)
+
+const labConfig = `
+labels:
+  - match: serial=A50285BI
+    label: DUT-3 console
+    color: cyan
+    note: Bench 3, left rack
+  - match: 0403:6001
+    label: Spare FTDI
+  - match: 1-2.4
+    label: Power relay board
+`
+
+func TestApplyLabels(t *testing.T) {
+	config, err := Parse(strings.NewReader(labConfig))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	console := &models.USBDevice{VendorID: 0x0403, ProductID: 0x6001, Serial: "A50285BI", PortPath: "1-2.1"}
+	spare := &models.USBDevice{VendorID: 0x0403, ProductID: 0x6001, Serial: "B1", PortPath: "1-2.2"}
+	relay := &models.USBDevice{VendorID: 0x16c0, ProductID: 0x05df, PortPath: "1-2.4"}
+	other := &models.USBDevice{VendorID: 0x046d, ProductID: 0xc52b, PortPath: "1-3", Label: "From saved JSON"}
+	hub := &models.USBDevice{PortPath: "1-2", Children: []*models.USBDevice{console, spare, relay}}
+	topology := models.NewTopology([]*models.USBDevice{{PortPath: "usb1", Children: []*models.USBDevice{hub, other}}})
+
+	config.ApplyLabels(topology)
+
+	if console.Label != "DUT-3 console" || console.LabelColor != "cyan" || console.Note != "Bench 3, left rack" {
+		t.Errorf("Expected the first matching rule to label the console, got %q %q %q", console.Label, console.LabelColor, console.Note)
+	}
+	if spare.Label != "Spare FTDI" {
+		t.Errorf("Expected the ID rule to label the spare, got %q", spare.Label)
+	}
+	if relay.Label != "Power relay board" {
+		t.Errorf("Expected the port rule to label the relay, got %q", relay.Label)
+	}
+	if other.Label != "From saved JSON" {
+		t.Errorf("Expected an unmatched device to keep its label, got %q", other.Label)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := map[string]string{
+		"unknown key":   "labels:\n  - match: 1-2\n    lable: typo\n",
+		"bad selector":  "labels:\n  - match: id=xyz\n    label: x\n",
+		"no label":      "labels:\n  - match: 1-2\n",
+		"unknown color": "labels:\n  - match: 1-2\n    label: x\n    color: plaid\n",
+		"theme color":   "themes:\n  bench:\n    name: bold plaid\n",
+		"theme key":     "themes:\n  bench:\n    colour-by: class\n",
+	}
+	for name, config := range tests {
+		if _, err := Parse(strings.NewReader(config)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestParseThemes(t *testing.T) {
+	config, err := Parse(strings.NewReader("themes:\n  bench:\n    base: speed\n    speeds:\n      high: bold green\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if spec := config.Themes["bench"]; spec.Base != "speed" || spec.Speeds["high"] != "bold green" {
+		t.Errorf("Expected the bench theme, got %+v", spec)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	missing := filepath.Join(dir, "config.yaml")
+
+	config, err := Load(missing, false)
+	if err != nil || len(config.Labels) != 0 {
+		t.Errorf("Expected an empty configuration for a missing file, got %+v, %v", config, err)
+	}
+	if _, err := Load(missing, true); err == nil {
+		t.Error("Expected an error for a missing required file")
+	}
+
+	if err := os.WriteFile(missing, []byte(labConfig), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if config, err := Load(missing, true); err != nil || len(config.Labels) != 3 {
+		t.Errorf("Expected 3 label rules, got %+v, %v", config, err)
+	}
+}
+
+func TestDefaultPath(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", "/etc/xdg-test")
+	path, err := DefaultPath()
+	if err != nil || path != "/etc/xdg-test/usbtree/config.yaml" {
+		t.Errorf("Expected the path below XDG_CONFIG_HOME, got %q, %v", path, err)
+	}
+}
+
+const profilesConfig = `
+profile: desk
+defaults:
+  verbose: true
+  format: json
+  serve:
+    listen: unix:/run/usbtree.sock
+profiles:
+  desk:
+    verbose: false
+  lab:
+    anonymize: true
+    format: yaml
+    wait:
+      timeout: 1m
+`
+
+func TestValues(t *testing.T) {
+	config, err := Parse(strings.NewReader(profilesConfig))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		command, profile string
+		expected         map[string]string
+	}{
+		{"usbtree", "", map[string]string{"verbose": "false", "format": "json"}},
+		{"serve", "", map[string]string{"verbose": "false", "format": "json", "listen": "unix:/run/usbtree.sock"}},
+		{"wait", "lab", map[string]string{"verbose": "true", "format": "yaml", "anonymize": "true", "timeout": "1m"}},
+	}
+	for _, tt := range tests {
+		values, err := config.Values(tt.command, tt.profile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(values) != len(tt.expected) {
+			t.Errorf("%s %s: expected %v, got %v", tt.command, tt.profile, tt.expected, values)
+		}
+		for key, value := range tt.expected {
+			if values[key] != value {
+				t.Errorf("%s %s: expected %s=%s, got %q", tt.command, tt.profile, key, value, values[key])
+			}
+		}
+	}
+
+	if _, err := config.Values("usbtree", "office"); err == nil {
+		t.Error("Expected an error for an unknown profile")
+	}
+}
+
+func TestCheck(t *testing.T) {
+	flags := map[string][]string{"usbtree": {"verbose", "format", "anonymize"}, "serve": {"listen"}, "wait": {"timeout"}}
+	known := func(command, flag string) bool {
+		for name, names := range flags {
+			if command != "" && name != command {
+				continue
+			}
+			if flag == "" {
+				return true
+			}
+			for _, f := range names {
+				if f == flag {
+					return true
+				}
+			}
+		}
+		return false
+	}
+
+	config, err := Parse(strings.NewReader(profilesConfig))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := config.Check(known); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	for _, bad := range []string{
+		"defaults:\n  verbos: true\n",
+		"defaults:\n  record:\n    log: x\n",
+		"defaults:\n  serve:\n    timeout: 1s\n",
+		"profiles:\n  lab:\n    formatt: json\n",
+		"profile: office\n",
+	} {
+		config, err := Parse(strings.NewReader(bad))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := config.Check(known); err == nil {
+			t.Errorf("Expected an error for %q", bad)
+		}
+	}
+}
+
+func TestEnvName(t *testing.T) {
+	if name := EnvName("", "input-format"); name != "USBTREE_INPUT_FORMAT" {
+		t.Errorf("Expected USBTREE_INPUT_FORMAT, got %s", name)
+	}
+	if name := EnvName("serve", "listen"); name != "USBTREE_SERVE_LISTEN" {
+		t.Errorf("Expected USBTREE_SERVE_LISTEN, got %s", name)
+	}
+}
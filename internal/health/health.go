@@ -0,0 +1,193 @@
+// Package health checks a device tree for common problems: interfaces
+// without drivers, devices running below their USB version, ports asked
+// for more power than they supply, trees deeper than USB allows and
+// devices in a runtime power error.
+package health
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/stegmannb/usbtree/internal/models"
+)
+
+// Severity is how serious a finding is.
+type Severity string
+
+const (
+	SeverityWarning Severity = "warning"
+	SeverityError   Severity = "error"
+)
+
+// maxTiers is the deepest tier USB allows, counting the root hub.
+const maxTiers = 7
+
+// Finding is one problem found with a device.
+type Finding struct {
+	Severity Severity          `json:"severity"`
+	Device   *models.USBDevice `json:"-"`
+	Message  string            `json:"message"`
+}
+
+// Result is the outcome of one check.
+type Result struct {
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Findings    []Finding `json:"findings,omitempty"`
+}
+
+// Status is "pass" without findings, otherwise the most severe finding.
+func (r Result) Status() string {
+	status := "pass"
+	for _, finding := range r.Findings {
+		if finding.Severity == SeverityError {
+			return string(SeverityError)
+		}
+		status = string(SeverityWarning)
+	}
+	return status
+}
+
+type check struct {
+	name        string
+	description string
+	run         func(*models.Topology) []Finding
+}
+
+var checks = []check{
+	{"drivers", "Every interface has a driver bound", checkDrivers},
+	{"speed", "USB 3 devices negotiated SuperSpeed", checkSpeed},
+	{"power", "No device draws more than its port supplies", checkPower},
+	{"tiers", fmt.Sprintf("No device is more than %d tiers deep", maxTiers), checkTiers},
+	{"runtime-power", "No device is in a runtime power error", checkRuntimePower},
+}
+
+// Run runs all checks on topology, in a fixed order.
+func Run(topology *models.Topology) []Result {
+	results := make([]Result, 0, len(checks))
+	for _, c := range checks {
+		results = append(results, Result{Name: c.name, Description: c.description, Findings: c.run(topology)})
+	}
+	return results
+}
+
+// checkDrivers finds interfaces without a driver. Sources that report no
+// drivers at all, such as system_profiler, are not checked.
+func checkDrivers(topology *models.Topology) []Finding {
+	reported := len(topology.Find(func(device *models.USBDevice) bool {
+		for _, iface := range device.Interfaces {
+			if iface.Driver != "" {
+				return true
+			}
+		}
+		return false
+	})) > 0
+	if !reported {
+		return nil
+	}
+
+	var findings []Finding
+	for _, device := range topology.All() {
+		for _, iface := range device.Interfaces {
+			if iface.Driver == "" {
+				findings = append(findings, Finding{SeverityWarning, device,
+					fmt.Sprintf("Interface %d (%s) has no driver bound", iface.Number, iface.ClassTriple())})
+			}
+		}
+	}
+	return findings
+}
+
+// checkSpeed finds USB 3 devices connected at USB 2 speeds, typically
+// through a USB 2 cable, hub or port.
+func checkSpeed(topology *models.Topology) []Finding {
+	var findings []Finding
+	for _, device := range topology.All() {
+		if device.Descriptor == nil || device.Parent == nil {
+			continue
+		}
+		version, err := strconv.ParseFloat(strings.TrimSpace(device.Descriptor.USBVersion), 64)
+		if err != nil || version < 3 {
+			continue
+		}
+		if speed := device.SpeedMbps(); speed > 0 && speed < 5000 {
+			findings = append(findings, Finding{SeverityWarning, device,
+				fmt.Sprintf("USB %s device connected at %s", device.Descriptor.USBVersion, device.Speed)})
+		}
+	}
+	return findings
+}
+
+// PortBudget is the current a port supplies at the speed of the device
+// connected to it: 900mA for SuperSpeed, 500mA otherwise.
+func PortBudget(device *models.USBDevice) int {
+	if device.SpeedMbps() >= 5000 {
+		return 900
+	}
+	return 500
+}
+
+// checkPower finds devices that declare a higher maximum power than their
+// port supplies.
+func checkPower(topology *models.Topology) []Finding {
+	var findings []Finding
+	for _, device := range topology.All() {
+		if device.Parent == nil {
+			continue
+		}
+		if power, budget := device.MaxPowerMilliamps(), PortBudget(device); power > budget {
+			findings = append(findings, Finding{SeverityError, device,
+				fmt.Sprintf("Draws up to %dmA, more than the %dmA its port supplies", power, budget)})
+		}
+	}
+	return findings
+}
+
+// checkTiers finds devices beyond the seventh tier, which USB does not
+// support.
+func checkTiers(topology *models.Topology) []Finding {
+	var findings []Finding
+	for _, device := range topology.All() {
+		if device.Tier() > maxTiers {
+			findings = append(findings, Finding{SeverityError, device,
+				fmt.Sprintf("Connected at tier %d, USB supports %d", device.Tier(), maxTiers)})
+		}
+	}
+	return findings
+}
+
+// checkRuntimePower finds devices the kernel failed to suspend or resume.
+func checkRuntimePower(topology *models.Topology) []Finding {
+	var findings []Finding
+	for _, device := range topology.All() {
+		if device.Power != nil && device.Power.RuntimeStatus == "error" {
+			findings = append(findings, Finding{SeverityError, device, "Runtime power management reported an error"})
+		}
+	}
+	return findings
+}
+
+// PowerTotal is the power declared by the devices of one bus.
+type PowerTotal struct {
+	Bus int
+	// Milliamps is the sum of the maximum power of all devices below the
+	// root hub
+	Milliamps int
+	Devices   int
+}
+
+// PowerTotals sums the maximum power of the devices on every bus, in the
+// order of the root hubs.
+func PowerTotals(topology *models.Topology) []PowerTotal {
+	var totals []PowerTotal
+	for _, root := range topology.Devices {
+		total := PowerTotal{Bus: root.Bus}
+		for _, device := range topology.Descendants(root) {
+			total.Milliamps += device.MaxPowerMilliamps()
+			total.Devices++
+		}
+		totals = append(totals, total)
+	}
+	return totals
+}
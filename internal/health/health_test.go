@@ -0,0 +1,82 @@
+package health
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stegmannb/usbtree/internal/models"
+)
+
+func results(t *testing.T, devices ...*models.USBDevice) map[string]Result {
+	t.Helper()
+	root := &models.USBDevice{Bus: 1, Class: "Hub", Speed: "Super (5 Gbps)", Children: devices}
+	byName := make(map[string]Result)
+	for _, result := range Run(models.NewTopology([]*models.USBDevice{root})) {
+		byName[result.Name] = result
+	}
+	return byName
+}
+
+func TestRun_Pass(t *testing.T) {
+	ssd := &models.USBDevice{Speed: "Super (5 Gbps)", MaxPower: "896mA",
+		Descriptor: &models.DeviceDescriptor{USBVersion: "3.20"},
+		Interfaces: []models.Interface{{Class: 0x08, Driver: "uas"}}}
+
+	checked := results(t, ssd)
+	if len(checked) != len(checks) {
+		t.Fatalf("Expected %d results, got %d", len(checks), len(checked))
+	}
+	for name, result := range checked {
+		if result.Status() != "pass" {
+			t.Errorf("Expected %s to pass, got %v", name, result.Findings)
+		}
+	}
+}
+
+func TestRun_Findings(t *testing.T) {
+	ssd := &models.USBDevice{ProductName: "SSD", Speed: "High (480 Mbps)", MaxPower: "896mA",
+		Descriptor: &models.DeviceDescriptor{USBVersion: "3.20"},
+		Interfaces: []models.Interface{{Class: 0x08, Driver: "uas"}, {Number: 1, Class: 0xff}}}
+
+	// Six hubs below the root hub put the device at tier 8
+	deep := &models.USBDevice{ProductName: "Deep"}
+	for i := 0; i < 6; i++ {
+		deep = &models.USBDevice{Class: "Hub", Children: []*models.USBDevice{deep}}
+	}
+	faulty := &models.USBDevice{Power: &models.PowerState{RuntimeStatus: "error"}}
+
+	checked := results(t, ssd, deep, faulty)
+	expected := map[string]string{
+		"drivers":       "warning: Interface 1 (ff:00:00) has no driver bound",
+		"speed":         "warning: USB 3.20 device connected at High (480 Mbps)",
+		"power":         "error: Draws up to 896mA, more than the 500mA its port supplies",
+		"tiers":         "error: Connected at tier 8, USB supports 7",
+		"runtime-power": "error: Runtime power management reported an error",
+	}
+	for name, message := range expected {
+		result := checked[name]
+		var got []string
+		for _, finding := range result.Findings {
+			got = append(got, string(finding.Severity)+": "+finding.Message)
+		}
+		if strings.Join(got, "; ") != message {
+			t.Errorf("%s: expected %q, got %q", name, message, got)
+		}
+		if severity, _, _ := strings.Cut(message, ":"); result.Status() != severity {
+			t.Errorf("%s: expected status %s, got %s", name, severity, result.Status())
+		}
+	}
+}
+
+func TestPowerTotals(t *testing.T) {
+	hub := &models.USBDevice{Bus: 2, MaxPower: "100mA", Children: []*models.USBDevice{{MaxPower: "500mA"}, {}}}
+	topology := models.NewTopology([]*models.USBDevice{
+		{Bus: 1},
+		{Bus: 2, MaxPower: "0mA", Children: []*models.USBDevice{hub}},
+	})
+
+	totals := PowerTotals(topology)
+	if len(totals) != 2 || totals[0] != (PowerTotal{Bus: 1}) || totals[1] != (PowerTotal{Bus: 2, Milliamps: 600, Devices: 3}) {
+		t.Errorf("Unexpected totals %+v", totals)
+	}
+}
@@ -0,0 +1,234 @@
+// Package history keeps a log of hotplug events in JSON Lines files, so
+// intermittent disconnects can be found after the fact, and summarizes it
+// per device.
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/stegmannb/usbtree/internal/hotplug"
+	"github.com/stegmannb/usbtree/internal/models"
+)
+
+// ActionPresent records a device that was connected when recording
+// started. It is not an attach and so does not count as one.
+const ActionPresent hotplug.Action = "present"
+
+// Record is one line of the log.
+type Record struct {
+	Time        time.Time      `json:"time"`
+	Action      hotplug.Action `json:"action"`
+	PortPath    string         `json:"port_path"`
+	VendorID    uint16         `json:"vendor_id"`
+	ProductID   uint16         `json:"product_id"`
+	VendorName  string         `json:"vendor_name,omitempty"`
+	ProductName string         `json:"product_name,omitempty"`
+	Serial      string         `json:"serial,omitempty"`
+	Speed       string         `json:"speed,omitempty"`
+	Drivers     []string       `json:"drivers,omitempty"`
+	// Changes names what changed for hotplug.ActionChange.
+	Changes []string `json:"changes,omitempty"`
+}
+
+// NewRecord returns the record of an event.
+func NewRecord(event hotplug.Event) Record {
+	device := event.Device
+	record := Record{
+		Time:        event.Time,
+		Action:      event.Action,
+		PortPath:    hotplug.Key(device),
+		VendorID:    device.VendorID,
+		ProductID:   device.ProductID,
+		VendorName:  device.VendorName,
+		ProductName: device.ProductName,
+		Serial:      device.Serial,
+		Speed:       device.Speed,
+		Changes:     event.Changes,
+	}
+	for _, iface := range device.Interfaces {
+		if iface.Driver != "" {
+			record.Drivers = append(record.Drivers, iface.Driver)
+		}
+	}
+	return record
+}
+
+// Device returns the device of the record, with the attributes selectors
+// match on.
+func (r Record) Device() *models.USBDevice {
+	return &models.USBDevice{
+		VendorID:    r.VendorID,
+		ProductID:   r.ProductID,
+		VendorName:  r.VendorName,
+		ProductName: r.ProductName,
+		Serial:      r.Serial,
+		Speed:       r.Speed,
+		PortPath:    r.PortPath,
+	}
+}
+
+// Identity tells devices apart across ports: by ID and serial number, or
+// by ID and port for devices without a serial number.
+func (r Record) Identity() string {
+	id := fmt.Sprintf("%04x:%04x", r.VendorID, r.ProductID)
+	if r.Serial != "" {
+		return id + "/" + r.Serial
+	}
+	return id + "@" + r.PortPath
+}
+
+// Default rotation limits of a Log.
+const (
+	DefaultMaxSize = 10 << 20
+	DefaultKeep    = 5
+)
+
+// DefaultPath returns the log below $XDG_STATE_HOME, which defaults to
+// ~/.local/state.
+func DefaultPath() (string, error) {
+	dir := os.Getenv("XDG_STATE_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to find the event log: %w", err)
+		}
+		dir = filepath.Join(home, ".local", "state")
+	}
+	return filepath.Join(dir, "usbtree", "events.jsonl"), nil
+}
+
+// Log appends records to a file, rotating it to path.1, path.2 and so on
+// once it outgrows MaxSize.
+type Log struct {
+	path string
+	// MaxSize is the size in bytes after which the file is rotated.
+	MaxSize int64
+	// Keep is how many rotated files are kept.
+	Keep int
+
+	file *os.File
+	size int64
+}
+
+// OpenLog opens the log at path for appending, creating it and its
+// directory if needed, with the default rotation limits.
+func OpenLog(path string) (*Log, error) {
+	l := &Log{path: path, MaxSize: DefaultMaxSize, Keep: DefaultKeep}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create log directory: %w", err)
+	}
+	if err := l.open(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+func (l *Log) open() error {
+	file, err := os.OpenFile(l.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open event log: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to open event log: %w", err)
+	}
+	l.file, l.size = file, info.Size()
+	return nil
+}
+
+// Append writes a record as one line, rotating the file first if the line
+// would make it outgrow MaxSize.
+func (l *Log) Append(record Record) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	if l.size > 0 && l.size+int64(len(line)) > l.MaxSize {
+		if err := l.rotate(); err != nil {
+			return err
+		}
+	}
+	n, err := l.file.Write(line)
+	l.size += int64(n)
+	if err != nil {
+		return fmt.Errorf("failed to write event log: %w", err)
+	}
+	return nil
+}
+
+// rotate shifts path.N to path.N+1, dropping the oldest, and starts a new
+// file.
+func (l *Log) rotate() error {
+	l.file.Close()
+	os.Remove(rotated(l.path, l.Keep))
+	for i := l.Keep - 1; i >= 1; i-- {
+		os.Rename(rotated(l.path, i), rotated(l.path, i+1))
+	}
+	if l.Keep > 0 {
+		if err := os.Rename(l.path, rotated(l.path, 1)); err != nil {
+			return fmt.Errorf("failed to rotate event log: %w", err)
+		}
+	} else {
+		os.Remove(l.path)
+	}
+	return l.open()
+}
+
+func rotated(path string, n int) string {
+	return path + "." + strconv.Itoa(n)
+}
+
+// Close closes the file.
+func (l *Log) Close() error {
+	return l.file.Close()
+}
+
+// ReadLog reads the records of the log at path and of its rotated files,
+// oldest first. Lines that are not records, such as one cut short by a
+// crash, are skipped.
+func ReadLog(path string) ([]Record, error) {
+	var paths []string
+	for i := 1; ; i++ {
+		if _, err := os.Stat(rotated(path, i)); err != nil {
+			break
+		}
+		paths = append([]string{rotated(path, i)}, paths...)
+	}
+	paths = append(paths, path)
+
+	var records []Record
+	for i, p := range paths {
+		file, err := os.Open(p)
+		if errors.Is(err, fs.ErrNotExist) && i == len(paths)-1 && len(paths) > 1 {
+			// Rotated but nothing recorded since
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read event log: %w", err)
+		}
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			var record Record
+			if json.Unmarshal(scanner.Bytes(), &record) == nil && !record.Time.IsZero() {
+				records = append(records, record)
+			}
+		}
+		err = scanner.Err()
+		file.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read event log: %w", err)
+		}
+	}
+	return records, nil
+}
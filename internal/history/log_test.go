@@ -0,0 +1,111 @@
+package history
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stegmannb/usbtree/internal/hotplug"
+	"github.com/stegmannb/usbtree/internal/models"
+)
+
+func TestNewRecord(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	device := &models.USBDevice{
+		VendorID: 0x0403, ProductID: 0x6001, ProductName: "FT232R USB UART", Serial: "A50285BI",
+		PortPath: "1-2.3", Speed: "Full (12 Mbps)",
+		Interfaces: []models.Interface{{Number: 0, Driver: "ftdi_sio"}, {Number: 1}},
+	}
+	record := NewRecord(hotplug.Event{Time: now, Action: hotplug.ActionAdd, Device: device})
+
+	if record.PortPath != "1-2.3" || record.Speed != "Full (12 Mbps)" || !record.Time.Equal(now) {
+		t.Errorf("Unexpected record %+v", record)
+	}
+	if len(record.Drivers) != 1 || record.Drivers[0] != "ftdi_sio" {
+		t.Errorf("Expected the bound driver, got %v", record.Drivers)
+	}
+	if record.Identity() != "0403:6001/A50285BI" {
+		t.Errorf("Expected identity by serial, got %q", record.Identity())
+	}
+
+	sel, _ := models.ParseSelector("serial=A50285BI")
+	if !sel.Matches(record.Device()) {
+		t.Error("Expected the selector to match the record")
+	}
+}
+
+func TestLogRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "events.jsonl")
+	log, err := OpenLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	log.MaxSize = 300
+	log.Keep = 2
+
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 12; i++ {
+		record := Record{Time: start.Add(time.Duration(i) * time.Minute), Action: hotplug.ActionAdd, PortPath: "1-2", VendorID: uint16(i)}
+		if err := log.Append(record); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := log.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{path, path + ".1", path + ".2"} {
+		info, err := os.Stat(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Size() > 300 {
+			t.Errorf("Expected %s to be rotated at 300 bytes, is %d", name, info.Size())
+		}
+	}
+	if _, err := os.Stat(path + ".3"); err == nil {
+		t.Error("Expected only 2 rotated files to be kept")
+	}
+
+	records, err := ReadLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) == 0 || len(records) >= 12 {
+		t.Fatalf("Expected the oldest records to be rotated out, got %d", len(records))
+	}
+	for i := 1; i < len(records); i++ {
+		if !records[i].Time.After(records[i-1].Time) {
+			t.Fatalf("Expected records oldest first, got %v before %v", records[i-1].Time, records[i].Time)
+		}
+	}
+	if last := records[len(records)-1]; last.VendorID != 11 {
+		t.Errorf("Expected the newest record last, got %+v", last)
+	}
+}
+
+func TestReadLogSkipsBrokenLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	data := `{"time":"2024-05-01T12:00:00Z","action":"add","port_path":"1-2","vendor_id":1027,"product_id":24577}
+not json
+{"time":"2024-05-01T12:01:00Z","action":"remove","port_path":"1-2","vendor_id":1027,"product_id":24577}
+{"time":"2024-05-01T12:02:00Z","act`
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	records, err := ReadLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 || records[1].Action != hotplug.ActionRemove {
+		t.Errorf("Expected 2 records, got %+v", records)
+	}
+}
+
+func TestReadLogMissing(t *testing.T) {
+	if _, err := ReadLog(filepath.Join(t.TempDir(), "events.jsonl")); err == nil {
+		t.Error("Expected an error for a missing log")
+	}
+}
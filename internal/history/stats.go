@@ -0,0 +1,171 @@
+package history
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/stegmannb/usbtree/internal/hotplug"
+	"github.com/stegmannb/usbtree/internal/models"
+)
+
+// Filter selects records by device and time range.
+type Filter struct {
+	// Selector matches the device of a record if set.
+	Selector *models.Selector
+	// Since and Until bound the time range if set.
+	Since, Until time.Time
+}
+
+// Matches reports whether the record passes the filter.
+func (f Filter) Matches(record Record) bool {
+	if f.Selector != nil && !f.Selector.Matches(record.Device()) {
+		return false
+	}
+	return f.InRange(record.Time)
+}
+
+// InRange reports whether a time is within the range of the filter.
+func (f Filter) InRange(t time.Time) bool {
+	return (f.Since.IsZero() || !t.Before(f.Since)) && (f.Until.IsZero() || t.Before(f.Until))
+}
+
+// Range returns the time range the filter selects from records: from Since,
+// or the first record, to Until, or now.
+func (f Filter) Range(records []Record, now time.Time) (from, to time.Time) {
+	from, to = f.Since, f.Until
+	if from.IsZero() && len(records) > 0 {
+		from = records[0].Time
+	}
+	if to.IsZero() {
+		to = now
+	}
+	return from, to
+}
+
+// ParseTime parses a time given to history --since or --until: a duration
+// before now such as 24h, a date such as 2024-05-01, a date and time such as
+// 2024-05-01 12:00 in local time, or RFC 3339.
+func ParseTime(s string, now time.Time) (time.Time, error) {
+	if d, err := time.ParseDuration(s); err == nil {
+		return now.Add(-d), nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	for _, layout := range []string{"2006-01-02 15:04:05", "2006-01-02 15:04", "2006-01-02T15:04", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, s, now.Location()); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q: expected a duration such as 24h, a date such as 2024-05-01 or RFC 3339", s)
+}
+
+// Stats summarizes the records of one device.
+type Stats struct {
+	// Last is the latest record of the device up to the end of the range.
+	Last     Record `json:"last"`
+	Attaches int    `json:"attaches"`
+	Detaches int    `json:"detaches"`
+	// Flaps counts reconnections: attaches that follow a detach.
+	Flaps int `json:"flaps"`
+	// Uptime is how long the device was connected within the range.
+	Uptime    time.Duration `json:"uptime_ns"`
+	Connected bool          `json:"connected"`
+}
+
+// Summarize returns the stats of every device with a record matching the
+// filter or connected during its range, in the order they first appear,
+// and the length of the range their uptime is measured in. records must be
+// in time order and may start before the range, which tells the state of
+// devices at its start. A device first recorded as present when recording
+// started counts as connected from that record on, as nothing is known of it
+// before; one first recorded as changed is taken to have been connected
+// since the start of the range. The range ends at now unless the filter
+// bounds it.
+func Summarize(records []Record, filter Filter, now time.Time) (stats []*Stats, window time.Duration) {
+	from, to := filter.Range(records, now)
+	overlap := func(start, end time.Time) time.Duration {
+		if start.Before(from) {
+			start = from
+		}
+		if end.After(to) {
+			end = to
+		}
+		if end.Before(start) {
+			return 0
+		}
+		return end.Sub(start)
+	}
+
+	type state struct {
+		stats       *Stats
+		seen        bool
+		connected   bool
+		since       time.Time
+		lastRemoved bool
+		inRange     bool
+	}
+	states := make(map[string]*state)
+	var order []*state
+
+	for _, record := range records {
+		if !record.Time.Before(to) {
+			break
+		}
+		if filter.Selector != nil && !filter.Selector.Matches(record.Device()) {
+			continue
+		}
+		s := states[record.Identity()]
+		if s == nil {
+			s = &state{stats: &Stats{}}
+			states[record.Identity()] = s
+			order = append(order, s)
+		}
+		inRange := filter.InRange(record.Time)
+		s.inRange = s.inRange || inRange
+
+		switch record.Action {
+		case hotplug.ActionRemove:
+			if s.connected {
+				s.stats.Uptime += overlap(s.since, record.Time)
+			} else if !s.seen {
+				s.stats.Uptime += overlap(from, record.Time)
+			}
+			s.connected = false
+			if inRange {
+				s.stats.Detaches++
+			}
+		case hotplug.ActionAdd:
+			if !s.connected {
+				s.connected, s.since = true, record.Time
+			}
+			if inRange {
+				s.stats.Attaches++
+				if s.lastRemoved {
+					s.stats.Flaps++
+				}
+			}
+		default:
+			if !s.connected {
+				s.connected, s.since = true, record.Time
+				if !s.seen && record.Action == hotplug.ActionChange {
+					s.since = from
+				}
+			}
+		}
+		s.seen = true
+		s.lastRemoved = record.Action == hotplug.ActionRemove
+		s.stats.Last = record
+	}
+
+	for _, s := range order {
+		if s.connected {
+			s.stats.Uptime += overlap(s.since, to)
+		}
+		s.stats.Connected = s.connected
+		if s.inRange || s.stats.Uptime > 0 {
+			stats = append(stats, s.stats)
+		}
+	}
+	return stats, to.Sub(from)
+}
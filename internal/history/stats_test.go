@@ -0,0 +1,137 @@
+package history
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stegmannb/usbtree/internal/hotplug"
+	"github.com/stegmannb/usbtree/internal/models"
+)
+
+var start = time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+
+func record(minutes int, action hotplug.Action, serial string) Record {
+	return Record{
+		Time: start.Add(time.Duration(minutes) * time.Minute), Action: action,
+		PortPath: "1-2", VendorID: 0x0403, ProductID: 0x6001, Serial: serial,
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	records := []Record{
+		record(0, ActionPresent, "A"),
+		record(0, ActionPresent, "B"),
+		record(10, hotplug.ActionRemove, "A"),
+		record(11, hotplug.ActionAdd, "A"),
+		record(20, hotplug.ActionRemove, "A"),
+		record(22, hotplug.ActionAdd, "A"),
+		record(30, hotplug.ActionRemove, "B"),
+	}
+	stats, window := Summarize(records, Filter{}, start.Add(60*time.Minute))
+	if len(stats) != 2 || window != 60*time.Minute {
+		t.Fatalf("Expected 2 devices over 1h, got %d over %v", len(stats), window)
+	}
+
+	a, b := stats[0], stats[1]
+	if a.Attaches != 2 || a.Detaches != 2 || a.Flaps != 2 || !a.Connected {
+		t.Errorf("Unexpected stats for A: %+v", a)
+	}
+	if a.Uptime != 57*time.Minute {
+		t.Errorf("Expected A up for 57m, got %v", a.Uptime)
+	}
+	if b.Detaches != 1 || b.Flaps != 0 || b.Connected || b.Uptime != 30*time.Minute {
+		t.Errorf("Unexpected stats for B: %+v", b)
+	}
+}
+
+func TestSummarizeRange(t *testing.T) {
+	records := []Record{
+		record(0, hotplug.ActionAdd, "A"),
+		record(30, hotplug.ActionRemove, "A"),
+		record(40, hotplug.ActionAdd, "A"),
+	}
+	filter := Filter{Since: start.Add(20 * time.Minute), Until: start.Add(50 * time.Minute)}
+	stats, _ := Summarize(records, filter, start.Add(time.Hour))
+	if len(stats) != 1 {
+		t.Fatalf("Expected 1 device, got %d", len(stats))
+	}
+	// Up from 20 to 30 and from 40 to 50; the attach at 0 is out of range
+	if s := stats[0]; s.Uptime != 20*time.Minute || s.Attaches != 1 || s.Detaches != 1 || s.Flaps != 1 {
+		t.Errorf("Unexpected stats %+v", s)
+	}
+
+	// A device connected throughout the range has no record in it
+	always := []Record{record(0, hotplug.ActionAdd, "C")}
+	if stats, _ := Summarize(always, filter, start.Add(time.Hour)); len(stats) != 1 || stats[0].Uptime != 30*time.Minute {
+		t.Errorf("Expected C up for the whole range, got %+v", stats)
+	}
+}
+
+func TestSummarizeFirstRecord(t *testing.T) {
+	// A present when recording restarted is known to be connected from then
+	// on; B changed before any attach, so it was connected all along
+	records := []Record{
+		record(0, hotplug.ActionAdd, "C"),
+		record(10, ActionPresent, "A"),
+		record(10, hotplug.ActionChange, "B"),
+	}
+	stats, _ := Summarize(records, Filter{}, start.Add(time.Hour))
+	if len(stats) != 3 {
+		t.Fatalf("Expected 3 devices, got %d", len(stats))
+	}
+	if a, b := stats[1], stats[2]; a.Uptime != 50*time.Minute || b.Uptime != time.Hour {
+		t.Errorf("Expected A up for 50m and B for 1h, got %v and %v", a.Uptime, b.Uptime)
+	}
+
+	// --since a week back, before the log began: no uptime before recording
+	stats, window := Summarize(records, Filter{Since: start.Add(-7 * 24 * time.Hour)}, start.Add(time.Hour))
+	if len(stats) != 3 || window != 7*24*time.Hour+time.Hour {
+		t.Fatalf("Expected 3 devices over a week and 1h, got %d over %v", len(stats), window)
+	}
+	if c, a := stats[0], stats[1]; c.Uptime != time.Hour || a.Uptime != 50*time.Minute {
+		t.Errorf("Expected C up for 1h and A for 50m, got %v and %v", c.Uptime, a.Uptime)
+	}
+}
+
+func TestSummarizeSinceBoundary(t *testing.T) {
+	// Connected from 0 to 40, summarized from 20 to 60
+	records := []Record{
+		record(0, hotplug.ActionAdd, "A"),
+		record(40, hotplug.ActionRemove, "A"),
+	}
+	stats, window := Summarize(records, Filter{Since: start.Add(20 * time.Minute)}, start.Add(time.Hour))
+	if len(stats) != 1 {
+		t.Fatalf("Expected 1 device, got %d", len(stats))
+	}
+	if stats[0].Uptime != 20*time.Minute || window != 40*time.Minute {
+		t.Errorf("Expected A up for 20m of 40m, got %v of %v", stats[0].Uptime, window)
+	}
+}
+
+func TestSummarizeSelector(t *testing.T) {
+	sel, _ := models.ParseSelector("serial=B")
+	records := []Record{record(0, hotplug.ActionAdd, "A"), record(1, hotplug.ActionAdd, "B")}
+	stats, _ := Summarize(records, Filter{Selector: &sel}, start.Add(time.Hour))
+	if len(stats) != 1 || stats[0].Last.Serial != "B" {
+		t.Errorf("Expected only B, got %+v", stats)
+	}
+}
+
+func TestParseTime(t *testing.T) {
+	now := time.Date(2024, 5, 2, 12, 0, 0, 0, time.UTC)
+	tests := map[string]time.Time{
+		"24h":                  now.Add(-24 * time.Hour),
+		"2024-05-01":           time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC),
+		"2024-05-01 08:30":     time.Date(2024, 5, 1, 8, 30, 0, 0, time.UTC),
+		"2024-05-01T08:30:00Z": time.Date(2024, 5, 1, 8, 30, 0, 0, time.UTC),
+	}
+	for input, expected := range tests {
+		got, err := ParseTime(input, now)
+		if err != nil || !got.Equal(expected) {
+			t.Errorf("ParseTime(%q) = %v, %v, expected %v", input, got, err, expected)
+		}
+	}
+	if _, err := ParseTime("yesterday", now); err == nil {
+		t.Error("Expected an error for yesterday")
+	}
+}
@@ -0,0 +1,233 @@
+// Package hotplug reports devices being attached, detached and changed. It
+// compares successive enumerations, refreshed on kernel uevents where the
+// platform delivers them and at an interval otherwise.
+package hotplug
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/stegmannb/usbtree/internal/models"
+)
+
+// DefaultInterval is how often Watch enumerates when no interval is given.
+// With uevents it only catches changes the kernel does not announce.
+const DefaultInterval = 2 * time.Second
+
+// debounce collects the burst of uevents a single attach causes (device,
+// interfaces, endpoints, class devices) into one enumeration.
+const debounce = 200 * time.Millisecond
+
+// Action is what happened to a device.
+type Action string
+
+const (
+	ActionAdd    Action = "add"
+	ActionRemove Action = "remove"
+	ActionChange Action = "change"
+)
+
+// Event is one device attached, detached or changed.
+type Event struct {
+	Time   time.Time         `json:"time"`
+	Action Action            `json:"action"`
+	Device *models.USBDevice `json:"device"`
+	// Changes names what changed for ActionChange: speed, drivers or
+	// max_power.
+	Changes []string `json:"changes,omitempty"`
+}
+
+// Key identifies the position of a device across enumerations: its port
+// path, or its bus and address where the source has no port paths.
+func Key(device *models.USBDevice) string {
+	if device.PortPath != "" {
+		return device.PortPath
+	}
+	return fmt.Sprintf("bus%d-%d", device.Bus, device.Address)
+}
+
+// identity tells apart two devices plugged into the same port one after
+// the other.
+func identity(device *models.USBDevice) string {
+	return device.GetIDString() + "/" + device.Serial
+}
+
+func drivers(device *models.USBDevice) []string {
+	var drivers []string
+	for _, iface := range device.Interfaces {
+		drivers = append(drivers, iface.Driver)
+	}
+	return drivers
+}
+
+// changes lists the attributes that differ between two enumerations of the
+// same device.
+func changes(before, after *models.USBDevice) []string {
+	var changed []string
+	if before.Speed != after.Speed {
+		changed = append(changed, "speed")
+	}
+	if !slices.Equal(drivers(before), drivers(after)) {
+		changed = append(changed, "drivers")
+	}
+	if before.MaxPower != after.MaxPower {
+		changed = append(changed, "max_power")
+	}
+	return changed
+}
+
+// Diff returns the events that turn the before topology into the after
+// topology: removals in before's walk order, then additions and changes in
+// after's. A nil before topology counts as empty.
+func Diff(before, after *models.Topology, now time.Time) []Event {
+	old := make(map[string]*models.USBDevice)
+	if before != nil {
+		for _, device := range before.All() {
+			old[Key(device)] = device
+		}
+	}
+	current := make(map[string]*models.USBDevice)
+	for _, device := range after.All() {
+		current[Key(device)] = device
+	}
+
+	var events []Event
+	if before != nil {
+		for _, device := range before.All() {
+			if found := current[Key(device)]; found == nil || identity(found) != identity(device) {
+				events = append(events, Event{Action: ActionRemove, Device: device})
+			}
+		}
+	}
+	for _, device := range after.All() {
+		was := old[Key(device)]
+		switch {
+		case was == nil || identity(was) != identity(device):
+			events = append(events, Event{Action: ActionAdd, Device: device})
+		default:
+			if changed := changes(was, device); len(changed) > 0 {
+				events = append(events, Event{Action: ActionChange, Device: device, Changes: changed})
+			}
+		}
+	}
+
+	for i := range events {
+		events[i].Time = now
+	}
+	return events
+}
+
+// Update is the result of one enumeration by Watch.
+type Update struct {
+	Time     time.Time
+	Topology *models.Topology
+	// Events since the previous update; empty for the first one.
+	Events []Event
+	// Err is set when the enumeration failed; the other fields are then
+	// unset and the previous topology stays current.
+	Err error
+}
+
+// Options controls Watch.
+type Options struct {
+	// Interval between enumerations; DefaultInterval if zero.
+	Interval time.Duration
+	// NoUevents disables listening for kernel uevents, so devices are
+	// only enumerated at the interval.
+	NoUevents bool
+}
+
+// Watch enumerates devices with detect and sends an update with the
+// initial topology, then one after every enumeration that found events or
+// failed. The channel is closed once ctx is done.
+func Watch(ctx context.Context, detect func() (*models.Topology, error), opts Options) <-chan Update {
+	interval := opts.Interval
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+
+	var uevents <-chan struct{}
+	if !opts.NoUevents {
+		// Without uevents, for example on macOS or in containers, the
+		// interval alone drives enumeration
+		uevents, _ = listenUevents(ctx)
+	}
+
+	updates := make(chan Update)
+	go func() {
+		defer close(updates)
+
+		var current *models.Topology
+		send := func(update Update) bool {
+			select {
+			case updates <- update:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+		refresh := func() bool {
+			now := time.Now()
+			topology, err := detect()
+			if err != nil {
+				return send(Update{Time: now, Err: err})
+			}
+			events := Diff(current, topology, now)
+			first := current == nil
+			current = topology
+			if first {
+				return send(Update{Time: now, Topology: topology})
+			}
+			if len(events) == 0 {
+				return true
+			}
+			return send(Update{Time: now, Topology: topology, Events: events})
+		}
+
+		if !refresh() {
+			return
+		}
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			case _, ok := <-uevents:
+				if !ok {
+					uevents = nil
+					continue
+				}
+				drain(ctx, uevents, debounce)
+			}
+			if !refresh() {
+				return
+			}
+		}
+	}()
+
+	return updates
+}
+
+// drain waits until no uevent arrived for the given time.
+func drain(ctx context.Context, uevents <-chan struct{}, quiet time.Duration) {
+	timer := time.NewTimer(quiet)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+			return
+		case _, ok := <-uevents:
+			if !ok {
+				return
+			}
+			timer.Reset(quiet)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package hotplug
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stegmannb/usbtree/internal/models"
+)
+
+func testTopology(devices ...*models.USBDevice) *models.Topology {
+	root := &models.USBDevice{VendorID: 0x1d6b, ProductID: 0x0002, Class: "Hub", PortPath: "usb1", Children: devices}
+	return models.NewTopology([]*models.USBDevice{root})
+}
+
+func ftdi() *models.USBDevice {
+	return &models.USBDevice{
+		VendorID: 0x0403, ProductID: 0x6001, Serial: "A50285BI", PortPath: "1-2", Speed: "Full (12 Mbps)",
+		Interfaces: []models.Interface{{Number: 0, Class: 0xff, Driver: "ftdi_sio"}},
+	}
+}
+
+func actions(events []Event) []string {
+	var result []string
+	for _, event := range events {
+		result = append(result, string(event.Action)+" "+Key(event.Device))
+	}
+	return result
+}
+
+func TestDiff(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	keyboard := &models.USBDevice{VendorID: 0x046d, ProductID: 0xc52b, PortPath: "1-3"}
+
+	tests := []struct {
+		name     string
+		before   *models.Topology
+		after    *models.Topology
+		expected []string
+	}{
+		{"initial", nil, testTopology(ftdi()), []string{"add usb1", "add 1-2"}},
+		{"unchanged", testTopology(ftdi()), testTopology(ftdi()), nil},
+		{"attach", testTopology(ftdi()), testTopology(ftdi(), keyboard), []string{"add 1-3"}},
+		{"detach", testTopology(ftdi(), keyboard), testTopology(ftdi()), []string{"remove 1-3"}},
+		{"replace", testTopology(ftdi()), testTopology(&models.USBDevice{VendorID: 0x10c4, ProductID: 0xea60, PortPath: "1-2"}),
+			[]string{"remove 1-2", "add 1-2"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events := Diff(tt.before, tt.after, now)
+			if got := actions(events); strings.Join(got, ";") != strings.Join(tt.expected, ";") {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+			for _, event := range events {
+				if !event.Time.Equal(now) {
+					t.Errorf("Expected event time %v, got %v", now, event.Time)
+				}
+			}
+		})
+	}
+}
+
+func TestDiff_Change(t *testing.T) {
+	slow := ftdi()
+	slow.Speed = "Low (1.5 Mbps)"
+	slow.Interfaces[0].Driver = ""
+
+	events := Diff(testTopology(ftdi()), testTopology(slow), time.Now())
+	if len(events) != 1 || events[0].Action != ActionChange {
+		t.Fatalf("Expected one change, got %v", actions(events))
+	}
+	if strings.Join(events[0].Changes, ",") != "speed,drivers" {
+		t.Errorf("Expected speed and drivers to change, got %v", events[0].Changes)
+	}
+}
+
+func TestKey(t *testing.T) {
+	if key := Key(&models.USBDevice{Bus: 2, Address: 7}); key != "bus2-7" {
+		t.Errorf("Expected bus and address key, got %q", key)
+	}
+}
+
+func TestParseUevent(t *testing.T) {
+	msg := []byte("add@/devices/pci0000:00/0000:00:14.0/usb1/1-2\x00ACTION=add\x00DEVPATH=/devices/pci0000:00/0000:00:14.0/usb1/1-2\x00SUBSYSTEM=usb\x00DEVTYPE=usb_device\x00PRODUCT=403/6001/600\x00")
+	event, ok := ParseUevent(msg)
+	if !ok {
+		t.Fatal("Expected uevent to parse")
+	}
+	if event["ACTION"] != "add" || event["PRODUCT"] != "403/6001/600" || !event.IsUSBDevice() {
+		t.Errorf("Unexpected uevent %v", event)
+	}
+
+	endpoint, _ := ParseUevent([]byte("add@/devices/x/ep_81\x00SUBSYSTEM=usb_endpoint\x00"))
+	if endpoint.IsUSBDevice() {
+		t.Error("Expected endpoint uevent not to be a USB device")
+	}
+	if _, ok := ParseUevent([]byte("libudev\x00\xfe\xed")); ok {
+		t.Error("Expected udev message to be rejected")
+	}
+}
+
+func TestWatch(t *testing.T) {
+	topologies := []*models.Topology{
+		testTopology(ftdi()),
+		testTopology(ftdi()),
+		nil,
+		testTopology(),
+	}
+	calls := 0
+	detect := func() (*models.Topology, error) {
+		topology := topologies[min(calls, len(topologies)-1)]
+		calls++
+		if topology == nil {
+			return nil, errors.New("lsusb failed")
+		}
+		return topology, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	updates := Watch(ctx, detect, Options{Interval: time.Millisecond, NoUevents: true})
+
+	first := <-updates
+	if first.Err != nil || first.Topology == nil || len(first.Events) != 0 {
+		t.Fatalf("Expected initial topology without events, got %+v", first)
+	}
+	// The unchanged second enumeration sends nothing
+	if failed := <-updates; failed.Err == nil {
+		t.Fatalf("Expected enumeration error, got %+v", failed)
+	}
+	removed := <-updates
+	if got := actions(removed.Events); strings.Join(got, ";") != "remove 1-2" {
+		t.Errorf("Expected removal, got %v", got)
+	}
+
+	cancel()
+	for range updates {
+	}
+}
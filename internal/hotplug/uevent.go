@@ -0,0 +1,30 @@
+package hotplug
+
+import "strings"
+
+// Uevent is a kernel device event as broadcast over netlink: a header such
+// as add@/devices/... followed by KEY=value pairs, separated by NUL bytes.
+type Uevent map[string]string
+
+// ParseUevent parses a netlink uevent message. Messages from udev rather
+// than the kernel start with "libudev" and are not uevents.
+func ParseUevent(msg []byte) (Uevent, bool) {
+	fields := strings.Split(string(msg), "\x00")
+	if len(fields) == 0 || !strings.Contains(fields[0], "@/") {
+		return nil, false
+	}
+
+	event := make(Uevent)
+	for _, field := range fields[1:] {
+		if key, value, found := strings.Cut(field, "="); found {
+			event[key] = value
+		}
+	}
+	return event, true
+}
+
+// IsUSBDevice reports whether the uevent concerns a USB device or one of
+// its interfaces, rather than an endpoint or an unrelated subsystem.
+func (e Uevent) IsUSBDevice() bool {
+	return e["SUBSYSTEM"] == "usb" && (e["DEVTYPE"] == "usb_device" || e["DEVTYPE"] == "usb_interface")
+}
@@ -0,0 +1,60 @@
+//go:build linux
+
+package hotplug
+
+import (
+	"context"
+	"errors"
+
+	"golang.org/x/sys/unix"
+)
+
+// listenUevents subscribes to the kernel's uevent broadcast and signals
+// every event about a USB device or interface. The channel is closed when
+// ctx is done or the socket fails.
+func listenUevents(ctx context.Context) (<-chan struct{}, error) {
+	fd, err := unix.Socket(unix.AF_NETLINK, unix.SOCK_RAW|unix.SOCK_CLOEXEC, unix.NETLINK_KOBJECT_UEVENT)
+	if err != nil {
+		return nil, err
+	}
+	// Group 1 carries the kernel's own messages
+	if err := unix.Bind(fd, &unix.SockaddrNetlink{Family: unix.AF_NETLINK, Groups: 1}); err != nil {
+		unix.Close(fd)
+		return nil, err
+	}
+	// Wake up regularly to notice ctx being done
+	timeout := unix.Timeval{Sec: 1}
+	if err := unix.SetsockoptTimeval(fd, unix.SOL_SOCKET, unix.SO_RCVTIMEO, &timeout); err != nil {
+		unix.Close(fd)
+		return nil, err
+	}
+
+	signals := make(chan struct{}, 1)
+	go func() {
+		defer close(signals)
+		defer unix.Close(fd)
+
+		buf := make([]byte, 16*1024)
+		for ctx.Err() == nil {
+			n, _, err := unix.Recvfrom(fd, buf, 0)
+			if errors.Is(err, unix.EAGAIN) || errors.Is(err, unix.EINTR) {
+				continue
+			}
+			// ENOBUFS means events were dropped, which a refresh makes up for
+			if err != nil && !errors.Is(err, unix.ENOBUFS) {
+				return
+			}
+			if err == nil {
+				if event, ok := ParseUevent(buf[:n]); !ok || !event.IsUSBDevice() {
+					continue
+				}
+			}
+			select {
+			case signals <- struct{}{}:
+			default:
+			}
+		}
+	}()
+
+	return signals, nil
+}
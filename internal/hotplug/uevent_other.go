@@ -0,0 +1,12 @@
+//go:build !linux
+
+package hotplug
+
+import (
+	"context"
+	"errors"
+)
+
+func listenUevents(ctx context.Context) (<-chan struct{}, error) {
+	return nil, errors.New("uevents are only available on Linux")
+}
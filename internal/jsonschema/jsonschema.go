@@ -0,0 +1,145 @@
+// Package jsonschema generates JSON Schema documents from Go types, so the
+// published schema cannot drift from what encoding/json writes.
+package jsonschema
+
+import (
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Draft is the JSON Schema dialect of generated documents.
+const Draft = "https://json-schema.org/draft/2020-12/schema"
+
+// Schema is a JSON Schema document or subschema.
+type Schema map[string]any
+
+var timeType = reflect.TypeOf(time.Time{})
+
+type generator struct {
+	defs Schema
+}
+
+// Generate returns the schema of the JSON encoding of v, which must be a
+// struct or a pointer to one. Field descriptions come from doc struct tags.
+// Other named struct types are placed in $defs, so recursive types work.
+func Generate(v any, id, title, description string) Schema {
+	t := reflect.TypeOf(v)
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	g := &generator{defs: Schema{}}
+	schema := g.structSchema(t)
+	schema["$schema"] = Draft
+	schema["$id"] = id
+	schema["title"] = title
+	schema["description"] = description
+	if len(g.defs) > 0 {
+		schema["$defs"] = g.defs
+	}
+	return schema
+}
+
+func (g *generator) schemaFor(t reflect.Type) Schema {
+	if t == timeType {
+		return Schema{"type": "string", "format": "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return g.schemaFor(t.Elem())
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.structSchema(t)
+		}
+		if _, ok := g.defs[t.Name()]; !ok {
+			// Reserve the name first, the struct may refer to itself
+			g.defs[t.Name()] = Schema{}
+			g.defs[t.Name()] = g.structSchema(t)
+		}
+		return Schema{"$ref": "#/$defs/" + t.Name()}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return Schema{"type": "string", "contentEncoding": "base64"}
+		}
+		return Schema{"type": "array", "items": g.schemaFor(t.Elem())}
+	case reflect.Map:
+		return Schema{"type": "object", "additionalProperties": g.schemaFor(t.Elem())}
+	case reflect.String:
+		return Schema{"type": "string"}
+	case reflect.Bool:
+		return Schema{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return Schema{"type": "integer"}
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return Schema{"type": "integer", "minimum": 0, "maximum": uint64(1)<<t.Bits() - 1}
+	case reflect.Uint, reflect.Uint64:
+		return Schema{"type": "integer", "minimum": 0}
+	case reflect.Float32, reflect.Float64:
+		return Schema{"type": "number"}
+	default:
+		return Schema{}
+	}
+}
+
+func (g *generator) structSchema(t reflect.Type) Schema {
+	properties := Schema{}
+	required := []string{}
+	g.addFields(t, properties, &required)
+
+	schema := Schema{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// addFields adds the properties encoding/json writes for the fields of t,
+// including those promoted from embedded structs.
+func (g *generator) addFields(t reflect.Type, properties Schema, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" || !field.IsExported() && !field.Anonymous {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+
+		fieldType := field.Type
+		if fieldType.Kind() == reflect.Pointer {
+			fieldType = fieldType.Elem()
+		}
+		if field.Anonymous && name == "" && fieldType.Kind() == reflect.Struct {
+			g.addFields(fieldType, properties, required)
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+		omitempty := strings.Contains(","+options+",", ",omitempty,")
+
+		schema := g.schemaFor(field.Type)
+		if field.Type.Kind() == reflect.Pointer && !omitempty {
+			schema = nullable(schema)
+		}
+		if doc := field.Tag.Get("doc"); doc != "" {
+			schema["description"] = doc
+		}
+
+		properties[name] = schema
+		if !omitempty {
+			*required = append(*required, name)
+		}
+	}
+}
+
+// nullable allows null in addition to what schema accepts.
+func nullable(schema Schema) Schema {
+	if typ, ok := schema["type"].(string); ok {
+		schema["type"] = []string{typ, "null"}
+		return schema
+	}
+	return Schema{"anyOf": []Schema{schema, {"type": "null"}}}
+}
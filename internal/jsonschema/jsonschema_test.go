@@ -0,0 +1,81 @@
+package jsonschema
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type testInfo struct {
+	Version int       `json:"version" doc:"Document version."`
+	Created time.Time `json:"created"`
+}
+
+type testNode struct {
+	Name     string      `json:"name" doc:"Node name."`
+	Class    uint8       `json:"class"`
+	Tags     []string    `json:"tags,omitempty"`
+	Children []*testNode `json:"children,omitempty"`
+	Hidden   string      `json:"-"`
+	internal string
+}
+
+type testDocument struct {
+	testInfo
+	Root   *testNode `json:"root"`
+	Parent *int      `json:"parent"`
+	Extra  *testInfo `json:"extra,omitempty"`
+}
+
+func TestGenerate(t *testing.T) {
+	schema := Generate(&testDocument{}, "https://example.com/doc.json", "Document", "A test document.")
+
+	if schema["$schema"] != Draft || schema["$id"] != "https://example.com/doc.json" || schema["title"] != "Document" {
+		t.Errorf("Unexpected header: %v", schema)
+	}
+
+	properties := schema["properties"].(Schema)
+	for _, name := range []string{"version", "created", "root", "parent", "extra"} {
+		if _, ok := properties[name]; !ok {
+			t.Errorf("Expected property %s", name)
+		}
+	}
+
+	if got := properties["version"].(Schema)["description"]; got != "Document version." {
+		t.Errorf("Expected description from doc tag, got %v", got)
+	}
+	if got := properties["created"].(Schema)["format"]; got != "date-time" {
+		t.Errorf("Expected time as date-time string, got %v", got)
+	}
+	if got := properties["parent"].(Schema)["type"]; !reflect.DeepEqual(got, []string{"integer", "null"}) {
+		t.Errorf("Expected nullable integer for pointer field, got %v", got)
+	}
+	if _, ok := properties["root"].(Schema)["anyOf"]; !ok {
+		t.Errorf("Expected nullable reference for pointer struct field, got %v", properties["root"])
+	}
+	if _, ok := properties["extra"].(Schema)["$ref"]; !ok {
+		t.Errorf("Expected plain reference for omitempty pointer field, got %v", properties["extra"])
+	}
+
+	required := schema["required"].([]string)
+	if !reflect.DeepEqual(required, []string{"version", "created", "root", "parent"}) {
+		t.Errorf("Unexpected required properties %v", required)
+	}
+
+	node := schema["$defs"].(Schema)["testNode"].(Schema)
+	nodeProperties := node["properties"].(Schema)
+	if len(nodeProperties) != 4 {
+		t.Errorf("Expected 4 node properties without hidden fields, got %v", nodeProperties)
+	}
+	if got := nodeProperties["children"].(Schema)["items"].(Schema)["$ref"]; got != "#/$defs/testNode" {
+		t.Errorf("Expected recursive reference, got %v", got)
+	}
+	if got := nodeProperties["class"].(Schema)["maximum"]; got != uint64(255) {
+		t.Errorf("Expected maximum 255 for uint8, got %v", got)
+	}
+
+	if _, err := json.Marshal(schema); err != nil {
+		t.Errorf("Schema does not encode: %v", err)
+	}
+}
@@ -0,0 +1,169 @@
+// Package metrics exports the device tree in the Prometheus text format,
+// so alerts can fire when a device disappears or drops to a slower speed.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/stegmannb/usbtree/internal/hotplug"
+	"github.com/stegmannb/usbtree/internal/models"
+)
+
+// ContentType is the media type of the Prometheus text format.
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// label is one name="value" pair of a sample.
+type label struct {
+	name, value string
+}
+
+// labels formats label pairs as {a="1",b="2"}, escaping the values as the
+// text format requires.
+func labels(pairs ...label) string {
+	escaper := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	parts := make([]string, len(pairs))
+	for i, pair := range pairs {
+		parts[i] = fmt.Sprintf(`%s="%s"`, pair.name, escaper.Replace(pair.value))
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+// deviceLabels identify a device in every per-device metric.
+func deviceLabels(device *models.USBDevice) []label {
+	return []label{
+		{"port_path", hotplug.Key(device)},
+		{"vendor_id", fmt.Sprintf("%04x", device.VendorID)},
+		{"product_id", fmt.Sprintf("%04x", device.ProductID)},
+		{"serial", device.Serial},
+	}
+}
+
+// connectLabels add the action of an event to the labels of a device.
+func connectLabels(device []label, action hotplug.Action) string {
+	return labels(append(device[:len(device):len(device)], label{"action", string(action)})...)
+}
+
+// Exporter keeps the state of the metrics between updates from
+// hotplug.Watch and writes them on every scrape. It is safe for concurrent
+// use.
+type Exporter struct {
+	mu       sync.Mutex
+	topology *models.Topology
+	// seen holds the labels of the device last seen at each port, so a
+	// device that disappeared is reported as absent until another takes
+	// its port, and the series stay bounded by the ports however many
+	// devices come and go
+	seen     map[string][]label
+	connects map[string]int
+	errors   int
+
+	overCurrent func(hub *models.USBDevice) map[int]int
+}
+
+// NewExporter returns an exporter without devices. overCurrent returns the
+// over-current count of every port of a hub, and may be nil where the
+// platform does not count them.
+func NewExporter(overCurrent func(hub *models.USBDevice) map[int]int) *Exporter {
+	return &Exporter{
+		seen:        make(map[string][]label),
+		connects:    make(map[string]int),
+		overCurrent: overCurrent,
+	}
+}
+
+// Update applies the result of an enumeration.
+func (e *Exporter) Update(update hotplug.Update) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if update.Err != nil {
+		e.errors++
+		return
+	}
+	e.topology = update.Topology
+	for _, event := range update.Events {
+		if event.Action == hotplug.ActionChange {
+			continue
+		}
+		e.connects[connectLabels(deviceLabels(event.Device), event.Action)]++
+	}
+	for _, device := range update.Topology.All() {
+		key, id := hotplug.Key(device), deviceLabels(device)
+		if last := e.seen[key]; last != nil && labels(last...) != labels(id...) {
+			// Another device took the port: forget the one that left
+			delete(e.connects, connectLabels(last, hotplug.ActionAdd))
+			delete(e.connects, connectLabels(last, hotplug.ActionRemove))
+		}
+		e.seen[key] = id
+	}
+}
+
+// metric is one metric family being written.
+type metric struct {
+	name, kind, help string
+	samples          map[string]string
+}
+
+// Write writes all metrics in the Prometheus text format, with families
+// and samples in a stable order.
+func (e *Exporter) Write(w io.Writer) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	present := metric{"usb_device_present", "gauge", "Whether the device is connected (1) or was the last seen at its port and is gone (0).", map[string]string{}}
+	speed := metric{"usb_device_speed_bps", "gauge", "Negotiated link speed of the device in bits per second.", map[string]string{}}
+	power := metric{"usb_device_max_power_milliamps", "gauge", "Maximum power the device declares it draws from the bus.", map[string]string{}}
+	overCurrent := metric{"usb_hub_port_over_current_total", "counter", "Over-current conditions reported by a hub port since boot.", map[string]string{}}
+	connects := metric{"usb_device_connect_events_total", "counter", "Devices attached (action=add) and detached (action=remove) since start.", map[string]string{}}
+	errors := metric{"usbtree_enumeration_errors_total", "counter", "Enumerations that failed since start.", map[string]string{"": fmt.Sprint(e.errors)}}
+
+	for _, id := range e.seen {
+		present.samples[labels(id...)] = "0"
+	}
+	if e.topology != nil {
+		for _, device := range e.topology.All() {
+			id := labels(deviceLabels(device)...)
+			present.samples[id] = "1"
+			if mbps := device.SpeedMbps(); mbps > 0 {
+				speed.samples[id] = fmt.Sprintf("%.0f", mbps*1e6)
+			}
+			if device.MaxPower != "" {
+				power.samples[id] = fmt.Sprint(device.MaxPowerMilliamps())
+			}
+			if e.overCurrent != nil && device.IsHub() {
+				for port, count := range e.overCurrent(device) {
+					overCurrent.samples[labels(label{"hub", hotplug.Key(device)}, label{"port", fmt.Sprint(port)})] = fmt.Sprint(count)
+				}
+			}
+		}
+	}
+	for key, count := range e.connects {
+		connects.samples[key] = fmt.Sprint(count)
+	}
+
+	bw := bufio.NewWriter(w)
+	for _, m := range []metric{present, speed, power, overCurrent, connects, errors} {
+		fmt.Fprintf(bw, "# HELP %s %s\n# TYPE %s %s\n", m.name, m.help, m.name, m.kind)
+		keys := make([]string, 0, len(m.samples))
+		for key := range m.samples {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			fmt.Fprintf(bw, "%s%s %s\n", m.name, key, m.samples[key])
+		}
+	}
+	return bw.Flush()
+}
+
+// ServeHTTP serves the metrics to a Prometheus scrape.
+func (e *Exporter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", ContentType)
+	e.Write(w)
+}
@@ -0,0 +1,153 @@
+package metrics
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stegmannb/usbtree/internal/hotplug"
+	"github.com/stegmannb/usbtree/internal/models"
+	"github.com/stegmannb/usbtree/internal/usb"
+)
+
+// writeSysfs creates a sysfs tree with a root hub and, if attached, an
+// FTDI adapter on port 2.
+func writeSysfs(t *testing.T, root string, attached bool) {
+	t.Helper()
+	files := map[string]string{
+		"usb1/idVendor": "1d6b", "usb1/idProduct": "0002", "usb1/busnum": "1", "usb1/devnum": "1",
+		"usb1/speed": "480", "usb1/bDeviceClass": "09", "usb1/maxchild": "2",
+		"1-0:1.0/bInterfaceClass":               "09",
+		"1-0:1.0/usb1-port1/over_current_count": "2",
+		"1-0:1.0/usb1-port2/over_current_count": "0",
+	}
+	if attached {
+		for attr, value := range map[string]string{
+			"idVendor": "0403", "idProduct": "6001", "manufacturer": "FTDI", "product": "FT232R \"USB\" UART",
+			"serial": "A50285BI", "busnum": "1", "devnum": "4", "speed": "12", "bMaxPower": "90mA",
+		} {
+			files["1-2/"+attr] = value
+		}
+	} else {
+		os.RemoveAll(filepath.Join(root, "1-2"))
+	}
+	for path, value := range files {
+		path = filepath.Join(root, path)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(value+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestExporter(t *testing.T) {
+	root := t.TempDir()
+	sysfs := usb.NewSysfs(root)
+	detect := func() *models.Topology {
+		devices, err := usb.NewSysfsDetector(sysfs).GetDevices()
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		return models.NewTopology(devices)
+	}
+	exporter := NewExporter(func(hub *models.USBDevice) map[int]int {
+		return sysfs.OverCurrentCounts(hub.PortPath)
+	})
+
+	writeSysfs(t, root, true)
+	attached := detect()
+	exporter.Update(hotplug.Update{Topology: attached})
+
+	writeSysfs(t, root, false)
+	detached := detect()
+	exporter.Update(hotplug.Update{Topology: detached, Events: hotplug.Diff(attached, detached, time.Now())})
+	exporter.Update(hotplug.Update{Err: os.ErrNotExist})
+
+	var buf bytes.Buffer
+	if err := exporter.Write(&buf); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	output := buf.String()
+
+	ftdi := `port_path="1-2",vendor_id="0403",product_id="6001",serial="A50285BI"`
+	for _, expected := range []string{
+		"# TYPE usb_device_present gauge\n",
+		`usb_device_present{` + ftdi + `} 0`,
+		`usb_device_present{port_path="usb1",vendor_id="1d6b",product_id="0002",serial=""} 1`,
+		`usb_device_speed_bps{port_path="usb1",vendor_id="1d6b",product_id="0002",serial=""} 480000000`,
+		`usb_hub_port_over_current_total{hub="usb1",port="1"} 2`,
+		`usb_hub_port_over_current_total{hub="usb1",port="2"} 0`,
+		`usb_device_connect_events_total{` + ftdi + `,action="remove"} 1`,
+		"usbtree_enumeration_errors_total 1\n",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected output to contain %q:\n%s", expected, output)
+		}
+	}
+
+	// Samples of a device only exist while it is connected
+	exporter.Update(hotplug.Update{Topology: attached, Events: hotplug.Diff(detached, attached, time.Now())})
+	recorder := httptest.NewRecorder()
+	exporter.ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	if recorder.Header().Get("Content-Type") != ContentType {
+		t.Errorf("Unexpected content type %q", recorder.Header().Get("Content-Type"))
+	}
+	for _, expected := range []string{
+		`usb_device_speed_bps{` + ftdi + `} 12000000`,
+		`usb_device_max_power_milliamps{` + ftdi + `} 90`,
+		`usb_device_connect_events_total{` + ftdi + `,action="add"} 1`,
+	} {
+		if !strings.Contains(recorder.Body.String(), expected) {
+			t.Errorf("Expected output to contain %q:\n%s", expected, recorder.Body.String())
+		}
+	}
+}
+
+func TestExporter_PortReused(t *testing.T) {
+	root := t.TempDir()
+	writeSysfs(t, root, true)
+	devices, err := usb.NewSysfsDetector(usb.NewSysfs(root)).GetDevices()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	exporter := NewExporter(nil)
+
+	// Adapters plugged into port 1-2 one after another
+	previous := models.NewTopology(devices)
+	exporter.Update(hotplug.Update{Topology: previous})
+	for _, serial := range []string{"A1", "A2", "A3"} {
+		hub := *devices[0]
+		adapter := *hub.Children[0]
+		adapter.Serial = serial
+		hub.Children = []*models.USBDevice{&adapter}
+		topology := models.NewTopology([]*models.USBDevice{&hub})
+		exporter.Update(hotplug.Update{Topology: topology, Events: hotplug.Diff(previous, topology, time.Now())})
+		previous = topology
+	}
+
+	var buf bytes.Buffer
+	if err := exporter.Write(&buf); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	output := buf.String()
+	if !strings.Contains(output, `usb_device_present{port_path="1-2",vendor_id="0403",product_id="6001",serial="A3"} 1`) {
+		t.Errorf("Expected the last adapter present:\n%s", output)
+	}
+	for _, gone := range []string{`serial="A50285BI"`, `serial="A1"`, `serial="A2"`} {
+		if strings.Contains(output, gone) {
+			t.Errorf("Expected no series for %s once its port was taken:\n%s", gone, output)
+		}
+	}
+}
+
+func TestLabels(t *testing.T) {
+	if got := labels(label{"a", "x\"y\\z\n"}, label{"b", ""}); got != `{a="x\"y\\z\n",b=""}` {
+		t.Errorf("Unexpected labels %s", got)
+	}
+}
@@ -1,22 +1,95 @@
 package models
 
-import "fmt"
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
 
 type USBDevice struct {
-	VendorID    uint16       `json:"vendor_id"`
-	ProductID   uint16       `json:"product_id"`
-	VendorName  string       `json:"vendor_name"`
-	ProductName string       `json:"product_name"`
-	Bus         int          `json:"bus"`
-	Port        int          `json:"port"`
-	Address     int          `json:"address"`
-	Serial      string       `json:"serial,omitempty"`
-	Speed       string       `json:"speed"`
-	Class       string       `json:"class,omitempty"`
-	SubClass    string       `json:"subclass,omitempty"`
-	Protocol    string       `json:"protocol,omitempty"`
-	MaxPower    string       `json:"max_power,omitempty"`
-	Children    []*USBDevice `json:"children,omitempty"`
+	VendorID         uint16            `json:"vendor_id" doc:"USB vendor ID (idVendor)."`
+	ProductID        uint16            `json:"product_id" doc:"USB product ID (idProduct)."`
+	VendorName       string            `json:"vendor_name" doc:"Vendor name from the device or the USB ID database."`
+	ProductName      string            `json:"product_name" doc:"Product name from the device or the USB ID database."`
+	Bus              int               `json:"bus" doc:"Number of the bus (host controller) the device is on."`
+	Port             int               `json:"port" doc:"Port number on the parent hub, 0 for root hubs."`
+	Address          int               `json:"address" doc:"Address of the device on its bus."`
+	Serial           string            `json:"serial,omitempty" doc:"Serial number string descriptor (iSerial)."`
+	Speed            string            `json:"speed" doc:"Negotiated link speed, e.g. \"High (480 Mbps)\"."`
+	Class            string            `json:"class,omitempty" doc:"Device class name, e.g. Hub or HID."`
+	SubClass         string            `json:"subclass,omitempty" doc:"Device subclass code in hex."`
+	Protocol         string            `json:"protocol,omitempty" doc:"Device protocol code in hex."`
+	MaxPower         string            `json:"max_power,omitempty" doc:"Maximum power the device draws from the bus, e.g. 100mA."`
+	Ports            int               `json:"ports,omitempty" doc:"Number of downstream ports if the device is a hub."`
+	ControllerDriver string            `json:"controller_driver,omitempty" doc:"Kernel driver of the host controller (Linux root hubs), e.g. xhci_hcd."`
+	PortPath         string            `json:"port_path,omitempty" doc:"Kernel port path (Linux), e.g. usb1 for root hubs or 1-2.3 for devices."`
+	LocationID       string            `json:"location_id,omitempty" doc:"Location ID assigned by macOS."`
+	Descriptor       *DeviceDescriptor `json:"descriptor,omitempty" doc:"Raw values of the device descriptor, where the source provides them."`
+	Power            *PowerState       `json:"power,omitempty" doc:"Runtime power management state (Linux)."`
+	Interfaces       []Interface       `json:"interfaces,omitempty" doc:"Interfaces of the active configuration."`
+	Label            string            `json:"label,omitempty" doc:"Label given to the device in the usbtree configuration, e.g. DUT-3 console."`
+	Note             string            `json:"note,omitempty" doc:"Note on the device from the usbtree configuration."`
+	Children         []*USBDevice      `json:"children,omitempty" doc:"Devices connected to this device if it is a hub."`
+
+	// LabelColor is the color the tree shows the label in, from the
+	// configuration.
+	LabelColor string `json:"-"`
+
+	// Via are the hubs between the device and its parent that a view of
+	// the tree leaves out, nearest to the parent first.
+	Via []*USBDevice `json:"-"`
+
+	// Parent and Depth are set by NewTopology. They are not serialized, so
+	// the JSON stays a tree without cycles.
+	Parent *USBDevice `json:"-"`
+	Depth  int        `json:"-"`
+}
+
+// Interface is one interface of the device's active configuration.
+type Interface struct {
+	Number   int    `json:"number" doc:"Interface number (bInterfaceNumber)."`
+	Class    uint8  `json:"class" doc:"Interface class code (bInterfaceClass)."`
+	SubClass uint8  `json:"subclass" doc:"Interface subclass code (bInterfaceSubClass)."`
+	Protocol uint8  `json:"protocol" doc:"Interface protocol code (bInterfaceProtocol)."`
+	Driver   string `json:"driver,omitempty" doc:"Kernel driver bound to the interface."`
+}
+
+// ClassTriple formats the interface class as cc:ss:pp in hex, the notation
+// used by USBGuard and lsusb.
+func (i Interface) ClassTriple() string {
+	return fmt.Sprintf("%02x:%02x:%02x", i.Class, i.SubClass, i.Protocol)
+}
+
+// DeviceDescriptor holds the values of the USB device descriptor that the
+// display fields of USBDevice do not keep, as lsusb -v prints them.
+type DeviceDescriptor struct {
+	USBVersion     string `json:"usb_version" doc:"USB specification release (bcdUSB), e.g. 2.00."`
+	Class          uint8  `json:"class" doc:"Device class code (bDeviceClass), 0 if the class is defined per interface."`
+	SubClass       uint8  `json:"subclass" doc:"Device subclass code (bDeviceSubClass)."`
+	Protocol       uint8  `json:"protocol" doc:"Device protocol code (bDeviceProtocol)."`
+	MaxPacketSize  uint8  `json:"max_packet_size" doc:"Maximum packet size of endpoint 0 (bMaxPacketSize0)."`
+	DeviceVersion  string `json:"device_version" doc:"Device release number (bcdDevice), e.g. 6.00."`
+	Configurations uint8  `json:"configurations" doc:"Number of configurations (bNumConfigurations)."`
+}
+
+// PowerState mirrors the runtime power management attributes the kernel
+// exposes under a device's power/ directory in sysfs.
+type PowerState struct {
+	Control            string `json:"control,omitempty" doc:"Runtime power control: auto allows autosuspend, on keeps the device powered."`
+	RuntimeStatus      string `json:"runtime_status,omitempty" doc:"Current runtime power state, e.g. active or suspended."`
+	AutosuspendDelayMs int    `json:"autosuspend_delay_ms" doc:"Idle time before the device is suspended, in milliseconds."`
+	ActiveTimeMs       int64  `json:"runtime_active_time_ms" doc:"Total time the device was active, in milliseconds."`
+	SuspendedTimeMs    int64  `json:"runtime_suspended_time_ms" doc:"Total time the device was suspended, in milliseconds."`
+	Wakeup             string `json:"wakeup,omitempty" doc:"Whether the device may wake the system: enabled or disabled."`
+	Persist            bool   `json:"persist" doc:"Whether the device survives a power loss during system suspend (USB persist)."`
+}
+
+// AutosuspendEnabled reports whether the kernel is allowed to suspend the
+// device when it is idle.
+func (p *PowerState) AutosuspendEnabled() bool {
+	return p.Control == "auto"
 }
 
 func (d *USBDevice) AddChild(child *USBDevice) {
@@ -27,7 +100,29 @@ func (d *USBDevice) HasChildren() bool {
 	return len(d.Children) > 0
 }
 
+// IsHub reports whether other devices can be connected to the device.
+func (d *USBDevice) IsHub() bool {
+	return d.Class == "Hub" || d.HasChildren()
+}
+
+// Tier is the USB tier of the device: 1 for root hubs, plus one for every
+// hub in between. USB allows at most 7 tiers.
+func (d *USBDevice) Tier() int {
+	return d.Depth + 1
+}
+
+// GetDisplayName returns the label of the device, or else its product or
+// vendor name.
 func (d *USBDevice) GetDisplayName() string {
+	if d.Label != "" {
+		return d.Label
+	}
+	return d.GetDeviceName()
+}
+
+// GetDeviceName returns the product or vendor name of the device, ignoring
+// its label.
+func (d *USBDevice) GetDeviceName() string {
 	if d.ProductName != "" {
 		return d.ProductName
 	}
@@ -39,4 +134,71 @@ func (d *USBDevice) GetDisplayName() string {
 
 func (d *USBDevice) GetIDString() string {
 	return fmt.Sprintf("%04x:%04x", d.VendorID, d.ProductID)
+}
+
+// speedPattern extracts the bit rate from a speed such as "High (480 Mbps)".
+var speedPattern = regexp.MustCompile(`\(([\d.]+) ([MG])bps\)`)
+
+// SpeedMbps returns the negotiated speed in Mbit/s, or 0 if it is unknown.
+func (d *USBDevice) SpeedMbps() float64 {
+	match := speedPattern.FindStringSubmatch(d.Speed)
+	if match == nil {
+		return 0
+	}
+	rate, err := strconv.ParseFloat(match[1], 64)
+	if err != nil {
+		return 0
+	}
+	if match[2] == "G" {
+		rate *= 1000
+	}
+	return rate
+}
+
+// SpeedTier names the USB speed class of the device: low, full, high,
+// super, superplus or unknown.
+func (d *USBDevice) SpeedTier() string {
+	switch speed := d.SpeedMbps(); {
+	case speed == 0:
+		return "unknown"
+	case speed < 12:
+		return "low"
+	case speed < 480:
+		return "full"
+	case speed < 5000:
+		return "high"
+	case speed == 5000:
+		return "super"
+	default:
+		return "superplus"
+	}
+}
+
+// ParseSpeed parses a bit rate as written on the command line, such as
+// 480M, 5G, 5Gbps or 12, into Mbit/s.
+func ParseSpeed(s string) (float64, error) {
+	number := strings.TrimSuffix(strings.ToLower(strings.TrimSpace(s)), "bps")
+	scale := 1.0
+	switch {
+	case strings.HasSuffix(number, "g"):
+		number, scale = strings.TrimSuffix(number, "g"), 1000
+	case strings.HasSuffix(number, "m"):
+		number = strings.TrimSuffix(number, "m")
+	case strings.HasSuffix(number, "k"):
+		number, scale = strings.TrimSuffix(number, "k"), 0.001
+	}
+	rate, err := strconv.ParseFloat(number, 64)
+	if err != nil || rate <= 0 || math.IsNaN(rate) || math.IsInf(rate, 0) {
+		return 0, fmt.Errorf("invalid speed %q: expected a rate such as 480M or 5G", s)
+	}
+	return rate * scale, nil
+}
+
+// MaxPowerMilliamps returns MaxPower in mA, or 0 if it is unknown.
+func (d *USBDevice) MaxPowerMilliamps() int {
+	milliamps, err := strconv.Atoi(strings.TrimSpace(strings.TrimSuffix(d.MaxPower, "mA")))
+	if err != nil {
+		return 0
+	}
+	return milliamps
 }
\ No newline at end of file
@@ -78,6 +78,14 @@ func TestUSBDevice_GetDisplayName(t *testing.T) {
 			device: &USBDevice{},
 			expected: "Unknown Device",
 		},
+		{
+			name: "Label from the configuration",
+			device: &USBDevice{
+				ProductName: "FT232R USB UART",
+				Label:       "DUT-3 console",
+			},
+			expected: "DUT-3 console",
+		},
 	}
 
 	for _, tt := range tests {
@@ -104,6 +112,69 @@ func TestUSBDevice_GetIDString(t *testing.T) {
 	}
 }
 
+func TestInterface_ClassTriple(t *testing.T) {
+	iface := Interface{Number: 0, Class: 0x03, SubClass: 0x01, Protocol: 0x02}
+
+	if result := iface.ClassTriple(); result != "03:01:02" {
+		t.Errorf("Expected %q, got %q", "03:01:02", result)
+	}
+}
+
+func TestUSBDevice_SpeedMbps(t *testing.T) {
+	tests := map[string]float64{
+		"Low (1.5 Mbps)":   1.5,
+		"High (480 Mbps)":  480,
+		"Super+ (10 Gbps)": 10000,
+		"Unknown":          0,
+		"":                 0,
+	}
+	for speed, expected := range tests {
+		if got := (&USBDevice{Speed: speed}).SpeedMbps(); got != expected {
+			t.Errorf("SpeedMbps(%q) = %v, expected %v", speed, got, expected)
+		}
+	}
+}
+
+func TestUSBDevice_SpeedTier(t *testing.T) {
+	tests := map[string]string{
+		"Low (1.5 Mbps)":   "low",
+		"Full (12 Mbps)":   "full",
+		"High (480 Mbps)":  "high",
+		"Super (5 Gbps)":   "super",
+		"Super+ (10 Gbps)": "superplus",
+		"":                 "unknown",
+	}
+	for speed, expected := range tests {
+		if got := (&USBDevice{Speed: speed}).SpeedTier(); got != expected {
+			t.Errorf("SpeedTier(%q) = %q, expected %q", speed, got, expected)
+		}
+	}
+}
+
+func TestParseSpeed(t *testing.T) {
+	tests := map[string]float64{"480M": 480, "480Mbps": 480, "5G": 5000, "12": 12, "1.5M": 1.5}
+	for input, expected := range tests {
+		got, err := ParseSpeed(input)
+		if err != nil || got != expected {
+			t.Errorf("ParseSpeed(%q) = %v, %v, expected %v", input, got, err, expected)
+		}
+	}
+	for _, input := range []string{"", "fast", "0", "-5G", "nan", "inf", "infinityM", "+Inf"} {
+		if _, err := ParseSpeed(input); err == nil {
+			t.Errorf("Expected an error for %q", input)
+		}
+	}
+}
+
+func TestUSBDevice_MaxPowerMilliamps(t *testing.T) {
+	tests := map[string]int{"500mA": 500, "0mA": 0, "": 0, "unknown": 0}
+	for power, expected := range tests {
+		if got := (&USBDevice{MaxPower: power}).MaxPowerMilliamps(); got != expected {
+			t.Errorf("MaxPowerMilliamps(%q) = %d, expected %d", power, got, expected)
+		}
+	}
+}
+
 func TestUSBDevice_JSONMarshaling(t *testing.T) {
 	device := &USBDevice{
 		VendorID:    0x05AC,
@@ -0,0 +1,100 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// SchemaVersion is the version of the JSON document describing a device
+// tree. It is bumped whenever a field is renamed or removed or changes its
+// meaning; new fields do not change it.
+const SchemaVersion = 1
+
+// ReportInfo describes where and when a report was generated.
+type ReportInfo struct {
+	SchemaVersion int       `json:"schema_version" doc:"Version of this document's schema. Bumped when fields are renamed, removed or change meaning."`
+	GeneratedAt   time.Time `json:"generated_at" doc:"Time the devices were read, in RFC 3339 format."`
+	Hostname      string    `json:"hostname,omitempty" doc:"Host the devices are connected to. Left out of anonymized reports."`
+	Backend       string    `json:"backend,omitempty" doc:"Source the devices were read from: lsusb, system_profiler, file or fixture."`
+}
+
+// NewReportInfo describes a report generated now from the given backend.
+func NewReportInfo(backend, hostname string) ReportInfo {
+	return ReportInfo{
+		SchemaVersion: SchemaVersion,
+		GeneratedAt:   time.Now().UTC(),
+		Hostname:      hostname,
+		Backend:       backend,
+	}
+}
+
+// Report is the versioned JSON document describing a device tree.
+type Report struct {
+	ReportInfo
+	Devices []*USBDevice `json:"devices" doc:"Root hubs, with connected devices nested below them."`
+}
+
+// FlatReport is a Report with the tree flattened into a list of devices
+// linked by ID, which is easier to load into databases.
+type FlatReport struct {
+	ReportInfo
+	Devices []FlatDevice `json:"devices" doc:"All devices, parents before their children."`
+}
+
+// FlatDevice is a device without its children, linked to its parent by ID.
+type FlatDevice struct {
+	ID       int  `json:"id" doc:"Number of the device within this report, starting at 1."`
+	ParentID *int `json:"parent_id" doc:"ID of the hub the device is connected to, null for root hubs."`
+	*USBDevice
+}
+
+// Flatten lists all devices of the topology in Walk order with IDs
+// assigned in that order.
+func Flatten(topology *Topology) []FlatDevice {
+	ids := make(map[*USBDevice]int)
+	var flat []FlatDevice
+	topology.Walk(func(device *USBDevice) error {
+		id := len(flat) + 1
+		ids[device] = id
+
+		entry := *device
+		entry.Children = nil
+		f := FlatDevice{ID: id, USBDevice: &entry}
+		if device.Parent != nil {
+			parentID := ids[device.Parent]
+			f.ParentID = &parentID
+		}
+		flat = append(flat, f)
+		return nil
+	})
+	return flat
+}
+
+// Unflatten rebuilds the device tree from flattened devices. Parents must
+// come before their children, as written by Flatten.
+func Unflatten(flat []FlatDevice) ([]*USBDevice, error) {
+	byID := make(map[int]*USBDevice)
+	var roots []*USBDevice
+	for _, f := range flat {
+		if f.USBDevice == nil {
+			return nil, fmt.Errorf("device %d has no attributes", f.ID)
+		}
+		if _, ok := byID[f.ID]; ok {
+			return nil, fmt.Errorf("duplicate device ID %d", f.ID)
+		}
+		device := f.USBDevice
+		device.Children = nil
+		byID[f.ID] = device
+
+		if f.ParentID == nil {
+			roots = append(roots, device)
+			continue
+		}
+		parent, ok := byID[*f.ParentID]
+		if !ok {
+			return nil, fmt.Errorf("device %d refers to unknown parent %d", f.ID, *f.ParentID)
+		}
+		parent.AddChild(device)
+	}
+	return roots, nil
+}
@@ -0,0 +1,29 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/stegmannb/usbtree/internal/jsonschema"
+)
+
+const schemaBaseURL = "https://github.com/stegmannb/usbtree/blob/main/docs/schema/"
+
+// ReportSchema returns the JSON Schema of Report, or of FlatReport when
+// flat is set, as indented JSON.
+func ReportSchema(flat bool) ([]byte, error) {
+	var schema jsonschema.Schema
+	if flat {
+		schema = jsonschema.Generate(&FlatReport{}, schemaBaseURL+fmt.Sprintf("report-flat-v%d.json", SchemaVersion),
+			"usbtree flat report", "USB devices as written by usbtree --json-flat: a list linked by id and parent_id.")
+	} else {
+		schema = jsonschema.Generate(&Report{}, schemaBaseURL+fmt.Sprintf("report-v%d.json", SchemaVersion),
+			"usbtree report", "USB device tree as written by usbtree --json.")
+	}
+
+	data, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
@@ -0,0 +1,158 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestReportSchema_Published(t *testing.T) {
+	for _, flat := range []bool{false, true} {
+		name := fmt.Sprintf("report-v%d.json", SchemaVersion)
+		if flat {
+			name = fmt.Sprintf("report-flat-v%d.json", SchemaVersion)
+		}
+
+		t.Run(name, func(t *testing.T) {
+			schema, err := ReportSchema(flat)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			path := filepath.Join("..", "..", "docs", "schema", name)
+			if os.Getenv("UPDATE_SCHEMA") != "" {
+				if err := os.WriteFile(path, schema, 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			published, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("Failed to read published schema: %v", err)
+			}
+			if !bytes.Equal(schema, published) {
+				t.Errorf("%s is out of date; regenerate it with UPDATE_SCHEMA=1 go test ./internal/models/", path)
+			}
+		})
+	}
+}
+
+func TestReportSchema_CoversReport(t *testing.T) {
+	schema, err := ReportSchema(false)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var doc struct {
+		Properties map[string]any `json:"properties"`
+		Defs       map[string]struct {
+			Properties map[string]any `json:"properties"`
+		} `json:"$defs"`
+	}
+	if err := json.Unmarshal(schema, &doc); err != nil {
+		t.Fatalf("Invalid schema: %v", err)
+	}
+
+	// Every key of an encoded report must be described by the schema
+	device := &USBDevice{
+		Serial:     "A1",
+		PortPath:   "1-2",
+		LocationID: "0x01200000",
+		Class:      "HID",
+		SubClass:   "01",
+		Protocol:   "02",
+		MaxPower:   "100mA",
+		Power:      &PowerState{Control: "auto", RuntimeStatus: "active", Wakeup: "enabled"},
+		Interfaces: []Interface{{Driver: "usbhid"}},
+	}
+	report := Report{ReportInfo: NewReportInfo("lsusb", "lab"), Devices: []*USBDevice{{Children: []*USBDevice{device}}}}
+	data, _ := json.Marshal(report)
+
+	var encoded struct {
+		Info    map[string]any
+		Devices []map[string]any `json:"devices"`
+	}
+	json.Unmarshal(data, &encoded.Info)
+	json.Unmarshal(data, &encoded)
+
+	check := func(keys map[string]any, properties map[string]any, where string) {
+		for key := range keys {
+			if _, ok := properties[key]; !ok {
+				t.Errorf("%s key %q missing from schema", where, key)
+			}
+		}
+	}
+	check(encoded.Info, doc.Properties, "Report")
+
+	child := encoded.Devices[0]["children"].([]any)[0].(map[string]any)
+	check(child, doc.Defs["USBDevice"].Properties, "USBDevice")
+	check(child["power"].(map[string]any), doc.Defs["PowerState"].Properties, "PowerState")
+	check(child["interfaces"].([]any)[0].(map[string]any), doc.Defs["Interface"].Properties, "Interface")
+}
+
+func TestFlatten(t *testing.T) {
+	topology := testTopology()
+
+	flat := Flatten(topology)
+	if len(flat) != 6 {
+		t.Fatalf("Expected 6 devices, got %d", len(flat))
+	}
+	if flat[0].ID != 1 || flat[0].ParentID != nil {
+		t.Errorf("Expected root hub with ID 1 and no parent, got %+v", flat[0])
+	}
+	// usb2, usb1, 1-2, 1-2.1: the receiver sits behind the hub with ID 3
+	if flat[3].PortPath != "1-2.1" || flat[3].ParentID == nil || *flat[3].ParentID != 3 {
+		t.Errorf("Expected 1-2.1 linked to parent 3, got %+v", flat[3])
+	}
+	if len(flat[2].Children) != 0 || len(topology.Devices[1].Children) != 2 {
+		t.Error("Expected flattening to drop children without touching the topology")
+	}
+
+	data, err := json.Marshal(FlatReport{ReportInfo: NewReportInfo("lsusb", ""), Devices: flat})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !bytes.Contains(data, []byte(`"id":4,"parent_id":3,"vendor_id":1133`)) {
+		t.Errorf("Expected flat device with id and parent_id: %s", data)
+	}
+
+	var decoded FlatReport
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	roots, err := Unflatten(decoded.Devices)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	rebuilt := NewTopology(roots)
+	if got := portPaths(rebuilt.All()); got != portPaths(topology.All()) {
+		t.Errorf("Expected the same tree after unflattening, got %q", got)
+	}
+	if device := rebuilt.FindByPortPath("1-2.3"); device == nil || device.Parent.PortPath != "1-2" {
+		t.Errorf("Expected 1-2.3 below 1-2 after unflattening, got %+v", device)
+	}
+}
+
+func TestUnflatten_Errors(t *testing.T) {
+	parent := 7
+	if _, err := Unflatten([]FlatDevice{{ID: 1, ParentID: &parent, USBDevice: &USBDevice{}}}); err == nil {
+		t.Error("Expected error for unknown parent")
+	}
+	if _, err := Unflatten([]FlatDevice{{ID: 1, USBDevice: &USBDevice{}}, {ID: 1, USBDevice: &USBDevice{}}}); err == nil {
+		t.Error("Expected error for duplicate ID")
+	}
+}
+
+func TestNewReportInfo(t *testing.T) {
+	info := NewReportInfo("lsusb", "lab")
+	if info.SchemaVersion != SchemaVersion || info.Backend != "lsusb" || info.Hostname != "lab" {
+		t.Errorf("Unexpected report info: %+v", info)
+	}
+	if time.Since(info.GeneratedAt) > time.Minute || info.GeneratedAt.Location() != time.UTC {
+		t.Errorf("Expected current UTC time, got %v", info.GeneratedAt)
+	}
+}
@@ -0,0 +1,100 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// SelectorKind identifies which device attribute a Selector matches on.
+type SelectorKind string
+
+const (
+	SelectPort   SelectorKind = "port"
+	SelectID     SelectorKind = "id"
+	SelectSerial SelectorKind = "serial"
+	SelectName   SelectorKind = "name"
+	SelectLabel  SelectorKind = "label"
+)
+
+var (
+	portPathPattern = regexp.MustCompile(`^(usb\d+|\d+-\d+(\.\d+)*)$`)
+	idPattern       = regexp.MustCompile(`^[0-9a-fA-F]{4}:([0-9a-fA-F]{4})?$`)
+)
+
+// Selector picks devices out of a tree. It is written on the command line
+// either as kind=value (port=1-2.3, id=0403:6001, serial=A50285BI,
+// name=receiver, label=dut-3) or as a bare value, in which case port paths
+// and vendor:product IDs are recognised and anything else matches by name.
+// Names match the label as well as the product and vendor name.
+type Selector struct {
+	Kind  SelectorKind
+	Value string
+}
+
+func ParseSelector(s string) (Selector, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return Selector{}, fmt.Errorf("empty device selector")
+	}
+
+	if kind, value, found := strings.Cut(s, "="); found {
+		sel := Selector{Kind: SelectorKind(strings.ToLower(kind)), Value: value}
+		switch sel.Kind {
+		case SelectPort, SelectSerial, SelectName, SelectLabel:
+		case SelectID:
+			if !idPattern.MatchString(value) {
+				return Selector{}, fmt.Errorf("invalid vendor:product ID %q", value)
+			}
+			sel.Value = strings.ToLower(value)
+		default:
+			return Selector{}, fmt.Errorf("unknown selector kind %q", kind)
+		}
+		if sel.Value == "" {
+			return Selector{}, fmt.Errorf("empty value for selector %q", kind)
+		}
+		return sel, nil
+	}
+
+	switch {
+	case portPathPattern.MatchString(s):
+		return Selector{Kind: SelectPort, Value: s}, nil
+	case idPattern.MatchString(s):
+		return Selector{Kind: SelectID, Value: strings.ToLower(s)}, nil
+	default:
+		return Selector{Kind: SelectName, Value: s}, nil
+	}
+}
+
+func (s Selector) Matches(d *USBDevice) bool {
+	switch s.Kind {
+	case SelectPort:
+		return d.PortPath == s.Value
+	case SelectID:
+		vendor, product, _ := strings.Cut(s.Value, ":")
+		if v, err := strconv.ParseUint(vendor, 16, 16); err != nil || uint16(v) != d.VendorID {
+			return false
+		}
+		if product == "" {
+			return true
+		}
+		p, err := strconv.ParseUint(product, 16, 16)
+		return err == nil && uint16(p) == d.ProductID
+	case SelectSerial:
+		return d.Serial == s.Value
+	case SelectName:
+		value := strings.ToLower(s.Value)
+		return strings.Contains(strings.ToLower(d.ProductName), value) ||
+			strings.Contains(strings.ToLower(d.VendorName), value) ||
+			strings.Contains(strings.ToLower(d.Label), value)
+	case SelectLabel:
+		return d.Label != "" && strings.Contains(strings.ToLower(d.Label), strings.ToLower(s.Value))
+	default:
+		return false
+	}
+}
+
+func (s Selector) String() string {
+	return fmt.Sprintf("%s=%s", s.Kind, s.Value)
+}
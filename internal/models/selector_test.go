@@ -0,0 +1,88 @@
+package models
+
+import (
+	"testing"
+)
+
+func TestParseSelector(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected Selector
+		wantErr  bool
+	}{
+		{input: "1-2.3", expected: Selector{Kind: SelectPort, Value: "1-2.3"}},
+		{input: "usb1", expected: Selector{Kind: SelectPort, Value: "usb1"}},
+		{input: "0403:6001", expected: Selector{Kind: SelectID, Value: "0403:6001"}},
+		{input: "0403:", expected: Selector{Kind: SelectID, Value: "0403:"}},
+		{input: "ID=0403:6001", expected: Selector{Kind: SelectID, Value: "0403:6001"}},
+		{input: "serial=A50285BI", expected: Selector{Kind: SelectSerial, Value: "A50285BI"}},
+		{input: "receiver", expected: Selector{Kind: SelectName, Value: "receiver"}},
+		{input: "label=DUT-3", expected: Selector{Kind: SelectLabel, Value: "DUT-3"}},
+		{input: "", wantErr: true},
+		{input: "id=xyz", wantErr: true},
+		{input: "color=red", wantErr: true},
+		{input: "serial=", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			sel, err := ParseSelector(tt.input)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Expected error for %q, got %v", tt.input, sel)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if sel != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, sel)
+			}
+		})
+	}
+}
+
+func TestSelector_Find(t *testing.T) {
+	root := &USBDevice{VendorID: 0x1d6b, ProductID: 0x0002, ProductName: "2.0 root hub", PortPath: "usb1"}
+	hub := &USBDevice{VendorID: 0x05e3, ProductID: 0x0610, ProductName: "Hub", PortPath: "1-2"}
+	ftdi1 := &USBDevice{VendorID: 0x0403, ProductID: 0x6001, ProductName: "FT232R USB UART", Serial: "A1", PortPath: "1-2.1", Label: "DUT-3 console"}
+	ftdi2 := &USBDevice{VendorID: 0x0403, ProductID: 0x6001, ProductName: "FT232R USB UART", Serial: "A2", PortPath: "1-2.2"}
+	root.AddChild(hub)
+	hub.AddChild(ftdi1)
+	hub.AddChild(ftdi2)
+	topology := NewTopology([]*USBDevice{root})
+
+	tests := []struct {
+		selector string
+		expected []*USBDevice
+	}{
+		{selector: "1-2.2", expected: []*USBDevice{ftdi2}},
+		{selector: "0403:6001", expected: []*USBDevice{ftdi1, ftdi2}},
+		{selector: "0403:", expected: []*USBDevice{ftdi1, ftdi2}},
+		{selector: "serial=A1", expected: []*USBDevice{ftdi1}},
+		{selector: "uart", expected: []*USBDevice{ftdi1, ftdi2}},
+		{selector: "label=dut-3", expected: []*USBDevice{ftdi1}},
+		{selector: "console", expected: []*USBDevice{ftdi1}},
+		{selector: "label=uart", expected: nil},
+		{selector: "1-3", expected: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.selector, func(t *testing.T) {
+			sel, err := ParseSelector(tt.selector)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			found := topology.Find(sel.Matches)
+			if len(found) != len(tt.expected) {
+				t.Fatalf("Expected %d devices, got %d", len(tt.expected), len(found))
+			}
+			for i := range found {
+				if found[i] != tt.expected[i] {
+					t.Errorf("Device %d: expected %s, got %s", i, tt.expected[i].PortPath, found[i].PortPath)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package models
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Sort orders accepted by SortDevices.
+const (
+	SortPort  = "port"
+	SortName  = "name"
+	SortID    = "id"
+	SortSpeed = "speed"
+	SortPower = "power"
+)
+
+// SortOrders lists the valid values of --sort.
+var SortOrders = []string{SortPort, SortName, SortID, SortSpeed, SortPower}
+
+// deviceOrders compare two devices by one attribute, falling back to their
+// position for devices that compare equal.
+var deviceOrders = map[string]func(a, b *USBDevice) int{
+	SortPort: ComparePorts,
+	SortName: func(a, b *USBDevice) int {
+		return cmp.Compare(strings.ToLower(a.GetDisplayName()), strings.ToLower(b.GetDisplayName()))
+	},
+	SortID: func(a, b *USBDevice) int {
+		return cmp.Or(cmp.Compare(a.VendorID, b.VendorID), cmp.Compare(a.ProductID, b.ProductID))
+	},
+	// Fastest first
+	SortSpeed: func(a, b *USBDevice) int {
+		return cmp.Compare(b.SpeedMbps(), a.SpeedMbps())
+	},
+	// Hungriest first
+	SortPower: func(a, b *USBDevice) int {
+		return cmp.Compare(b.MaxPowerMilliamps(), a.MaxPowerMilliamps())
+	},
+}
+
+// SortDevices orders the devices and, recursively, the children of every
+// device. Root hubs are always ordered by bus; siblings by the given order
+// and then by port.
+func SortDevices(devices []*USBDevice, order string) error {
+	compare, ok := deviceOrders[order]
+	if !ok {
+		return fmt.Errorf("unknown sort order %q: expected one of %s", order, strings.Join(SortOrders, ", "))
+	}
+	slices.SortStableFunc(devices, ComparePorts)
+	for _, device := range devices {
+		sortChildren(device, compare)
+	}
+	return nil
+}
+
+// SortByPort orders the devices and the children of every device by bus and
+// port path, the default order of SortDevices.
+func SortByPort(devices []*USBDevice) {
+	slices.SortStableFunc(devices, ComparePorts)
+	for _, device := range devices {
+		sortChildren(device, ComparePorts)
+	}
+}
+
+func sortChildren(device *USBDevice, compare func(a, b *USBDevice) int) {
+	slices.SortStableFunc(device.Children, func(a, b *USBDevice) int {
+		return cmp.Or(compare(a, b), ComparePorts(a, b))
+	})
+	for _, child := range device.Children {
+		sortChildren(child, compare)
+	}
+}
+
+// ComparePorts orders devices by bus, then by port path, comparing port
+// numbers as numbers so 1-2.10 follows 1-2.9, and then by address.
+func ComparePorts(a, b *USBDevice) int {
+	return cmp.Or(
+		cmp.Compare(a.Bus, b.Bus),
+		slices.Compare(portNumbers(a), portNumbers(b)),
+		cmp.Compare(a.Address, b.Address),
+	)
+}
+
+// portNumbers returns the ports of the path to a device, e.g. [2 3] for
+// 1-2.3, or its port on the parent hub if the port path is unknown.
+func portNumbers(device *USBDevice) []int {
+	_, path, found := strings.Cut(device.PortPath, "-")
+	if !found {
+		if device.Port == 0 {
+			return nil
+		}
+		return []int{device.Port}
+	}
+	var ports []int
+	for _, part := range strings.Split(path, ".") {
+		port, err := strconv.Atoi(part)
+		if err != nil {
+			break
+		}
+		ports = append(ports, port)
+	}
+	return ports
+}
@@ -0,0 +1,40 @@
+package models
+
+import "testing"
+
+func TestSortDevices(t *testing.T) {
+	newTree := func() []*USBDevice {
+		return []*USBDevice{
+			{Bus: 2, PortPath: "usb2"},
+			{Bus: 1, PortPath: "usb1", Children: []*USBDevice{
+				{Bus: 1, PortPath: "1-10", ProductName: "alpha", VendorID: 0x0403, Speed: "Full (12 Mbps)", MaxPower: "90mA"},
+				{Bus: 1, PortPath: "1-9", ProductName: "Charlie", VendorID: 0x046d, Speed: "High (480 Mbps)", MaxPower: "500mA", Children: []*USBDevice{
+					{Bus: 1, PortPath: "1-9.2", ProductName: "b"},
+					{Bus: 1, PortPath: "1-9.1", ProductName: "c"},
+				}},
+				{Bus: 1, PortPath: "1-2", ProductName: "Bravo", VendorID: 0x0403, Speed: "Full (12 Mbps)", MaxPower: "100mA"},
+			}},
+		}
+	}
+
+	tests := map[string]string{
+		SortPort:  "usb1 1-2 1-9 1-9.1 1-9.2 1-10 usb2",
+		SortName:  "usb1 1-10 1-2 1-9 1-9.2 1-9.1 usb2",
+		SortID:    "usb1 1-2 1-10 1-9 1-9.1 1-9.2 usb2",
+		SortSpeed: "usb1 1-9 1-9.1 1-9.2 1-2 1-10 usb2",
+		SortPower: "usb1 1-9 1-9.1 1-9.2 1-2 1-10 usb2",
+	}
+	for order, expected := range tests {
+		devices := newTree()
+		if err := SortDevices(devices, order); err != nil {
+			t.Fatal(err)
+		}
+		if got := portPaths(NewTopology(devices).All()); got != expected {
+			t.Errorf("%s: expected %s, got %s", order, expected, got)
+		}
+	}
+
+	if err := SortDevices(newTree(), "color"); err == nil {
+		t.Error("Expected an error for an unknown order")
+	}
+}
@@ -0,0 +1,153 @@
+package models
+
+import "errors"
+
+// SkipChildren is returned by a WalkFunc to skip the children of the
+// current device.
+var SkipChildren = errors.New("skip children")
+
+// WalkFunc is called for every device visited by Topology.Walk.
+type WalkFunc func(device *USBDevice) error
+
+// Topology owns a device tree and links every device to its parent.
+type Topology struct {
+	// Devices are the root hubs.
+	Devices []*USBDevice
+	// Backend names the source the devices were read from, if known.
+	Backend string
+}
+
+// NewTopology takes ownership of the tree below the given root hubs and
+// sets Parent and Depth of every device in it.
+func NewTopology(devices []*USBDevice) *Topology {
+	t := &Topology{Devices: devices}
+	link(devices, nil, 0)
+	return t
+}
+
+func link(devices []*USBDevice, parent *USBDevice, depth int) {
+	for _, device := range devices {
+		device.Parent = parent
+		device.Depth = depth
+		link(device.Children, device, depth+1)
+	}
+}
+
+// Parent returns the hub device is connected to, or nil for root hubs.
+func (t *Topology) Parent(device *USBDevice) *USBDevice {
+	return device.Parent
+}
+
+// Walk visits all devices depth-first, parents before their children. It
+// stops at the first error fn returns other than SkipChildren and returns
+// it.
+func (t *Topology) Walk(fn WalkFunc) error {
+	return walk(t.Devices, fn)
+}
+
+func walk(devices []*USBDevice, fn WalkFunc) error {
+	for _, device := range devices {
+		err := fn(device)
+		if err == SkipChildren {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if err := walk(device.Children, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// All returns every device in Walk order.
+func (t *Topology) All() []*USBDevice {
+	return t.Find(func(*USBDevice) bool { return true })
+}
+
+// Find returns all devices match accepts, in Walk order.
+func (t *Topology) Find(match func(*USBDevice) bool) []*USBDevice {
+	var found []*USBDevice
+	t.Walk(func(device *USBDevice) error {
+		if match(device) {
+			found = append(found, device)
+		}
+		return nil
+	})
+	return found
+}
+
+// FindByPortPath returns the device at a port path such as 1-2.3, or nil.
+func (t *Topology) FindByPortPath(portPath string) *USBDevice {
+	var found *USBDevice
+	t.Walk(func(device *USBDevice) error {
+		if device.PortPath == portPath {
+			found = device
+			return errStop
+		}
+		return nil
+	})
+	return found
+}
+
+var errStop = errors.New("stop")
+
+// FindByID returns all devices with the given vendor and product ID.
+func (t *Topology) FindByID(vendorID, productID uint16) []*USBDevice {
+	return t.Find(func(device *USBDevice) bool {
+		return device.VendorID == vendorID && device.ProductID == productID
+	})
+}
+
+// FindBySerial returns all devices with the given serial number.
+func (t *Topology) FindBySerial(serial string) []*USBDevice {
+	return t.Find(func(device *USBDevice) bool {
+		return device.Serial == serial
+	})
+}
+
+// Select returns the devices matching a selector as accepted on the command
+// line; see ParseSelector.
+func (t *Topology) Select(selector string) ([]*USBDevice, error) {
+	sel, err := ParseSelector(selector)
+	if err != nil {
+		return nil, err
+	}
+	return t.Find(sel.Matches), nil
+}
+
+// Ancestors returns the hubs between device and its root, nearest first.
+func (t *Topology) Ancestors(device *USBDevice) []*USBDevice {
+	var ancestors []*USBDevice
+	for parent := device.Parent; parent != nil; parent = parent.Parent {
+		ancestors = append(ancestors, parent)
+	}
+	return ancestors
+}
+
+// Root returns the root hub device is connected to, or device itself for
+// root hubs.
+func (t *Topology) Root(device *USBDevice) *USBDevice {
+	for device.Parent != nil {
+		device = device.Parent
+	}
+	return device
+}
+
+// Descendants returns every device below device, in Walk order.
+func (t *Topology) Descendants(device *USBDevice) []*USBDevice {
+	return (&Topology{Devices: device.Children}).All()
+}
+
+// Leaves returns the devices without children.
+func (t *Topology) Leaves() []*USBDevice {
+	return t.Find(func(device *USBDevice) bool {
+		return !device.HasChildren()
+	})
+}
+
+// Hubs returns the root hubs and every hub connected to them.
+func (t *Topology) Hubs() []*USBDevice {
+	return t.Find((*USBDevice).IsHub)
+}
@@ -0,0 +1,125 @@
+package models
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func testTopology() *Topology {
+	return NewTopology([]*USBDevice{
+		{VendorID: 0x1d6b, ProductID: 0x0003, Class: "Hub", PortPath: "usb2"},
+		{
+			VendorID:  0x1d6b,
+			ProductID: 0x0002,
+			Class:     "Hub",
+			PortPath:  "usb1",
+			Children: []*USBDevice{
+				{
+					VendorID:  0x05e3,
+					ProductID: 0x0610,
+					Class:     "Hub",
+					PortPath:  "1-2",
+					Children: []*USBDevice{
+						{VendorID: 0x046d, ProductID: 0xc52b, PortPath: "1-2.1"},
+						{VendorID: 0x0403, ProductID: 0x6001, PortPath: "1-2.3", Serial: "A50285BI"},
+					},
+				},
+				{VendorID: 0x0403, ProductID: 0x6001, PortPath: "1-4", Serial: "FTB6SPL3"},
+			},
+		},
+	})
+}
+
+func portPaths(devices []*USBDevice) string {
+	var paths []string
+	for _, device := range devices {
+		paths = append(paths, device.PortPath)
+	}
+	return strings.Join(paths, " ")
+}
+
+func TestNewTopology(t *testing.T) {
+	topology := testTopology()
+
+	ftdi := topology.FindByPortPath("1-2.3")
+	if ftdi == nil {
+		t.Fatal("Expected device at 1-2.3")
+	}
+	if ftdi.Parent == nil || ftdi.Parent.PortPath != "1-2" {
+		t.Errorf("Expected hub 1-2 as parent, got %+v", ftdi.Parent)
+	}
+	if ftdi.Depth != 2 || ftdi.Tier() != 3 {
+		t.Errorf("Expected depth 2 and tier 3, got %d and %d", ftdi.Depth, ftdi.Tier())
+	}
+
+	root := topology.Devices[1]
+	if root.Parent != nil || root.Depth != 0 || root.Tier() != 1 {
+		t.Errorf("Unexpected root hub links: parent %v, depth %d", root.Parent, root.Depth)
+	}
+	if topology.Root(ftdi) != root || topology.Root(root) != root {
+		t.Error("Expected usb1 as root of 1-2.3 and of itself")
+	}
+}
+
+func TestTopology_Traversal(t *testing.T) {
+	topology := testTopology()
+	ftdi := topology.FindByPortPath("1-2.3")
+	hub := topology.FindByPortPath("1-2")
+
+	tests := []struct {
+		name     string
+		devices  []*USBDevice
+		expected string
+	}{
+		{"All", topology.All(), "usb2 usb1 1-2 1-2.1 1-2.3 1-4"},
+		{"Ancestors", topology.Ancestors(ftdi), "1-2 usb1"},
+		{"Descendants", topology.Descendants(topology.Devices[1]), "1-2 1-2.1 1-2.3 1-4"},
+		{"Descendants of leaf", topology.Descendants(ftdi), ""},
+		{"Leaves", topology.Leaves(), "usb2 1-2.1 1-2.3 1-4"},
+		{"Hubs", topology.Hubs(), "usb2 usb1 1-2"},
+		{"FindByID", topology.FindByID(0x0403, 0x6001), "1-2.3 1-4"},
+		{"FindBySerial", topology.FindBySerial("FTB6SPL3"), "1-4"},
+		{"Find", topology.Find(func(d *USBDevice) bool { return d.Parent == hub }), "1-2.1 1-2.3"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := portPaths(tt.devices); got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+
+	if device := topology.FindByPortPath("3-1"); device != nil {
+		t.Errorf("Expected no device at 3-1, got %+v", device)
+	}
+}
+
+func TestTopology_Walk(t *testing.T) {
+	topology := testTopology()
+
+	var visited []*USBDevice
+	topology.Walk(func(device *USBDevice) error {
+		visited = append(visited, device)
+		if device.PortPath == "1-2" {
+			return SkipChildren
+		}
+		return nil
+	})
+	if got := portPaths(visited); got != "usb2 usb1 1-2 1-4" {
+		t.Errorf("Expected the hub's children to be skipped, got %q", got)
+	}
+}
+
+func TestTopology_JSON(t *testing.T) {
+	topology := testTopology()
+
+	data, err := json.Marshal(topology.Devices)
+	if err != nil {
+		t.Fatalf("Expected JSON without cycles, got %v", err)
+	}
+	if strings.Contains(string(data), "parent") || strings.Contains(string(data), "depth") {
+		t.Errorf("Expected parent links to be left out of JSON: %s", data)
+	}
+}
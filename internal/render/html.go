@@ -0,0 +1,72 @@
+package render
+
+import (
+	_ "embed"
+	"fmt"
+	"html/template"
+	"io"
+
+	"github.com/stegmannb/usbtree/internal/health"
+	"github.com/stegmannb/usbtree/internal/models"
+	"github.com/stegmannb/usbtree/internal/tree"
+)
+
+//go:embed html.tmpl
+var htmlTemplate string
+
+// htmlReport is the data of html.tmpl.
+type htmlReport struct {
+	models.ReportInfo
+	Devices  []*models.USBDevice
+	All      []*models.USBDevice
+	Hubs     int
+	Power    []health.PowerTotal
+	PowerSum int
+	Health   []health.Result
+	Passed   int
+	anchors  map[*models.USBDevice]string
+}
+
+// WriteHTML writes topology as a single HTML page with a collapsible tree,
+// a detail table per device, power totals per bus and the results of the
+// health checks. Styles and scripts are inlined and nothing is loaded from
+// the network, so the page can be archived as it is.
+func WriteHTML(w io.Writer, info models.ReportInfo, topology *models.Topology) error {
+	report := &htmlReport{
+		ReportInfo: info,
+		Devices:    topology.Devices,
+		All:        topology.All(),
+		Hubs:       len(topology.Hubs()),
+		Power:      health.PowerTotals(topology),
+		Health:     health.Run(topology),
+		anchors:    make(map[*models.USBDevice]string),
+	}
+	for i, device := range report.All {
+		report.anchors[device] = fmt.Sprintf("device-%d", i+1)
+	}
+	for _, total := range report.Power {
+		report.PowerSum += total.Milliamps
+	}
+	for _, result := range report.Health {
+		if result.Status() == "pass" {
+			report.Passed++
+		}
+	}
+
+	tmpl, err := template.New("report").Funcs(template.FuncMap{
+		"anchor":      func(device *models.USBDevice) string { return report.anchors[device] },
+		"label":       tree.Label,
+		"details":     tree.Details,
+		"speedClass":  speedClass,
+		"speedColors": func() map[string]string { return speedColors },
+	}).Parse(htmlTemplate)
+	if err != nil {
+		return fmt.Errorf("failed to parse HTML template: %w", err)
+	}
+	return tmpl.Execute(w, report)
+}
+
+// speedClass is the CSS class coloring a device by its negotiated speed.
+func speedClass(device *models.USBDevice) string {
+	return "speed-" + device.SpeedTier()
+}
@@ -0,0 +1,44 @@
+package render
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stegmannb/usbtree/internal/models"
+)
+
+func TestWriteHTML(t *testing.T) {
+	report := testReport()
+	ftdi := report.Devices[0].Children[0]
+	ftdi.MaxPower = "90mA"
+	ftdi.Interfaces = append(ftdi.Interfaces, models.Interface{Number: 1, Class: 0x03})
+
+	var buf bytes.Buffer
+	if err := WriteHTML(&buf, report.ReportInfo, models.NewTopology(report.Devices)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	output := buf.String()
+
+	for _, expected := range []string{
+		"<!DOCTYPE html>",
+		`<a href="#device-2">FT232R &#34;USB&#34; UART</a>`,
+		`<section class="device" id="device-2">`,
+		`<span class="badge speed-full">12M</span>`,
+		"<td><code>1-2</code></td>",
+		`<td class="number">90mA</td>`,
+		"<strong>4/5</strong> health checks passed",
+		"Interface 1 (03:00:00) has no driver bound",
+		"2024-05-01 12:00:00 UTC from lsusb",
+	} {
+		if !strings.Contains(output, expected) && !strings.Contains(output, strings.ReplaceAll(expected, "<td><code>1-2</code></td>", "<code>1-2</code>")) {
+			t.Errorf("Expected output to contain %q", expected)
+		}
+	}
+
+	// Nothing may be loaded from elsewhere
+	if external := regexp.MustCompile(`(?i)\b(src|href)="(https?:)?//|<link|@import|url\(`).FindString(output); external != "" {
+		t.Errorf("Expected a self-contained page, found %q", external)
+	}
+}
@@ -0,0 +1,107 @@
+// Package render writes reports as YAML, XML and TOML, derived from the
+// JSON encoding so element names and nesting match the --json output
+// exactly, as a self-contained HTML page and as an SVG diagram.
+package render
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// Output formats.
+const (
+	FormatTree = "tree"
+	FormatJSON = "json"
+	FormatYAML = "yaml"
+	FormatXML  = "xml"
+	FormatTOML = "toml"
+	FormatHTML = "html"
+	FormatSVG  = "svg"
+)
+
+// field is one key of a JSON object, kept in encoding order.
+type field struct {
+	key   string
+	value any
+}
+
+// object is a JSON object with its keys in the order encoding/json wrote
+// them, which is the order of the struct fields.
+type object []field
+
+// toTree encodes v as JSON and decodes it into objects, []any, string,
+// json.Number, bool and nil values.
+func toTree(v any) (any, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	return decodeValue(decoder)
+}
+
+func decodeValue(decoder *json.Decoder) (any, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	delim, ok := token.(json.Delim)
+	if !ok {
+		return token, nil
+	}
+
+	switch delim {
+	case '{':
+		obj := object{}
+		for decoder.More() {
+			key, err := decoder.Token()
+			if err != nil {
+				return nil, err
+			}
+			value, err := decodeValue(decoder)
+			if err != nil {
+				return nil, err
+			}
+			obj = append(obj, field{key: key.(string), value: value})
+		}
+		_, err = decoder.Token()
+		return obj, err
+	case '[':
+		list := []any{}
+		for decoder.More() {
+			value, err := decodeValue(decoder)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, value)
+		}
+		_, err = decoder.Token()
+		return list, err
+	default:
+		return nil, fmt.Errorf("unexpected %v in JSON", delim)
+	}
+}
+
+// Write renders v, a report or any other JSON-encodable value, in the given
+// format.
+func Write(w io.Writer, format string, v any) error {
+	switch format {
+	case FormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(v)
+	case FormatYAML:
+		return WriteYAML(w, v)
+	case FormatXML:
+		return WriteXML(w, v)
+	case FormatTOML:
+		return WriteTOML(w, v)
+	default:
+		return fmt.Errorf("unknown output format %q", format)
+	}
+}
@@ -0,0 +1,162 @@
+package render
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/stegmannb/usbtree/internal/models"
+)
+
+func testReport() models.Report {
+	ftdi := &models.USBDevice{
+		VendorID: 0x0403, ProductID: 0x6001, VendorName: "FTDI", ProductName: "FT232R \"USB\" UART",
+		Bus: 1, Port: 2, Address: 5, Serial: "0123", Speed: "Full (12 Mbps)", PortPath: "1-2",
+		Power:      &models.PowerState{Control: "on", Persist: true},
+		Interfaces: []models.Interface{{Number: 0, Class: 0xff, SubClass: 0xff, Protocol: 0xff, Driver: "ftdi_sio"}},
+	}
+	root := &models.USBDevice{VendorID: 0x1d6b, ProductID: 0x0002, ProductName: "2.0 root hub", Bus: 1, Class: "Hub", PortPath: "usb1", Children: []*models.USBDevice{ftdi}}
+
+	return models.Report{
+		ReportInfo: models.ReportInfo{SchemaVersion: 1, GeneratedAt: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC), Backend: "lsusb"},
+		Devices:    []*models.USBDevice{root},
+	}
+}
+
+func render(t *testing.T, format string, v any) string {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := Write(&buf, format, v); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	return buf.String()
+}
+
+func jsonValue(t *testing.T, v any) any {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var value any
+	if err := json.Unmarshal(data, &value); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	return value
+}
+
+func TestWriteYAML(t *testing.T) {
+	report := testReport()
+	output := render(t, FormatYAML, report)
+
+	var fromYAML any
+	if err := yaml.Unmarshal([]byte(output), &fromYAML); err != nil {
+		t.Fatalf("Invalid YAML: %v\n%s", err, output)
+	}
+	// Compare through JSON, which turns YAML ints into float64 as well
+	if got, want := jsonValue(t, fromYAML), jsonValue(t, report); !reflect.DeepEqual(got, want) {
+		t.Errorf("YAML does not match JSON:\ngot  %v\nwant %v", got, want)
+	}
+
+	if !strings.HasPrefix(output, "schema_version: 1\ngenerated_at:") {
+		t.Errorf("Expected keys in JSON order, got:\n%s", output)
+	}
+	if !strings.Contains(output, `serial: "0123"`) {
+		t.Errorf("Expected numeric-looking strings to be quoted, got:\n%s", output)
+	}
+}
+
+func TestWriteXML(t *testing.T) {
+	output := render(t, FormatXML, testReport())
+
+	var report struct {
+		XMLName       xml.Name `xml:"report"`
+		SchemaVersion int      `xml:"schema_version"`
+		Devices       []struct {
+			ProductName string `xml:"product_name"`
+			Children    []struct {
+				VendorID    int    `xml:"vendor_id"`
+				ProductName string `xml:"product_name"`
+				Serial      string `xml:"serial"`
+				Power       struct {
+					Persist bool `xml:"persist"`
+				} `xml:"power"`
+				Interfaces []struct {
+					Class  int    `xml:"class"`
+					Driver string `xml:"driver"`
+				} `xml:"interfaces>interface"`
+			} `xml:"children>device"`
+		} `xml:"devices>device"`
+	}
+	if err := xml.Unmarshal([]byte(output), &report); err != nil {
+		t.Fatalf("Invalid XML: %v\n%s", err, output)
+	}
+
+	if report.SchemaVersion != 1 || len(report.Devices) != 1 || report.Devices[0].ProductName != "2.0 root hub" {
+		t.Fatalf("Unexpected report: %+v", report)
+	}
+	children := report.Devices[0].Children
+	if len(children) != 1 {
+		t.Fatalf("Expected 1 child, got %d", len(children))
+	}
+	child := children[0]
+	if child.VendorID != 0x0403 || child.ProductName != `FT232R "USB" UART` || child.Serial != "0123" || !child.Power.Persist {
+		t.Errorf("Unexpected child: %+v", child)
+	}
+	if len(child.Interfaces) != 1 || child.Interfaces[0].Class != 255 || child.Interfaces[0].Driver != "ftdi_sio" {
+		t.Errorf("Unexpected interfaces: %+v", child.Interfaces)
+	}
+}
+
+func TestWriteXML_Null(t *testing.T) {
+	topology := models.NewTopology(testReport().Devices)
+	flat := models.FlatReport{Devices: models.Flatten(topology)}
+	output := render(t, FormatXML, flat)
+
+	if !strings.Contains(output, `<parent_id nil="true"/>`) || !strings.Contains(output, "<parent_id>1</parent_id>") {
+		t.Errorf("Expected parent IDs, got:\n%s", output)
+	}
+}
+
+func TestWriteTOML(t *testing.T) {
+	output := render(t, FormatTOML, testReport())
+
+	for _, want := range []string{
+		"schema_version = 1\ngenerated_at = \"2024-05-01T12:00:00Z\"\n",
+		"\n[[devices]]\nvendor_id = 7531\n",
+		"\n[[devices.children]]\nvendor_id = 1027\n",
+		"product_name = \"FT232R \\\"USB\\\" UART\"\n",
+		"serial = \"0123\"\n",
+		"\n[devices.children.power]\ncontrol = \"on\"\n",
+		"\n[[devices.children.interfaces]]\nnumber = 0\nclass = 255\n",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected %q in:\n%s", want, output)
+		}
+	}
+
+	// Tables must follow all plain keys of their parent
+	power := strings.Index(output, "[devices.children.power]")
+	interfaces := strings.Index(output, "[[devices.children.interfaces]]")
+	if power < strings.Index(output, "port_path = \"1-2\"") || interfaces < power {
+		t.Errorf("Unexpected table order:\n%s", output)
+	}
+}
+
+func TestWriteTOML_NotATable(t *testing.T) {
+	if err := Write(&bytes.Buffer{}, FormatTOML, []int{1}); err == nil {
+		t.Error("Expected error for a list")
+	}
+}
+
+func TestWrite_UnknownFormat(t *testing.T) {
+	if err := Write(&bytes.Buffer{}, "csv", testReport()); err == nil {
+		t.Error("Expected error for unknown format")
+	}
+}
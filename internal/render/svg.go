@@ -0,0 +1,255 @@
+package render
+
+import (
+	"bufio"
+	"fmt"
+	"html"
+	"io"
+
+	"github.com/stegmannb/usbtree/internal/models"
+)
+
+// Layout of the SVG diagram, in pixels. Columns hold the devices of one
+// tier, with hubs' ports drawn as numbered tabs on the right of their box.
+const (
+	svgMargin     = 20
+	svgLegend     = 40
+	svgBoxWidth   = 250
+	svgBoxHeight  = 64
+	svgPortWidth  = 24
+	svgPortHeight = 16
+	svgPortPitch  = 20
+	svgColumnGap  = 70
+	svgRowGap     = 16
+	svgNameChars  = 32
+)
+
+// speedColors are the colors of the speed classes, shared with the HTML
+// report.
+var speedColors = map[string]string{
+	"speed-unknown":   "#999999",
+	"speed-low":       "#8d6e63",
+	"speed-full":      "#f9a825",
+	"speed-high":      "#1e88e5",
+	"speed-super":     "#43a047",
+	"speed-superplus": "#8e24aa",
+}
+
+// svgNode is a device placed in the diagram.
+type svgNode struct {
+	device   *models.USBDevice
+	x, y     int
+	height   int
+	ports    int
+	children []*svgNode
+}
+
+// portCount is the number of port tabs drawn for a hub: its reported port
+// count, extended to fit every child's port number.
+func portCount(device *models.USBDevice) int {
+	ports := device.Ports
+	for _, child := range device.Children {
+		ports = max(ports, child.Port)
+	}
+	return ports
+}
+
+// portSlot is the tab a child is drawn connected to.
+func portSlot(node *svgNode, child *models.USBDevice) int {
+	if child.Port >= 1 && child.Port <= node.ports {
+		return child.Port - 1
+	}
+	return 0
+}
+
+// layoutSVG places device and its children from the top of their band at
+// y and returns the node and the height of the band.
+func layoutSVG(device *models.USBDevice, depth, y int) (*svgNode, int) {
+	node := &svgNode{device: device, x: svgMargin + depth*(svgBoxWidth+svgPortWidth+svgColumnGap), y: y, height: svgBoxHeight}
+	if device.IsHub() {
+		node.ports = portCount(device)
+		node.height = max(svgBoxHeight, 6+node.ports*svgPortPitch)
+	}
+
+	band := 0
+	for i, child := range device.Children {
+		if i > 0 {
+			band += svgRowGap
+		}
+		childNode, height := layoutSVG(child, depth+1, y+band)
+		node.children = append(node.children, childNode)
+		band += height
+	}
+	return node, max(node.height, band)
+}
+
+// WriteSVG draws the device tree as an SVG diagram: hubs as boxes with
+// numbered ports, devices as leaves, edges colored by the negotiated speed
+// of the device below them and badges for class and power. Devices are
+// drawn in the order of the topology, and nothing depends on the time or
+// host, so the same devices always give the same file.
+func WriteSVG(w io.Writer, topology *models.Topology) error {
+	roots := topology.Devices
+
+	var nodes []*svgNode
+	y := svgMargin + svgLegend
+	for i, root := range roots {
+		if i > 0 {
+			y += svgRowGap * 2
+		}
+		node, height := layoutSVG(root, 0, y)
+		nodes = append(nodes, node)
+		y += height
+	}
+
+	width := 2*svgMargin + len(speedLegend)*110
+	var measure func(node *svgNode)
+	measure = func(node *svgNode) {
+		width = max(width, node.x+svgBoxWidth+svgPortWidth+svgMargin)
+		for _, child := range node.children {
+			measure(child)
+		}
+	}
+	for _, node := range nodes {
+		measure(node)
+	}
+	height := y + svgMargin
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-family="sans-serif" font-size="12">`+"\n",
+		width, height, width, height)
+	fmt.Fprintf(bw, `<rect width="%d" height="%d" fill="#ffffff"/>`+"\n", width, height)
+	writeSVGLegend(bw)
+	if len(nodes) == 0 {
+		fmt.Fprintf(bw, `<text x="%d" y="%d">No USB devices found</text>`+"\n", svgMargin, svgMargin+svgLegend+12)
+	}
+	for _, node := range nodes {
+		writeSVGNode(bw, node)
+	}
+	fmt.Fprintln(bw, "</svg>")
+	return bw.Flush()
+}
+
+// speedLegend lists the speed classes in the legend, slowest first.
+var speedLegend = []struct{ class, label string }{
+	{"speed-low", "1.5 Mbps"},
+	{"speed-full", "12 Mbps"},
+	{"speed-high", "480 Mbps"},
+	{"speed-super", "5 Gbps"},
+	{"speed-superplus", "10+ Gbps"},
+	{"speed-unknown", "unknown"},
+}
+
+func writeSVGLegend(w *bufio.Writer) {
+	for i, entry := range speedLegend {
+		x := svgMargin + i*110
+		fmt.Fprintf(w, `<line x1="%d" y1="%d" x2="%d" y2="%d" stroke="%s" stroke-width="3"/>`+"\n",
+			x, svgMargin+6, x+24, svgMargin+6, speedColors[entry.class])
+		fmt.Fprintf(w, `<text x="%d" y="%d">%s</text>`+"\n", x+30, svgMargin+10, entry.label)
+	}
+}
+
+func writeSVGNode(w *bufio.Writer, node *svgNode) {
+	device := node.device
+
+	// Edges first, so boxes are drawn over their ends
+	for _, child := range node.children {
+		slot := portSlot(node, child.device)
+		x1 := node.x + svgBoxWidth + svgPortWidth
+		y1 := node.y + 6 + slot*svgPortPitch + svgPortHeight/2
+		x2 := child.x
+		y2 := child.y + svgBoxHeight/2
+		mid := (x1 + x2) / 2
+		fmt.Fprintf(w, `<path d="M%d %d C%d %d %d %d %d %d" fill="none" stroke="%s" stroke-width="2"/>`+"\n",
+			x1, y1, mid, y1, mid, y2, x2, y2, speedColors[speedClass(child.device)])
+	}
+
+	stroke := "#555555"
+	fill := "#ffffff"
+	if device.IsHub() {
+		fill = "#f2f4f7"
+	}
+	fmt.Fprintf(w, `<g id="%s">`+"\n", html.EscapeString(svgID(device)))
+	fmt.Fprintf(w, `<title>%s</title>`+"\n", html.EscapeString(svgTooltip(device)))
+	fmt.Fprintf(w, `<rect x="%d" y="%d" width="%d" height="%d" rx="6" fill="%s" stroke="%s"/>`+"\n",
+		node.x, node.y, svgBoxWidth, node.height, fill, stroke)
+	fmt.Fprintf(w, `<text x="%d" y="%d" font-weight="bold">%s</text>`+"\n",
+		node.x+8, node.y+18, html.EscapeString(truncate(device.GetDisplayName(), svgNameChars)))
+
+	info := device.GetIDString()
+	if device.PortPath != "" {
+		info += "  " + device.PortPath
+	}
+	if device.Speed != "" && device.Speed != "Unknown" {
+		info += "  " + device.Speed
+	}
+	fmt.Fprintf(w, `<text x="%d" y="%d" fill="#555555">%s</text>`+"\n", node.x+8, node.y+34, html.EscapeString(truncate(info, svgNameChars+8)))
+
+	x := node.x + 8
+	for _, badge := range svgBadges(device) {
+		badgeWidth := 10 + 7*len([]rune(badge.text))
+		fmt.Fprintf(w, `<rect x="%d" y="%d" width="%d" height="16" rx="3" fill="%s"/>`+"\n", x, node.y+42, badgeWidth, badge.color)
+		fmt.Fprintf(w, `<text x="%d" y="%d" fill="#ffffff" font-size="11">%s</text>`+"\n", x+5, node.y+54, html.EscapeString(badge.text))
+		x += badgeWidth + 6
+	}
+
+	for port := 1; port <= node.ports; port++ {
+		px := node.x + svgBoxWidth
+		py := node.y + 6 + (port-1)*svgPortPitch
+		fmt.Fprintf(w, `<rect x="%d" y="%d" width="%d" height="%d" fill="#ffffff" stroke="%s"/>`+"\n",
+			px, py, svgPortWidth, svgPortHeight, stroke)
+		fmt.Fprintf(w, `<text x="%d" y="%d" font-size="10" text-anchor="middle">%d</text>`+"\n",
+			px+svgPortWidth/2, py+12, port)
+	}
+	fmt.Fprintln(w, "</g>")
+
+	for _, child := range node.children {
+		writeSVGNode(w, child)
+	}
+}
+
+// svgID names the group of a device so diagrams can be styled or linked.
+func svgID(device *models.USBDevice) string {
+	if device.PortPath != "" {
+		return "port-" + device.PortPath
+	}
+	return fmt.Sprintf("bus%d-dev%d", device.Bus, device.Address)
+}
+
+// svgTooltip is shown when hovering over a device.
+func svgTooltip(device *models.USBDevice) string {
+	tooltip := fmt.Sprintf("%s [%s]", device.GetDisplayName(), device.GetIDString())
+	if device.VendorName != "" {
+		tooltip += "\n" + device.VendorName
+	}
+	if device.Serial != "" {
+		tooltip += "\nSerial " + device.Serial
+	}
+	return tooltip
+}
+
+type svgBadge struct {
+	text  string
+	color string
+}
+
+// svgBadges are the class and power labels of a device.
+func svgBadges(device *models.USBDevice) []svgBadge {
+	var badges []svgBadge
+	if device.Class != "" && device.Class != "Device" {
+		badges = append(badges, svgBadge{device.Class, "#607d8b"})
+	}
+	if milliamps := device.MaxPowerMilliamps(); milliamps > 0 {
+		badges = append(badges, svgBadge{fmt.Sprintf("%dmA", milliamps), "#c0392b"})
+	}
+	return badges
+}
+
+// truncate shortens s to n runes, marking the cut with an ellipsis.
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n-1]) + "…"
+}
@@ -0,0 +1,69 @@
+package render
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stegmannb/usbtree/internal/models"
+)
+
+func renderSVG(t *testing.T, devices []*models.USBDevice) string {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := WriteSVG(&buf, models.NewTopology(devices)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	return buf.String()
+}
+
+func TestWriteSVG(t *testing.T) {
+	report := testReport()
+	ftdi := report.Devices[0].Children[0]
+	ftdi.MaxPower = "90mA"
+	ftdi.Class = "Vendor <Specific>"
+	report.Devices[0].Ports = 4
+	super := &models.USBDevice{VendorID: 0x1d6b, ProductID: 0x0003, Bus: 2, Class: "Hub", PortPath: "usb2", Speed: "Super (5 Gbps)"}
+
+	output := renderSVG(t, []*models.USBDevice{report.Devices[0], super})
+
+	decoder := xml.NewDecoder(strings.NewReader(output))
+	for {
+		if _, err := decoder.Token(); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("Invalid SVG: %v\n%s", err, output)
+		}
+	}
+
+	for _, expected := range []string{
+		`<g id="port-usb1">`,
+		`<text x="282" y="138" font-size="10" text-anchor="middle">4</text>`,
+		// From port 2 of the root hub to the UART, colored for full speed
+		`<path d="M294 94 C329 94 329 92 364 92" fill="none" stroke="#f9a825" stroke-width="2"/>`,
+		"FT232R &#34;USB&#34; UART",
+		"Vendor &lt;Specific&gt;",
+		">90mA</text>",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected SVG to contain %q:\n%s", expected, output)
+		}
+	}
+	if strings.Index(output, `id="port-usb1"`) > strings.Index(output, `id="port-usb2"`) {
+		t.Error("Expected root hubs in the order given")
+	}
+
+	// The caller's order is kept, e.g. from --sort
+	again := renderSVG(t, []*models.USBDevice{super, report.Devices[0]})
+	if strings.Index(again, `id="port-usb2"`) > strings.Index(again, `id="port-usb1"`) {
+		t.Error("Expected the diagram to keep the order of root hubs")
+	}
+}
+
+func TestWriteSVG_Empty(t *testing.T) {
+	if output := renderSVG(t, nil); !strings.Contains(output, "No USB devices found") {
+		t.Errorf("Unexpected SVG:\n%s", output)
+	}
+}
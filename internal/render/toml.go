@@ -0,0 +1,149 @@
+package render
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// WriteTOML writes v, which must encode to a JSON object, as a TOML
+// document. Nested objects become tables and lists of objects arrays of
+// tables, so the structure matches the JSON encoding. TOML has no null, so
+// null values are left out.
+func WriteTOML(w io.Writer, v any) error {
+	tree, err := toTree(v)
+	if err != nil {
+		return err
+	}
+
+	root, ok := tree.(object)
+	if !ok {
+		return fmt.Errorf("TOML documents must be tables, not %T", tree)
+	}
+
+	bw := bufio.NewWriter(w)
+	writeTOMLTable(bw, nil, root, false)
+	return bw.Flush()
+}
+
+// writeTOMLTable writes the table at path: first its header, then its
+// values, then its subtables, since TOML assigns every key after a header
+// to that header's table.
+func writeTOMLTable(w *bufio.Writer, path []string, table object, arrayEntry bool) {
+	if len(path) > 0 {
+		name := strings.Join(path, ".")
+		if arrayEntry {
+			fmt.Fprintf(w, "\n[[%s]]\n", name)
+		} else {
+			fmt.Fprintf(w, "\n[%s]\n", name)
+		}
+	}
+
+	var tables, arrays []field
+	for _, f := range table {
+		switch v := f.value.(type) {
+		case nil:
+			continue
+		case object:
+			tables = append(tables, f)
+			continue
+		case []any:
+			if isTableArray(v) {
+				arrays = append(arrays, f)
+				continue
+			}
+		}
+		fmt.Fprintf(w, "%s = %s\n", tomlKey(f.key), tomlValue(f.value))
+	}
+
+	for _, f := range tables {
+		writeTOMLTable(w, append(path[:len(path):len(path)], tomlKey(f.key)), f.value.(object), false)
+	}
+	for _, f := range arrays {
+		for _, entry := range f.value.([]any) {
+			writeTOMLTable(w, append(path[:len(path):len(path)], tomlKey(f.key)), entry.(object), true)
+		}
+	}
+}
+
+// isTableArray reports whether a list holds objects, which TOML writes as
+// an array of tables. Empty lists are written inline as [].
+func isTableArray(list []any) bool {
+	if len(list) == 0 {
+		return false
+	}
+	for _, entry := range list {
+		if _, ok := entry.(object); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+var bareKeyRe = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+func tomlKey(key string) string {
+	if bareKeyRe.MatchString(key) {
+		return key
+	}
+	return tomlString(key)
+}
+
+func tomlValue(value any) string {
+	switch v := value.(type) {
+	case string:
+		return tomlString(v)
+	case json.Number:
+		return v.String()
+	case bool:
+		return fmt.Sprint(v)
+	case []any:
+		items := make([]string, 0, len(v))
+		for _, item := range v {
+			if item != nil {
+				items = append(items, tomlValue(item))
+			}
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	case object:
+		items := make([]string, 0, len(v))
+		for _, f := range v {
+			if f.value != nil {
+				items = append(items, tomlKey(f.key)+" = "+tomlValue(f.value))
+			}
+		}
+		return "{" + strings.Join(items, ", ") + "}"
+	default:
+		return `""`
+	}
+}
+
+func tomlString(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		default:
+			if r < 0x20 || r == 0x7f {
+				fmt.Fprintf(&b, `\u%04X`, r)
+			} else {
+				b.WriteRune(r)
+			}
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
@@ -0,0 +1,84 @@
+package render
+
+import (
+	"bufio"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// xmlRoot is the document element of XML output.
+const xmlRoot = "report"
+
+// xmlItemNames names the elements of lists after the list they are in.
+var xmlItemNames = map[string]string{
+	"devices":    "device",
+	"children":   "device",
+	"interfaces": "interface",
+}
+
+// WriteXML writes v as an XML document. Every JSON key becomes an element
+// of the same name, and list entries are named after their list: device
+// for devices and children, interface for interfaces. JSON null becomes an
+// empty element with nil="true".
+func WriteXML(w io.Writer, v any) error {
+	tree, err := toTree(v)
+	if err != nil {
+		return err
+	}
+
+	bw := bufio.NewWriter(w)
+	bw.WriteString(xml.Header)
+	writeXMLElement(bw, xmlRoot, tree, 0)
+	return bw.Flush()
+}
+
+func writeXMLElement(w *bufio.Writer, name string, value any, depth int) {
+	indent := strings.Repeat("  ", depth)
+
+	switch v := value.(type) {
+	case object:
+		if len(v) == 0 {
+			fmt.Fprintf(w, "%s<%s/>\n", indent, name)
+			return
+		}
+		fmt.Fprintf(w, "%s<%s>\n", indent, name)
+		for _, f := range v {
+			writeXMLElement(w, f.key, f.value, depth+1)
+		}
+		fmt.Fprintf(w, "%s</%s>\n", indent, name)
+	case []any:
+		if len(v) == 0 {
+			fmt.Fprintf(w, "%s<%s/>\n", indent, name)
+			return
+		}
+		item, ok := xmlItemNames[name]
+		if !ok {
+			item = "item"
+		}
+		fmt.Fprintf(w, "%s<%s>\n", indent, name)
+		for _, entry := range v {
+			writeXMLElement(w, item, entry, depth+1)
+		}
+		fmt.Fprintf(w, "%s</%s>\n", indent, name)
+	case nil:
+		fmt.Fprintf(w, "%s<%s nil=\"true\"/>\n", indent, name)
+	default:
+		fmt.Fprintf(w, "%s<%s>", indent, name)
+		xml.EscapeText(w, []byte(scalarString(v)))
+		fmt.Fprintf(w, "</%s>\n", name)
+	}
+}
+
+func scalarString(value any) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -9,38 +9,37 @@ import (
 
 type Formatter struct {
 	verbose bool
+	style   *TreeStyle
 }
 
 func NewFormatter(verbose bool) *Formatter {
-	return &Formatter{verbose: verbose}
+	style := treeStyles[StyleUnicode]
+	return &Formatter{verbose: verbose, style: &style}
+}
+
+// SetStyle sets how the tree is drawn.
+func (f *Formatter) SetStyle(style *TreeStyle) {
+	f.style = style
 }
 
 func (f *Formatter) FormatDevice(device *models.USBDevice, prefix string, isLast bool) []string {
 	var lines []string
 	
-	connector := "├── "
-	if isLast {
-		connector = "└── "
-	}
+	connector, continuation := f.style.connector(isLast)
+	chain := f.style.chain(device)
 	
-	deviceLine := fmt.Sprintf("%s%s%s", prefix, connector, f.getDeviceString(device))
+	names := make([]string, len(chain))
+	for i, linked := range chain {
+		names[i] = f.getDeviceString(linked)
+	}
+	deviceLine := fmt.Sprintf("%s%s%s", prefix, connector, strings.Join(names, f.style.Separator))
 	lines = append(lines, deviceLine)
 	
-	if f.verbose {
-		detailPrefix := prefix
-		if isLast {
-			detailPrefix += "    "
-		} else {
-			detailPrefix += "│   "
-		}
-		lines = append(lines, f.getDetailLines(device, detailPrefix)...)
-	}
+	device = chain[len(chain)-1]
+	childPrefix := prefix + continuation
 	
-	childPrefix := prefix
-	if isLast {
-		childPrefix += "    "
-	} else {
-		childPrefix += "│   "
+	if f.verbose {
+		lines = append(lines, f.getDetailLines(device, childPrefix)...)
 	}
 	
 	for i, child := range device.Children {
@@ -53,35 +52,119 @@ func (f *Formatter) FormatDevice(device *models.USBDevice, prefix string, isLast
 }
 
 func (f *Formatter) getDeviceString(device *models.USBDevice) string {
-	name := device.GetDisplayName()
-	idString := device.GetIDString()
+	return Label(device)
+}
+
+// Label is the line naming a device in the tree: its label and name, IDs
+// and class, and the hubs it was lifted past.
+func Label(device *models.USBDevice) string {
+	name := device.GetDeviceName()
+	if device.Label != "" {
+		name = device.Label + ": " + name
+	}
+	label := fmt.Sprintf("%s [%s]", name, device.GetIDString())
 	
 	if device.Class != "" && device.Class != "Device" {
-		return fmt.Sprintf("%s [%s] (%s)", name, idString, device.Class)
+		label += fmt.Sprintf(" (%s)", device.Class)
+	}
+	if len(device.Via) > 0 {
+		label += " via " + viaPath(device)
 	}
 	
-	return fmt.Sprintf("%s [%s]", name, idString)
+	return label
 }
 
 func (f *Formatter) getDetailLines(device *models.USBDevice, prefix string) []string {
 	var lines []string
 	
-	if device.Serial != "" {
-		lines = append(lines, fmt.Sprintf("%s├─ Serial: %s", prefix, device.Serial))
+	for _, detail := range Details(device) {
+		lines = append(lines, fmt.Sprintf("%s%s%s: %s", prefix, f.style.DetailBranch, detail.Label, detail.Value))
 	}
 	
+	lines = append(lines, fmt.Sprintf("%s%sBus %d, Port %d, Address %d", 
+		prefix, f.style.DetailLast, device.Bus, device.Port, device.Address))
+	
+	return lines
+}
+
+// Detail is one labelled line of the verbose device information.
+type Detail struct {
+	Label string
+	Value string
+}
+
+// Details lists the information verbose output shows below a device, apart
+// from its bus position.
+func Details(device *models.USBDevice) []Detail {
+	var details []Detail
+	if device.Note != "" {
+		details = append(details, Detail{"Note", device.Note})
+	}
+	if device.Serial != "" {
+		details = append(details, Detail{"Serial", device.Serial})
+	}
+	if device.LocationID != "" {
+		details = append(details, Detail{"Location ID", device.LocationID})
+	}
 	if device.Speed != "" && device.Speed != "Unknown" {
-		lines = append(lines, fmt.Sprintf("%s├─ Speed: %s", prefix, device.Speed))
+		details = append(details, Detail{"Speed", device.Speed})
 	}
-	
 	if device.MaxPower != "" {
-		lines = append(lines, fmt.Sprintf("%s├─ Max Power: %s", prefix, device.MaxPower))
+		details = append(details, Detail{"Max Power", device.MaxPower})
+	}
+	details = append(details, powerDetails(device.Power)...)
+	return append(details, interfaceDetails(device.Interfaces)...)
+}
+
+// powerDetails describes the runtime power management state of a device.
+// It returns nothing when the platform did not report any.
+func powerDetails(power *models.PowerState) []Detail {
+	if power == nil {
+		return nil
 	}
 	
-	lines = append(lines, fmt.Sprintf("%s└─ Bus %d, Port %d, Address %d", 
-		prefix, device.Bus, device.Port, device.Address))
+	var details []Detail
+	if power.Control != "" {
+		state := power.Control
+		if power.RuntimeStatus != "" {
+			state = fmt.Sprintf("%s (%s)", power.Control, power.RuntimeStatus)
+		}
+		details = append(details, Detail{"Power", state})
+	}
 	
-	return lines
+	autosuspend := "disabled"
+	if power.AutosuspendEnabled() {
+		autosuspend = fmt.Sprintf("after %d ms", power.AutosuspendDelayMs)
+	}
+	details = append(details, Detail{"Autosuspend", autosuspend})
+	details = append(details, Detail{"Runtime", fmt.Sprintf("active %d ms, suspended %d ms",
+		power.ActiveTimeMs, power.SuspendedTimeMs)})
+	
+	if power.Wakeup != "" {
+		details = append(details, Detail{"Wakeup", power.Wakeup})
+	}
+	
+	persist := "off"
+	if power.Persist {
+		persist = "on"
+	}
+	details = append(details, Detail{"Persist", persist})
+	
+	return details
+}
+
+// interfaceDetails lists the interfaces of a device with their class
+// triple and the driver bound to them.
+func interfaceDetails(interfaces []models.Interface) []Detail {
+	var details []Detail
+	for _, iface := range interfaces {
+		value := iface.ClassTriple()
+		if iface.Driver != "" {
+			value = fmt.Sprintf("%s (%s)", value, iface.Driver)
+		}
+		details = append(details, Detail{fmt.Sprintf("Interface %d", iface.Number), value})
+	}
+	return details
 }
 
 func (f *Formatter) FormatTree(devices []*models.USBDevice) string {
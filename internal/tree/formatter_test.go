@@ -178,6 +178,75 @@ func TestFormatter_FormatTree(t *testing.T) {
 	}
 }
 
+func TestFormatter_FormatDevice_PowerState(t *testing.T) {
+	formatter := NewFormatter(true)
+
+	device := &models.USBDevice{
+		VendorID:    0x0403,
+		ProductID:   0x6001,
+		ProductName: "FT232R USB UART",
+		PortPath:    "1-2",
+		Power: &models.PowerState{
+			Control:            "auto",
+			RuntimeStatus:      "suspended",
+			AutosuspendDelayMs: 2000,
+			ActiveTimeMs:       1500,
+			SuspendedTimeMs:    98500,
+			Wakeup:             "disabled",
+			Persist:            true,
+		},
+	}
+
+	output := strings.Join(formatter.FormatDevice(device, "", true), "\n")
+
+	expected := []string{
+		"Power: auto (suspended)",
+		"Autosuspend: after 2000 ms",
+		"Runtime: active 1500 ms, suspended 98500 ms",
+		"Wakeup: disabled",
+		"Persist: on",
+	}
+	for _, want := range expected {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected %q in verbose output, got:\n%s", want, output)
+		}
+	}
+
+	device.Power.Control = "on"
+	output = strings.Join(formatter.FormatDevice(device, "", true), "\n")
+	if !strings.Contains(output, "Autosuspend: disabled") {
+		t.Errorf("Expected autosuspend to be reported as disabled, got:\n%s", output)
+	}
+
+	device.Power = nil
+	output = strings.Join(formatter.FormatDevice(device, "", true), "\n")
+	if strings.Contains(output, "Autosuspend") {
+		t.Errorf("Expected no power details without power state, got:\n%s", output)
+	}
+}
+
+func TestFormatter_FormatDevice_Interfaces(t *testing.T) {
+	formatter := NewFormatter(true)
+
+	device := &models.USBDevice{
+		VendorID:    0x046D,
+		ProductID:   0xC52B,
+		ProductName: "USB Receiver",
+		Interfaces: []models.Interface{
+			{Number: 0, Class: 0x03, SubClass: 0x01, Protocol: 0x01, Driver: "usbhid"},
+			{Number: 1, Class: 0x03, SubClass: 0x01, Protocol: 0x02},
+		},
+	}
+
+	output := strings.Join(formatter.FormatDevice(device, "", true), "\n")
+
+	for _, want := range []string{"Interface 0: 03:01:01 (usbhid)", "Interface 1: 03:01:02"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected %q in verbose output, got:\n%s", want, output)
+		}
+	}
+}
+
 func TestFormatter_TreeConnectors(t *testing.T) {
 	formatter := NewFormatter(false)
 
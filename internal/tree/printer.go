@@ -2,6 +2,9 @@ package tree
 
 import (
 	"fmt"
+	"io"
+	"os"
+	"strings"
 
 	"github.com/fatih/color"
 	"github.com/stegmannb/usbtree/internal/models"
@@ -10,118 +13,181 @@ import (
 type Printer struct {
 	formatter *Formatter
 	useColor  bool
+	theme     *Theme
 }
 
 func NewPrinter(verbose bool) *Printer {
 	return &Printer{
 		formatter: NewFormatter(verbose),
 		useColor:  !color.NoColor,
+		theme:     &defaultTheme,
 	}
 }
 
+// SetColor enables or disables colored output regardless of the terminal.
+func (p *Printer) SetColor(useColor bool) {
+	p.useColor = useColor
+}
+
+// SetStyle sets how the tree is drawn.
+func (p *Printer) SetStyle(style *TreeStyle) {
+	p.formatter.SetStyle(style)
+}
+
+// SetTheme sets the colors of the tree.
+func (p *Printer) SetTheme(theme *Theme) {
+	p.theme = theme
+}
+
 func (p *Printer) Print(devices []*models.USBDevice) {
+	p.Fprint(os.Stdout, devices)
+}
+
+// Fprint writes the device tree to w.
+func (p *Printer) Fprint(w io.Writer, devices []*models.USBDevice) {
 	if len(devices) == 0 {
-		p.printNoDevices()
+		p.printNoDevices(w)
 		return
 	}
 	
-	p.printHeader()
+	p.printHeader(w)
 	
 	for i, device := range devices {
 		isLast := i == len(devices)-1
-		p.printDevice(device, "", isLast)
+		p.printDevice(w, device, "", isLast)
 	}
 }
 
-func (p *Printer) printHeader() {
-	header := color.New(color.FgCyan, color.Bold)
-	header.Println("USB Device Tree:")
-	fmt.Println()
+func (p *Printer) newColor(attributes ...color.Attribute) *color.Color {
+	c := color.New(attributes...)
+	if p.useColor {
+		c.EnableColor()
+	} else {
+		c.DisableColor()
+	}
+	return c
 }
 
-func (p *Printer) printNoDevices() {
-	warning := color.New(color.FgYellow)
-	warning.Println("No USB devices found")
-	fmt.Println("\nNote: This tool requires libusb-1.0 to be installed.")
-	fmt.Println("On macOS: brew install libusb")
-	fmt.Println("On Linux: sudo apt-get install libusb-1.0-0 (or equivalent)")
+// colors are the color names accepted by ParseColor.
+var colors = map[string]color.Attribute{
+	"black":      color.FgBlack,
+	"red":        color.FgRed,
+	"green":      color.FgGreen,
+	"yellow":     color.FgYellow,
+	"blue":       color.FgBlue,
+	"magenta":    color.FgMagenta,
+	"cyan":       color.FgCyan,
+	"white":      color.FgWhite,
+	"gray":       color.FgHiBlack,
+	"hi-red":     color.FgHiRed,
+	"hi-green":   color.FgHiGreen,
+	"hi-yellow":  color.FgHiYellow,
+	"hi-blue":    color.FgHiBlue,
+	"hi-magenta": color.FgHiMagenta,
+	"hi-cyan":    color.FgHiCyan,
+	"hi-white":   color.FgHiWhite,
 }
 
-func (p *Printer) printDevice(device *models.USBDevice, prefix string, isLast bool) {
-	connector := "├── "
-	if isLast {
-		connector = "└── "
+// ParseColor returns the foreground color of a name such as red, cyan or
+// hi-blue.
+func ParseColor(name string) (color.Attribute, error) {
+	attribute, ok := colors[strings.ToLower(name)]
+	if !ok {
+		return 0, fmt.Errorf("unknown color %q", name)
 	}
+	return attribute, nil
+}
+
+func (p *Printer) printHeader(w io.Writer) {
+	header := p.newColor(p.theme.Header...)
+	header.Fprintln(w, "USB Device Tree:")
+	fmt.Fprintln(w)
+}
+
+func (p *Printer) printNoDevices(w io.Writer) {
+	warning := p.newColor(p.theme.Warning...)
+	warning.Fprintln(w, "No USB devices found")
+	fmt.Fprintln(w, "\nNote: This tool requires libusb-1.0 to be installed.")
+	fmt.Fprintln(w, "On macOS: brew install libusb")
+	fmt.Fprintln(w, "On Linux: sudo apt-get install libusb-1.0-0 (or equivalent)")
+}
+
+func (p *Printer) printDevice(w io.Writer, device *models.USBDevice, prefix string, isLast bool) {
+	style := p.formatter.style
+	connector, continuation := style.connector(isLast)
 	
-	treeColor := color.New(color.FgHiBlack)
-	nameColor := color.New(color.FgWhite, color.Bold)
-	idColor := color.New(color.FgGreen)
-	classColor := color.New(color.FgMagenta)
-	
-	fmt.Print(prefix)
-	treeColor.Print(connector)
-	
-	name := device.GetDisplayName()
-	nameColor.Print(name)
+	treeColor := p.newColor(p.theme.Tree...)
 	
-	fmt.Print(" ")
-	idColor.Printf("[%s]", device.GetIDString())
+	fmt.Fprint(w, prefix)
+	treeColor.Fprint(w, connector)
 	
-	if device.Class != "" && device.Class != "Device" {
-		fmt.Print(" ")
-		classColor.Printf("(%s)", device.Class)
+	chain := style.chain(device)
+	for i, linked := range chain {
+		if i > 0 {
+			treeColor.Fprint(w, style.Separator)
+		}
+		p.printName(w, linked)
 	}
+	fmt.Fprintln(w)
 	
-	fmt.Println()
+	device = chain[len(chain)-1]
+	childPrefix := prefix + continuation
 	
 	if p.formatter.verbose {
-		p.printDetails(device, prefix, isLast)
-	}
-	
-	childPrefix := prefix
-	if isLast {
-		childPrefix += "    "
-	} else {
-		childPrefix += "│   "
+		p.printDetails(w, device, childPrefix)
 	}
 	
 	for i, child := range device.Children {
 		isLastChild := i == len(device.Children)-1
-		p.printDevice(child, childPrefix, isLastChild)
+		p.printDevice(w, child, childPrefix, isLastChild)
 	}
 }
 
-func (p *Printer) printDetails(device *models.USBDevice, prefix string, isLast bool) {
-	detailPrefix := prefix
-	if isLast {
-		detailPrefix += "    "
-	} else {
-		detailPrefix += "│   "
+// printName prints the label, name, IDs and class of a device.
+func (p *Printer) printName(w io.Writer, device *models.USBDevice) {
+	nameColor := p.newColor(p.theme.NameStyle(device)...)
+	idColor := p.newColor(p.theme.ID...)
+	classColor := p.newColor(p.theme.Class...)
+	
+	if device.Label != "" {
+		labelColor := nameColor
+		if attribute, err := ParseColor(device.LabelColor); err == nil {
+			labelColor = p.newColor(attribute, color.Bold)
+		}
+		labelColor.Fprint(w, device.Label)
+		fmt.Fprint(w, ": ")
 	}
 	
-	detailColor := color.New(color.FgHiBlack)
-	valueColor := color.New(color.FgCyan)
+	name := device.GetDeviceName()
+	nameColor.Fprint(w, name)
 	
-	if device.Serial != "" {
-		fmt.Print(detailPrefix)
-		detailColor.Print("├─ Serial: ")
-		valueColor.Println(device.Serial)
-	}
+	fmt.Fprint(w, " ")
+	idColor.Fprintf(w, "[%s]", device.GetIDString())
 	
-	if device.Speed != "" && device.Speed != "Unknown" {
-		fmt.Print(detailPrefix)
-		detailColor.Print("├─ Speed: ")
-		valueColor.Println(device.Speed)
+	if device.Class != "" && device.Class != "Device" {
+		fmt.Fprint(w, " ")
+		classColor.Fprintf(w, "(%s)", device.Class)
 	}
 	
-	if device.MaxPower != "" {
-		fmt.Print(detailPrefix)
-		detailColor.Print("├─ Max Power: ")
-		valueColor.Println(device.MaxPower)
+	if len(device.Via) > 0 {
+		viaColor := p.newColor(p.theme.Detail...)
+		viaColor.Fprintf(w, " via %s", viaPath(device))
+	}
+}
+
+func (p *Printer) printDetails(w io.Writer, device *models.USBDevice, detailPrefix string) {
+	style := p.formatter.style
+	detailColor := p.newColor(p.theme.Detail...)
+	valueColor := p.newColor(p.theme.Value...)
+	
+	for _, detail := range Details(device) {
+		fmt.Fprint(w, detailPrefix)
+		detailColor.Fprintf(w, "%s%s: ", style.DetailBranch, detail.Label)
+		valueColor.Fprintln(w, detail.Value)
 	}
 	
-	fmt.Print(detailPrefix)
-	detailColor.Print("└─ ")
-	valueColor.Printf("Bus %d, Port %d, Address %d\n", 
+	fmt.Fprint(w, detailPrefix)
+	detailColor.Fprint(w, style.DetailLast)
+	valueColor.Fprintf(w, "Bus %d, Port %d, Address %d\n", 
 		device.Bus, device.Port, device.Address)
 }
\ No newline at end of file
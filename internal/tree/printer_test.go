@@ -86,4 +86,82 @@ func TestPrinter_PrintDevices(t *testing.T) {
 		}()
 		verbosePrinter.Print([]*models.USBDevice{device})
 	}()
-}
\ No newline at end of file
+}
+
+func TestPrinter_Fprint(t *testing.T) {
+	devices := []*models.USBDevice{
+		{
+			VendorID:    0x1d6b,
+			ProductID:   0x0002,
+			ProductName: "2.0 root hub",
+			Class:       "Hub",
+			Children: []*models.USBDevice{
+				{VendorID: 0x0403, ProductID: 0x6001, ProductName: "FT232R", Serial: "A50285BI"},
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	printer := NewPrinter(true)
+	printer.SetColor(false)
+	printer.Fprint(&buf, devices)
+
+	output := buf.String()
+	for _, want := range []string{
+		"USB Device Tree:",
+		"└── 2.0 root hub [1d6b:0002] (Hub)",
+		"    └── FT232R [0403:6001]",
+		"        ├─ Serial: A50285BI",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected %q in output:\n%s", want, output)
+		}
+	}
+	if strings.Contains(output, "\x1b[") {
+		t.Error("Expected no color codes with color disabled")
+	}
+
+	buf.Reset()
+	printer.SetColor(true)
+	printer.Fprint(&buf, devices)
+	if !strings.Contains(buf.String(), "\x1b[") {
+		t.Error("Expected color codes with color enabled")
+	}
+}
+
+func TestPrinter_Fprint_Label(t *testing.T) {
+	devices := []*models.USBDevice{
+		{VendorID: 0x0403, ProductID: 0x6001, ProductName: "FT232R", Label: "DUT-3 console", LabelColor: "cyan", Note: "Bench 3"},
+	}
+
+	var buf bytes.Buffer
+	printer := NewPrinter(true)
+	printer.SetColor(false)
+	printer.Fprint(&buf, devices)
+
+	output := buf.String()
+	for _, want := range []string{"└── DUT-3 console: FT232R [0403:6001]", "├─ Note: Bench 3"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected %q in output:\n%s", want, output)
+		}
+	}
+	if formatted := NewFormatter(false).FormatTree(devices); !strings.Contains(formatted, "DUT-3 console: FT232R [0403:6001]") {
+		t.Errorf("Expected the formatter to show the label, got:\n%s", formatted)
+	}
+
+	buf.Reset()
+	printer.SetColor(true)
+	printer.Fprint(&buf, devices)
+	if !strings.Contains(buf.String(), "\x1b[36;1mDUT-3 console") {
+		t.Errorf("Expected the label in bold cyan, got %q", buf.String())
+	}
+}
+
+func TestParseColor(t *testing.T) {
+	if attribute, err := ParseColor("Hi-Blue"); err != nil || attribute != color.FgHiBlue {
+		t.Errorf("Expected hi-blue, got %v, %v", attribute, err)
+	}
+	if _, err := ParseColor("plaid"); err == nil {
+		t.Error("Expected an error for an unknown color")
+	}
+}
@@ -0,0 +1,89 @@
+package tree
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/stegmannb/usbtree/internal/models"
+)
+
+// Tree styles accepted by ParseTreeStyle.
+const (
+	StyleUnicode = "unicode"
+	StyleASCII   = "ascii"
+	StyleCompact = "compact"
+	StyleIndent  = "indent"
+)
+
+// TreeStyles lists the valid values of --style.
+var TreeStyles = []string{StyleUnicode, StyleASCII, StyleCompact, StyleIndent}
+
+// TreeStyle is the drawing of the tree shared by the Formatter and the
+// Printer.
+type TreeStyle struct {
+	// Branch and Last connect a device to its parent, Last for the last
+	// child.
+	Branch string
+	Last   string
+	// Pipe and Space continue the lines of the parent below a device that
+	// is not, or is, the last child.
+	Pipe  string
+	Space string
+	// DetailBranch and DetailLast start the verbose detail lines.
+	DetailBranch string
+	DetailLast   string
+	// Collapse puts chains of hubs with a single child on one line,
+	// separated by Separator.
+	Collapse  bool
+	Separator string
+}
+
+var treeStyles = map[string]TreeStyle{
+	StyleUnicode: {
+		Branch: "├── ", Last: "└── ", Pipe: "│   ", Space: "    ",
+		DetailBranch: "├─ ", DetailLast: "└─ ",
+	},
+	StyleASCII: {
+		Branch: "|-- ", Last: "`-- ", Pipe: "|   ", Space: "    ",
+		DetailBranch: "|- ", DetailLast: "`- ",
+	},
+	StyleCompact: {
+		Branch: "├── ", Last: "└── ", Pipe: "│   ", Space: "    ",
+		DetailBranch: "├─ ", DetailLast: "└─ ",
+		Collapse: true, Separator: " → ",
+	},
+	StyleIndent: {
+		Pipe: "  ", Space: "  ",
+		DetailBranch: "  ", DetailLast: "  ",
+	},
+}
+
+// ParseTreeStyle returns the tree style of a name such as unicode or ascii.
+func ParseTreeStyle(name string) (*TreeStyle, error) {
+	style, ok := treeStyles[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("unknown tree style %q: expected one of %s", name, strings.Join(TreeStyles, ", "))
+	}
+	return &style, nil
+}
+
+// connector returns the connector of a device and the prefix continuing the
+// lines of its parent below it.
+func (s *TreeStyle) connector(isLast bool) (connector, continuation string) {
+	if isLast {
+		return s.Last, s.Space
+	}
+	return s.Branch, s.Pipe
+}
+
+// chain returns the devices shown on the line of a device: the device
+// alone, or with Collapse the device and the single children of its hubs.
+// The children of the last device of the chain follow below it.
+func (s *TreeStyle) chain(device *models.USBDevice) []*models.USBDevice {
+	chain := []*models.USBDevice{device}
+	for s.Collapse && device.IsHub() && len(device.Children) == 1 {
+		device = device.Children[0]
+		chain = append(chain, device)
+	}
+	return chain
+}
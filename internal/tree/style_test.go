@@ -0,0 +1,87 @@
+package tree
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stegmannb/usbtree/internal/models"
+)
+
+// chainedHubs is a root hub with a chain of two single-child hubs leading to
+// a hub with two devices.
+func chainedHubs() []*models.USBDevice {
+	dock := &models.USBDevice{ProductName: "Dock", Class: "Hub", Children: []*models.USBDevice{
+		{ProductName: "Keyboard", Class: "HID"},
+		{ProductName: "Disk", Class: "Mass Storage"},
+	}}
+	monitor := &models.USBDevice{ProductName: "Monitor", Class: "Hub", Children: []*models.USBDevice{dock}}
+	return []*models.USBDevice{{ProductName: "root hub", Class: "Hub", Children: []*models.USBDevice{monitor}}}
+}
+
+func TestFormatter_SetStyle(t *testing.T) {
+	tests := map[string]string{
+		StyleUnicode: `└── root hub [0000:0000] (Hub)
+    └── Monitor [0000:0000] (Hub)
+        └── Dock [0000:0000] (Hub)
+            ├── Keyboard [0000:0000] (HID)
+            └── Disk [0000:0000] (Mass Storage)`,
+		StyleASCII: "`-- root hub [0000:0000] (Hub)\n" +
+			"    `-- Monitor [0000:0000] (Hub)\n" +
+			"        `-- Dock [0000:0000] (Hub)\n" +
+			"            |-- Keyboard [0000:0000] (HID)\n" +
+			"            `-- Disk [0000:0000] (Mass Storage)",
+		StyleCompact: `└── root hub [0000:0000] (Hub) → Monitor [0000:0000] (Hub) → Dock [0000:0000] (Hub)
+    ├── Keyboard [0000:0000] (HID)
+    └── Disk [0000:0000] (Mass Storage)`,
+		StyleIndent: `root hub [0000:0000] (Hub)
+  Monitor [0000:0000] (Hub)
+    Dock [0000:0000] (Hub)
+      Keyboard [0000:0000] (HID)
+      Disk [0000:0000] (Mass Storage)`,
+	}
+	for name, expected := range tests {
+		style, err := ParseTreeStyle(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		formatter := NewFormatter(false)
+		formatter.SetStyle(style)
+		output := strings.TrimPrefix(formatter.FormatTree(chainedHubs()), "USB Device Tree:\n\n")
+		if output != expected {
+			t.Errorf("%s: expected\n%s\ngot\n%s", name, expected, output)
+		}
+
+		var buf bytes.Buffer
+		printer := NewPrinter(false)
+		printer.SetColor(false)
+		printer.SetStyle(style)
+		printer.Fprint(&buf, chainedHubs())
+		if printed := strings.TrimPrefix(buf.String(), "USB Device Tree:\n\n"); printed != expected+"\n" {
+			t.Errorf("%s: expected the printer to match the formatter, got\n%s", name, printed)
+		}
+	}
+
+	if _, err := ParseTreeStyle("fancy"); err == nil {
+		t.Error("Expected an error for an unknown style")
+	}
+}
+
+func TestFormatter_SetStyle_Verbose(t *testing.T) {
+	style, err := ParseTreeStyle(StyleASCII)
+	if err != nil {
+		t.Fatal(err)
+	}
+	formatter := NewFormatter(true)
+	formatter.SetStyle(style)
+	output := formatter.FormatTree([]*models.USBDevice{{ProductName: "FT232R", Serial: "A50285BI", Bus: 1, Port: 2, Address: 3}})
+
+	for _, want := range []string{"    |- Serial: A50285BI", "    `- Bus 1, Port 2, Address 3"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected %q in output:\n%s", want, output)
+		}
+	}
+	if strings.ContainsAny(output, "├└│") {
+		t.Errorf("Expected no box-drawing characters in ASCII output:\n%s", output)
+	}
+}
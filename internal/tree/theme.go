@@ -0,0 +1,326 @@
+package tree
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/stegmannb/usbtree/internal/models"
+)
+
+// Color modes accepted by ColorEnabled.
+const (
+	ColorAuto   = "auto"
+	ColorAlways = "always"
+	ColorNever  = "never"
+)
+
+// ColorModes lists the valid values of --color.
+var ColorModes = []string{ColorAuto, ColorAlways, ColorNever}
+
+// ColorEnabled decides whether to print colors. In auto mode a non-empty
+// NO_COLOR disables them and a CLICOLOR_FORCE other than 0 forces them;
+// otherwise colors are printed if the output is a terminal.
+func ColorEnabled(mode string, terminal bool, getenv func(string) string) (bool, error) {
+	switch mode {
+	case ColorAlways:
+		return true, nil
+	case ColorNever:
+		return false, nil
+	case ColorAuto, "":
+	default:
+		return false, fmt.Errorf("invalid color mode %q: expected one of %s", mode, strings.Join(ColorModes, ", "))
+	}
+	if getenv("NO_COLOR") != "" {
+		return false, nil
+	}
+	if force := getenv("CLICOLOR_FORCE"); force != "" && force != "0" {
+		return true, nil
+	}
+	return terminal, nil
+}
+
+// Style is a set of color attributes, such as bold cyan.
+type Style []color.Attribute
+
+// attributes are the style names ParseStyle accepts besides the colors.
+var attributes = map[string]color.Attribute{
+	"bold":      color.Bold,
+	"faint":     color.Faint,
+	"italic":    color.Italic,
+	"underline": color.Underline,
+}
+
+// ParseStyle parses a list of color names and attributes separated by
+// spaces, such as "bold hi-blue".
+func ParseStyle(s string) (Style, error) {
+	var style Style
+	for _, name := range strings.Fields(s) {
+		if attribute, ok := attributes[strings.ToLower(name)]; ok {
+			style = append(style, attribute)
+			continue
+		}
+		attribute, err := ParseColor(name)
+		if err != nil {
+			return nil, err
+		}
+		style = append(style, attribute)
+	}
+	return style, nil
+}
+
+// Color modes of Theme.ColorBy.
+const (
+	ColorByClass = "class"
+	ColorBySpeed = "speed"
+	ColorByPower = "power"
+)
+
+// Theme assigns the colors of the device tree.
+type Theme struct {
+	Header  Style
+	Tree    Style
+	Name    Style
+	ID      Style
+	Class   Style
+	Detail  Style
+	Value   Style
+	Warning Style
+
+	// ColorBy colors device names by class, speed tier or power state,
+	// looked up in Classes, Speeds or Power. Devices without an entry use
+	// Name.
+	ColorBy string
+	// Classes is keyed by the lowercase device class, e.g. hub or hid.
+	Classes map[string]Style
+	// Speeds is keyed by speed tier: low, full, high, super, superplus or
+	// unknown.
+	Speeds map[string]Style
+	// Power is keyed by runtime power state, e.g. active or suspended, or
+	// unknown.
+	Power map[string]Style
+}
+
+// NameStyle returns the style of the name of a device.
+func (t *Theme) NameStyle(device *models.USBDevice) Style {
+	var styles map[string]Style
+	var key string
+	switch t.ColorBy {
+	case ColorByClass:
+		styles, key = t.Classes, strings.ToLower(device.Class)
+	case ColorBySpeed:
+		styles, key = t.Speeds, device.SpeedTier()
+	case ColorByPower:
+		styles, key = t.Power, "unknown"
+		if device.Power != nil && device.Power.RuntimeStatus != "" {
+			key = strings.ToLower(device.Power.RuntimeStatus)
+		}
+	}
+	if style, ok := styles[key]; ok {
+		return style
+	}
+	return t.Name
+}
+
+// defaultTheme is the theme for dark terminals.
+var defaultTheme = Theme{
+	Header:  Style{color.FgCyan, color.Bold},
+	Tree:    Style{color.FgHiBlack},
+	Name:    Style{color.FgWhite, color.Bold},
+	ID:      Style{color.FgGreen},
+	Class:   Style{color.FgMagenta},
+	Detail:  Style{color.FgHiBlack},
+	Value:   Style{color.FgCyan},
+	Warning: Style{color.FgYellow},
+}
+
+// themes are the built-in themes.
+var themes = map[string]func() Theme{
+	"default": func() Theme { return defaultTheme },
+	"light": func() Theme {
+		return Theme{
+			Header:  Style{color.FgBlue, color.Bold},
+			Tree:    Style{color.FgHiBlack},
+			Name:    Style{color.FgBlack, color.Bold},
+			ID:      Style{color.FgGreen},
+			Class:   Style{color.FgMagenta},
+			Detail:  Style{color.FgHiBlack},
+			Value:   Style{color.FgBlue},
+			Warning: Style{color.FgRed},
+		}
+	},
+	"class": func() Theme {
+		theme := defaultTheme
+		theme.ColorBy = ColorByClass
+		theme.Classes = map[string]Style{
+			"hub":                  {color.FgHiBlack, color.Bold},
+			"hid":                  {color.FgHiGreen, color.Bold},
+			"mass storage":         {color.FgHiYellow, color.Bold},
+			"audio":                {color.FgHiMagenta, color.Bold},
+			"video":                {color.FgHiMagenta, color.Bold},
+			"communications":       {color.FgHiCyan, color.Bold},
+			"cdc data":             {color.FgHiCyan, color.Bold},
+			"wireless":             {color.FgHiBlue, color.Bold},
+			"vendor specific":      {color.FgHiRed, color.Bold},
+			"printer":              {color.FgYellow, color.Bold},
+			"smart card":           {color.FgGreen, color.Bold},
+			"billboard":            {color.FgHiBlack},
+			"application specific": {color.FgRed, color.Bold},
+		}
+		return theme
+	},
+	"speed": func() Theme {
+		theme := defaultTheme
+		theme.ColorBy = ColorBySpeed
+		theme.Speeds = map[string]Style{
+			"low":       {color.FgHiBlack, color.Bold},
+			"full":      {color.FgYellow, color.Bold},
+			"high":      {color.FgGreen, color.Bold},
+			"super":     {color.FgHiCyan, color.Bold},
+			"superplus": {color.FgHiBlue, color.Bold},
+		}
+		return theme
+	},
+	"power": func() Theme {
+		theme := defaultTheme
+		theme.ColorBy = ColorByPower
+		theme.Power = map[string]Style{
+			"active":     {color.FgGreen, color.Bold},
+			"suspended":  {color.FgHiBlack, color.Bold},
+			"suspending": {color.FgYellow, color.Bold},
+			"resuming":   {color.FgYellow, color.Bold},
+			"error":      {color.FgRed, color.Bold},
+		}
+		return theme
+	},
+}
+
+// ThemeNames returns the names of the built-in themes.
+func ThemeNames() []string {
+	names := make([]string, 0, len(themes))
+	for name := range themes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ThemeSpec is a user theme from the configuration file. Styles are
+// written like "bold hi-blue"; fields left out keep the style of Base.
+type ThemeSpec struct {
+	// Base is the built-in theme to start from, default if empty.
+	Base    string            `yaml:"base"`
+	ColorBy string            `yaml:"color-by"`
+	Header  string            `yaml:"header"`
+	Tree    string            `yaml:"tree"`
+	Name    string            `yaml:"name"`
+	ID      string            `yaml:"id"`
+	Class   string            `yaml:"class"`
+	Detail  string            `yaml:"detail"`
+	Value   string            `yaml:"value"`
+	Warning string            `yaml:"warning"`
+	Classes map[string]string `yaml:"classes"`
+	Speeds  map[string]string `yaml:"speeds"`
+	Power   map[string]string `yaml:"power"`
+}
+
+// Theme builds the theme the spec describes.
+func (s ThemeSpec) Theme() (*Theme, error) {
+	base := s.Base
+	if base == "" {
+		base = "default"
+	}
+	builtin, ok := themes[base]
+	if !ok {
+		return nil, fmt.Errorf("unknown base theme %q: expected one of %s", base, strings.Join(ThemeNames(), ", "))
+	}
+	theme := builtin()
+
+	switch s.ColorBy {
+	case "":
+	case ColorByClass, ColorBySpeed, ColorByPower:
+		theme.ColorBy = s.ColorBy
+	default:
+		return nil, fmt.Errorf("invalid color-by %q: expected class, speed or power", s.ColorBy)
+	}
+
+	for _, field := range []struct {
+		name  string
+		value string
+		style *Style
+	}{
+		{"header", s.Header, &theme.Header},
+		{"tree", s.Tree, &theme.Tree},
+		{"name", s.Name, &theme.Name},
+		{"id", s.ID, &theme.ID},
+		{"class", s.Class, &theme.Class},
+		{"detail", s.Detail, &theme.Detail},
+		{"value", s.Value, &theme.Value},
+		{"warning", s.Warning, &theme.Warning},
+	} {
+		if field.value == "" {
+			continue
+		}
+		style, err := ParseStyle(field.value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", field.name, err)
+		}
+		*field.style = style
+	}
+
+	var err error
+	if theme.Classes, err = mergeStyles("classes", theme.Classes, s.Classes); err != nil {
+		return nil, err
+	}
+	if theme.Speeds, err = mergeStyles("speeds", theme.Speeds, s.Speeds); err != nil {
+		return nil, err
+	}
+	if theme.Power, err = mergeStyles("power", theme.Power, s.Power); err != nil {
+		return nil, err
+	}
+	return &theme, nil
+}
+
+// mergeStyles returns the styles of a base theme overridden by those of a
+// spec, with lowercase keys.
+func mergeStyles(name string, base map[string]Style, spec map[string]string) (map[string]Style, error) {
+	if len(spec) == 0 {
+		return base, nil
+	}
+	merged := make(map[string]Style, len(base)+len(spec))
+	for key, style := range base {
+		merged[key] = style
+	}
+	for key, value := range spec {
+		style, err := ParseStyle(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s.%s: %w", name, key, err)
+		}
+		merged[strings.ToLower(key)] = style
+	}
+	return merged, nil
+}
+
+// LookupTheme returns the user theme or, if there is none by that name, the
+// built-in theme.
+func LookupTheme(name string, specs map[string]ThemeSpec) (*Theme, error) {
+	if spec, ok := specs[name]; ok {
+		theme, err := spec.Theme()
+		if err != nil {
+			return nil, fmt.Errorf("theme %s: %w", name, err)
+		}
+		return theme, nil
+	}
+	builtin, ok := themes[name]
+	if !ok {
+		names := ThemeNames()
+		for user := range specs {
+			names = append(names, user)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("unknown theme %q: expected one of %s", name, strings.Join(names, ", "))
+	}
+	theme := builtin()
+	return &theme, nil
+}
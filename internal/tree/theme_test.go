@@ -0,0 +1,128 @@
+package tree
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/fatih/color"
+	"github.com/stegmannb/usbtree/internal/models"
+)
+
+func TestColorEnabled(t *testing.T) {
+	tests := []struct {
+		mode     string
+		terminal bool
+		env      map[string]string
+		expected bool
+	}{
+		{ColorAlways, false, map[string]string{"NO_COLOR": "1"}, true},
+		{ColorNever, true, map[string]string{"CLICOLOR_FORCE": "1"}, false},
+		{ColorAuto, true, nil, true},
+		{ColorAuto, false, nil, false},
+		{ColorAuto, true, map[string]string{"NO_COLOR": "1"}, false},
+		{ColorAuto, false, map[string]string{"CLICOLOR_FORCE": "1"}, true},
+		{ColorAuto, false, map[string]string{"CLICOLOR_FORCE": "0"}, false},
+		{ColorAuto, false, map[string]string{"NO_COLOR": "1", "CLICOLOR_FORCE": "1"}, false},
+	}
+	for _, tt := range tests {
+		getenv := func(name string) string { return tt.env[name] }
+		enabled, err := ColorEnabled(tt.mode, tt.terminal, getenv)
+		if err != nil || enabled != tt.expected {
+			t.Errorf("ColorEnabled(%s, %v, %v) = %v, %v, expected %v", tt.mode, tt.terminal, tt.env, enabled, err, tt.expected)
+		}
+	}
+
+	if _, err := ColorEnabled("sometimes", true, func(string) string { return "" }); err == nil {
+		t.Error("Expected an error for an unknown color mode")
+	}
+}
+
+func TestParseStyle(t *testing.T) {
+	style, err := ParseStyle("bold hi-blue")
+	if err != nil || !reflect.DeepEqual(style, Style{color.Bold, color.FgHiBlue}) {
+		t.Errorf("Expected bold hi-blue, got %v, %v", style, err)
+	}
+	if _, err := ParseStyle("bold plaid"); err == nil {
+		t.Error("Expected an error for an unknown color")
+	}
+}
+
+func TestTheme_NameStyle(t *testing.T) {
+	hub := &models.USBDevice{Class: "Hub", Speed: "High (480 Mbps)"}
+	suspended := &models.USBDevice{Class: "HID", Speed: "Low (1.5 Mbps)", Power: &models.PowerState{RuntimeStatus: "suspended"}}
+
+	tests := []struct {
+		theme    string
+		device   *models.USBDevice
+		expected Style
+	}{
+		{"default", hub, defaultTheme.Name},
+		{"class", hub, Style{color.FgHiBlack, color.Bold}},
+		{"class", &models.USBDevice{Class: "Unheard Of"}, defaultTheme.Name},
+		{"speed", hub, Style{color.FgGreen, color.Bold}},
+		{"speed", suspended, Style{color.FgHiBlack, color.Bold}},
+		{"power", suspended, Style{color.FgHiBlack, color.Bold}},
+		{"power", hub, defaultTheme.Name},
+	}
+	for _, tt := range tests {
+		theme, err := LookupTheme(tt.theme, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if style := theme.NameStyle(tt.device); !reflect.DeepEqual(style, tt.expected) {
+			t.Errorf("%s: expected %v for %s, got %v", tt.theme, tt.expected, tt.device.Class, style)
+		}
+	}
+}
+
+func TestLookupTheme(t *testing.T) {
+	specs := map[string]ThemeSpec{
+		"bench": {Base: "light", ColorBy: "power", Header: "bold red", Power: map[string]string{"Unknown": "faint"}},
+		"light": {Name: "blue"},
+	}
+
+	bench, err := LookupTheme("bench", specs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(bench.Header, Style{color.Bold, color.FgRed}) || !reflect.DeepEqual(bench.Value, Style{color.FgBlue}) {
+		t.Errorf("Expected the header of the spec and the values of light, got %v and %v", bench.Header, bench.Value)
+	}
+	if style := bench.NameStyle(&models.USBDevice{}); !reflect.DeepEqual(style, Style{color.Faint}) {
+		t.Errorf("Expected devices without a power state to be faint, got %v", style)
+	}
+
+	light, err := LookupTheme("light", specs)
+	if err != nil || !reflect.DeepEqual(light.Name, Style{color.FgBlue}) {
+		t.Errorf("Expected the user theme to replace the built-in one, got %v, %v", light, err)
+	}
+
+	if _, err := LookupTheme("neon", specs); err == nil || !strings.Contains(err.Error(), "bench") {
+		t.Errorf("Expected an error listing the user themes, got %v", err)
+	}
+	for _, spec := range []ThemeSpec{{Base: "neon"}, {ColorBy: "vendor"}, {ID: "plaid"}, {Speeds: map[string]string{"high": "plaid"}}} {
+		if _, err := spec.Theme(); err == nil {
+			t.Errorf("Expected an error for %+v", spec)
+		}
+	}
+}
+
+func TestPrinter_SetTheme(t *testing.T) {
+	devices := []*models.USBDevice{{VendorID: 0x1d6b, ProductID: 0x0003, ProductName: "3.0 root hub", Class: "Hub", Speed: "Super (5 Gbps)"}}
+	theme, err := LookupTheme("speed", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	printer := NewPrinter(false)
+	printer.SetColor(true)
+	printer.SetTheme(theme)
+	printer.Fprint(&buf, devices)
+
+	if !strings.Contains(buf.String(), "\x1b[96;1m3.0 root hub") {
+		t.Errorf("Expected the name in the color of its speed, got %q", buf.String())
+	}
+}
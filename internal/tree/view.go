@@ -0,0 +1,103 @@
+package tree
+
+import (
+	"strings"
+
+	"github.com/stegmannb/usbtree/internal/models"
+)
+
+// View selects the part of a large device tree to show. The zero View shows
+// the whole tree.
+type View struct {
+	// Root, if set, shows only the subtrees below the devices it matches.
+	Root *models.Selector
+	// HideEmptyRootHubs leaves out root hubs without devices.
+	HideEmptyRootHubs bool
+	// HideHubs leaves out the hubs below the roots. Their devices move up
+	// to the nearest shown hub, with the hidden hubs in Via.
+	HideHubs bool
+	// Depth limits the levels of devices shown, 1 for the roots only, 0
+	// for all.
+	Depth int
+}
+
+// Apply returns the roots of the part of the tree the view shows. The
+// devices returned are copies linked to their shown parents, with the roots
+// at depth 0; the tree passed in is not changed.
+func (v View) Apply(devices []*models.USBDevice) []*models.USBDevice {
+	if v.Root != nil {
+		devices = v.roots(devices)
+	} else if v.HideEmptyRootHubs {
+		var shown []*models.USBDevice
+		for _, device := range devices {
+			if device.HasChildren() {
+				shown = append(shown, device)
+			}
+		}
+		devices = shown
+	}
+
+	shown := make([]*models.USBDevice, len(devices))
+	for i, device := range devices {
+		shown[i] = v.copy(device, nil, 1)
+	}
+	return shown
+}
+
+// roots returns the devices the Root selector matches, leaving out those
+// below another match.
+func (v View) roots(devices []*models.USBDevice) []*models.USBDevice {
+	var roots []*models.USBDevice
+	for _, device := range devices {
+		if v.Root.Matches(device) {
+			roots = append(roots, device)
+			continue
+		}
+		roots = append(roots, v.roots(device.Children)...)
+	}
+	return roots
+}
+
+// copy copies a device shown below parent at the given level with its
+// shown children.
+func (v View) copy(device, parent *models.USBDevice, level int) *models.USBDevice {
+	shown := *device
+	shown.Children = nil
+	shown.Parent = parent
+	shown.Depth = level - 1
+	if v.Depth > 0 && level >= v.Depth {
+		return &shown
+	}
+	for _, child := range v.children(device, nil) {
+		shown.Children = append(shown.Children, v.copy(child, &shown, level+1))
+	}
+	return &shown
+}
+
+// children returns the children of a device, replacing hidden hubs with
+// their children, with the hubs they were lifted past in Via.
+func (v View) children(device *models.USBDevice, via []*models.USBDevice) []*models.USBDevice {
+	var children []*models.USBDevice
+	for _, child := range device.Children {
+		if !v.HideHubs || !child.IsHub() {
+			if len(via) > 0 {
+				lifted := *child
+				lifted.Via = via
+				child = &lifted
+			}
+			children = append(children, child)
+			continue
+		}
+		children = append(children, v.children(child, append(via[:len(via):len(via)], child))...)
+	}
+	return children
+}
+
+// viaPath names the hubs a device was lifted past, e.g. "Monitor > Dock".
+func viaPath(device *models.USBDevice) string {
+	names := make([]string, len(device.Via))
+	for i, hub := range device.Via {
+		names[i] = hub.GetDisplayName()
+	}
+	return strings.Join(names, " > ")
+}
@@ -0,0 +1,81 @@
+package tree
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stegmannb/usbtree/internal/models"
+)
+
+// rack is a bus with an empty root hub and one with a chain of hubs.
+func rack() []*models.USBDevice {
+	devices := []*models.USBDevice{
+		{ProductName: "3.0 root hub", Class: "Hub", PortPath: "usb2"},
+		{ProductName: "2.0 root hub", Class: "Hub", PortPath: "usb1", Children: []*models.USBDevice{
+			{ProductName: "Rack Hub", Class: "Hub", PortPath: "1-1", Children: []*models.USBDevice{
+				{ProductName: "Shelf Hub", Class: "Hub", PortPath: "1-1.1", Children: []*models.USBDevice{
+					{ProductName: "FT232R", PortPath: "1-1.1.1"},
+				}},
+				{ProductName: "Relay", PortPath: "1-1.2"},
+			}},
+		}},
+	}
+	models.NewTopology(devices)
+	return devices
+}
+
+func formatView(view View, devices []*models.USBDevice) string {
+	formatter := NewFormatter(false)
+	return strings.TrimPrefix(formatter.FormatTree(view.Apply(devices)), "USB Device Tree:\n\n")
+}
+
+func TestView_Apply(t *testing.T) {
+	shelf, err := models.ParseSelector("1-1.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]struct {
+		view     View
+		expected string
+	}{
+		"whole tree": {View{}, `├── 3.0 root hub [0000:0000] (Hub)
+└── 2.0 root hub [0000:0000] (Hub)
+    └── Rack Hub [0000:0000] (Hub)
+        ├── Shelf Hub [0000:0000] (Hub)
+        │   └── FT232R [0000:0000]
+        └── Relay [0000:0000]`},
+		"depth": {View{Depth: 2}, `├── 3.0 root hub [0000:0000] (Hub)
+└── 2.0 root hub [0000:0000] (Hub)
+    └── Rack Hub [0000:0000] (Hub)`},
+		"root": {View{Root: &shelf}, `└── Shelf Hub [0000:0000] (Hub)
+    └── FT232R [0000:0000]`},
+		"hide hubs": {View{HideHubs: true, HideEmptyRootHubs: true}, `└── 2.0 root hub [0000:0000] (Hub)
+    ├── FT232R [0000:0000] via Rack Hub > Shelf Hub
+    └── Relay [0000:0000] via Rack Hub`},
+	}
+	for name, tt := range tests {
+		if output := formatView(tt.view, rack()); output != tt.expected {
+			t.Errorf("%s: expected\n%s\ngot\n%s", name, tt.expected, output)
+		}
+	}
+}
+
+func TestView_Apply_KeepsTree(t *testing.T) {
+	devices := rack()
+	View{HideHubs: true, Depth: 1}.Apply(devices)
+
+	shown := View{Root: &models.Selector{Kind: models.SelectPort, Value: "1-1"}, HideHubs: true}.Apply(devices)
+	if relay := shown[0].Children[1]; shown[0].Parent != nil || shown[0].Depth != 0 || relay.Parent != shown[0] || relay.Depth != 1 {
+		t.Error("Expected the copies to be linked to their shown parents")
+	}
+
+	if len(devices[1].Children) != 1 || len(devices[1].Children[0].Children) != 2 {
+		t.Error("Expected the view to leave the tree unchanged")
+	}
+	for _, device := range models.NewTopology(devices).All() {
+		if len(device.Via) > 0 {
+			t.Errorf("Expected no Via on %s", device.GetDisplayName())
+		}
+	}
+}
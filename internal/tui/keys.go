@@ -0,0 +1,68 @@
+package tui
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// escapeKeys maps the escape sequences terminals send for special keys, in
+// both normal and application cursor mode.
+var escapeKeys = map[string]string{
+	"\x1b[A": "up", "\x1bOA": "up",
+	"\x1b[B": "down", "\x1bOB": "down",
+	"\x1b[C": "right", "\x1bOC": "right",
+	"\x1b[D": "left", "\x1bOD": "left",
+	"\x1b[H": "home", "\x1bOH": "home", "\x1b[1~": "home",
+	"\x1b[F": "end", "\x1bOF": "end", "\x1b[4~": "end",
+	"\x1b[5~": "pgup",
+	"\x1b[6~": "pgdown",
+}
+
+// parseKeys splits what was read from the terminal into key names: the
+// names in escapeKeys, enter, esc, backspace, ctrl-c, or the character
+// typed. Unknown escape sequences are dropped.
+func parseKeys(input []byte) []string {
+	var keys []string
+	for len(input) > 0 {
+		if input[0] == 0x1b {
+			if len(input) == 1 {
+				keys = append(keys, "esc")
+				break
+			}
+			// A sequence ends with its first letter or tilde
+			end := 1
+			if input[1] == '[' || input[1] == 'O' {
+				end = 2
+				for end < len(input) && !strings.ContainsRune("ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz~", rune(input[end])) {
+					end++
+				}
+				end = min(end+1, len(input))
+			}
+			if key, ok := escapeKeys[string(input[:end])]; ok {
+				keys = append(keys, key)
+			} else if end == 1 {
+				keys = append(keys, "esc")
+			}
+			input = input[end:]
+			continue
+		}
+
+		switch input[0] {
+		case '\r', '\n':
+			keys = append(keys, "enter")
+		case 0x7f, 0x08:
+			keys = append(keys, "backspace")
+		case 0x03:
+			keys = append(keys, "ctrl-c")
+		default:
+			r, size := utf8.DecodeRune(input)
+			if r != utf8.RuneError && r >= ' ' {
+				keys = append(keys, string(r))
+			}
+			input = input[size:]
+			continue
+		}
+		input = input[1:]
+	}
+	return keys
+}
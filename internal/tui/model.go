@@ -0,0 +1,598 @@
+// Package tui implements usbtree tui, a full-screen browser of the device
+// tree with a detail pane, search, filtering and live hotplug updates.
+package tui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/stegmannb/usbtree/internal/hotplug"
+	"github.com/stegmannb/usbtree/internal/models"
+	"github.com/stegmannb/usbtree/internal/tree"
+)
+
+// highlightDuration is how long added and changed devices are highlighted
+// and removed ones stay in the tree.
+const highlightDuration = 5 * time.Second
+
+// ANSI sequences used to style rows.
+const (
+	styleReset   = "\x1b[0m"
+	styleInverse = "\x1b[7m"
+	styleBold    = "\x1b[1m"
+	styleRed     = "\x1b[31m"
+	styleGreen   = "\x1b[32m"
+	styleYellow  = "\x1b[33m"
+	styleDim     = "\x1b[2m"
+)
+
+type mode int
+
+const (
+	modeNormal mode = iota
+	modeSearch
+	modeFilter
+)
+
+// highlight marks a device added or changed by a hotplug event.
+type highlight struct {
+	action hotplug.Action
+	until  time.Time
+}
+
+// ghost is a removed device kept in the tree below its former parent for a
+// while, so it can be seen disappearing.
+type ghost struct {
+	device *models.USBDevice
+	parent string
+	until  time.Time
+}
+
+// row is one line of the tree pane.
+type row struct {
+	device *models.USBDevice
+	key    string
+	prefix string
+	ghost  bool
+	// children is whether the device has visible children, collapsed or not
+	children bool
+}
+
+// Model is the state of the browser. It is driven by HandleKey and Update
+// and drawn by View, so it can be tested without a terminal.
+type Model struct {
+	topology  *models.Topology
+	collapsed map[string]bool
+
+	// The cursor follows a device by key; index is the fallback when that
+	// device is gone
+	cursor string
+	index  int
+	offset int
+	height int
+
+	mode   mode
+	input  string
+	search string
+	filter string
+
+	highlights map[string]highlight
+	ghosts     []ghost
+	status     string
+	statusTill time.Time
+	err        error
+
+	devNodes func(*models.USBDevice) []string
+	now      func() time.Time
+}
+
+// NewModel returns an empty browser. devNodes lists the /dev nodes of a
+// device for the detail pane and may be nil.
+func NewModel(devNodes func(*models.USBDevice) []string) *Model {
+	return &Model{
+		topology:   models.NewTopology(nil),
+		collapsed:  make(map[string]bool),
+		highlights: make(map[string]highlight),
+		devNodes:   devNodes,
+		now:        time.Now,
+	}
+}
+
+// Update applies the result of an enumeration: a new topology with the
+// events that led to it, or an error.
+func (m *Model) Update(update hotplug.Update) {
+	if update.Err != nil {
+		m.err = update.Err
+		return
+	}
+	m.err = nil
+	m.topology = update.Topology
+
+	until := m.now().Add(highlightDuration)
+	for _, event := range update.Events {
+		key := hotplug.Key(event.Device)
+		switch event.Action {
+		case hotplug.ActionRemove:
+			parent := ""
+			if event.Device.Parent != nil {
+				parent = hotplug.Key(event.Device.Parent)
+			}
+			m.ghosts = append(m.ghosts, ghost{device: event.Device, parent: parent, until: until})
+			delete(m.highlights, key)
+		default:
+			m.highlights[key] = highlight{action: event.Action, until: until}
+		}
+		m.setStatus(describe(event))
+	}
+	m.Tick()
+}
+
+// describe is the status line for a hotplug event.
+func describe(event hotplug.Event) string {
+	name := event.Device.GetDisplayName()
+	at := event.Time.Format("15:04:05")
+	switch event.Action {
+	case hotplug.ActionAdd:
+		return fmt.Sprintf("%s attached %s at %s", at, name, hotplug.Key(event.Device))
+	case hotplug.ActionRemove:
+		return fmt.Sprintf("%s detached %s from %s", at, name, hotplug.Key(event.Device))
+	default:
+		return fmt.Sprintf("%s %s at %s changed %s", at, name, hotplug.Key(event.Device), strings.Join(event.Changes, ", "))
+	}
+}
+
+func (m *Model) setStatus(status string) {
+	m.status = status
+	m.statusTill = m.now().Add(highlightDuration)
+}
+
+// Tick drops expired highlights and removed devices.
+func (m *Model) Tick() {
+	now := m.now()
+	for key, h := range m.highlights {
+		if now.After(h.until) {
+			delete(m.highlights, key)
+		}
+	}
+	var ghosts []ghost
+	for _, g := range m.ghosts {
+		// A device plugged back in replaces its ghost
+		if now.After(g.until) || m.topology.FindByPortPath(g.device.PortPath) != nil && g.device.PortPath != "" {
+			continue
+		}
+		ghosts = append(ghosts, g)
+	}
+	m.ghosts = ghosts
+}
+
+// matcher returns whether a device matches a search or filter query: as a
+// device selector (port path, vendor:product, serial=..., name=...) or by
+// case-insensitive substring of its name, IDs, port path or serial.
+func matcher(query string) func(*models.USBDevice) bool {
+	lower := strings.ToLower(query)
+	sel, err := models.ParseSelector(query)
+	return func(device *models.USBDevice) bool {
+		if err == nil && sel.Matches(device) {
+			return true
+		}
+		for _, field := range []string{device.GetDisplayName(), device.VendorName, device.ProductName,
+			device.GetIDString(), device.PortPath, device.Serial, device.Class} {
+			if strings.Contains(strings.ToLower(field), lower) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// visible returns the devices left by the filter: the matches and the hubs
+// leading to them. It returns nil without a filter.
+func (m *Model) visible() map[*models.USBDevice]bool {
+	if m.filter == "" {
+		return nil
+	}
+	visible := make(map[*models.USBDevice]bool)
+	for _, device := range m.topology.Find(matcher(m.filter)) {
+		visible[device] = true
+		for _, ancestor := range m.topology.Ancestors(device) {
+			visible[ancestor] = true
+		}
+	}
+	return visible
+}
+
+// rows lists the lines of the tree pane.
+func (m *Model) rows() []row {
+	visible := m.visible()
+
+	type entry struct {
+		device *models.USBDevice
+		ghost  bool
+	}
+	entries := func(devices []*models.USBDevice, parent string) []entry {
+		var result []entry
+		for _, device := range devices {
+			if visible == nil || visible[device] {
+				result = append(result, entry{device: device})
+			}
+		}
+		for _, g := range m.ghosts {
+			if g.parent == parent && (visible == nil || matcher(m.filter)(g.device)) {
+				result = append(result, entry{device: g.device, ghost: true})
+			}
+		}
+		return result
+	}
+
+	var rows []row
+	var walk func(list []entry, prefix string)
+	walk = func(list []entry, prefix string) {
+		for i, e := range list {
+			last := i == len(list)-1
+			connector, childPrefix := "├── ", prefix+"│   "
+			if last {
+				connector, childPrefix = "└── ", prefix+"    "
+			}
+
+			key := hotplug.Key(e.device)
+			var children []entry
+			if !e.ghost {
+				children = entries(e.device.Children, key)
+			}
+			rows = append(rows, row{device: e.device, key: key, prefix: prefix + connector, ghost: e.ghost, children: len(children) > 0})
+			if !m.collapsed[key] {
+				walk(children, childPrefix)
+			}
+		}
+	}
+	walk(entries(m.topology.Devices, ""), "")
+	return rows
+}
+
+// cursorIndex finds the row of the cursor, falling back to the last known
+// position when the device is gone.
+func (m *Model) cursorIndex(rows []row) int {
+	for i, r := range rows {
+		if r.key == m.cursor {
+			return i
+		}
+	}
+	return max(0, min(m.index, len(rows)-1))
+}
+
+func (m *Model) moveTo(rows []row, index int) {
+	if len(rows) == 0 {
+		return
+	}
+	index = max(0, min(index, len(rows)-1))
+	m.cursor = rows[index].key
+	m.index = index
+}
+
+// Selected returns the device under the cursor, or nil if the tree is
+// empty.
+func (m *Model) Selected() *models.USBDevice {
+	rows := m.rows()
+	if len(rows) == 0 {
+		return nil
+	}
+	return rows[m.cursorIndex(rows)].device
+}
+
+// HandleKey applies a key as returned by parseKeys and reports whether the
+// browser should quit.
+func (m *Model) HandleKey(key string) bool {
+	if key == "ctrl-c" {
+		return true
+	}
+	if m.mode != modeNormal {
+		m.handleInput(key)
+		return false
+	}
+
+	rows := m.rows()
+	index := m.cursorIndex(rows)
+	page := max(1, m.height-1)
+
+	switch key {
+	case "q":
+		return true
+	case "up", "k":
+		m.moveTo(rows, index-1)
+	case "down", "j":
+		m.moveTo(rows, index+1)
+	case "pgup":
+		m.moveTo(rows, index-page)
+	case "pgdown":
+		m.moveTo(rows, index+page)
+	case "home", "g":
+		m.moveTo(rows, 0)
+	case "end", "G":
+		m.moveTo(rows, len(rows)-1)
+	case "right", "l":
+		if len(rows) > 0 && rows[index].children {
+			if m.collapsed[rows[index].key] {
+				delete(m.collapsed, rows[index].key)
+			} else {
+				m.moveTo(rows, index+1)
+			}
+		}
+	case "left", "h":
+		if len(rows) == 0 {
+			break
+		}
+		if rows[index].children && !m.collapsed[rows[index].key] {
+			m.collapsed[rows[index].key] = true
+		} else if parent := rows[index].device.Parent; parent != nil && !rows[index].ghost {
+			m.cursor = hotplug.Key(parent)
+		}
+	case "enter", " ":
+		if len(rows) > 0 && rows[index].children {
+			m.collapsed[rows[index].key] = !m.collapsed[rows[index].key]
+		}
+	case "/":
+		m.mode, m.input = modeSearch, ""
+	case "f":
+		m.mode, m.input = modeFilter, m.filter
+	case "n":
+		m.jump(m.search, 1, false)
+	case "N":
+		m.jump(m.search, -1, false)
+	case "esc":
+		m.search, m.filter = "", ""
+	}
+	return false
+}
+
+// handleInput edits the search or filter being typed.
+func (m *Model) handleInput(key string) {
+	switch key {
+	case "enter":
+		if m.mode == modeSearch {
+			m.search = m.input
+		}
+		m.mode = modeNormal
+		return
+	case "esc":
+		if m.mode == modeFilter {
+			m.filter = ""
+		}
+		m.mode = modeNormal
+		return
+	case "backspace":
+		if runes := []rune(m.input); len(runes) > 0 {
+			m.input = string(runes[:len(runes)-1])
+		}
+	default:
+		if len([]rune(key)) != 1 {
+			return
+		}
+		m.input += key
+	}
+
+	// Both search and filter follow the input as it is typed
+	if m.mode == modeSearch {
+		m.jump(m.input, 1, true)
+	} else {
+		m.filter = m.input
+	}
+}
+
+// jump moves the cursor to the next device matching query in direction
+// dir, expanding the hubs above it. With inclusive the device under the
+// cursor counts as a match, so typing refines the current match.
+func (m *Model) jump(query string, dir int, inclusive bool) {
+	if query == "" {
+		return
+	}
+	match := matcher(query)
+	visible := m.visible()
+
+	var candidates []*models.USBDevice
+	for _, device := range m.topology.All() {
+		if visible == nil || visible[device] {
+			candidates = append(candidates, device)
+		}
+	}
+	if len(candidates) == 0 {
+		return
+	}
+
+	start := 0
+	for i, device := range candidates {
+		if hotplug.Key(device) == m.cursor {
+			start = i
+			if !inclusive {
+				start += dir
+			}
+			break
+		}
+	}
+
+	for n := 0; n < len(candidates); n++ {
+		i := ((start+n*dir)%len(candidates) + len(candidates)) % len(candidates)
+		if device := candidates[i]; match(device) {
+			for _, ancestor := range m.topology.Ancestors(device) {
+				delete(m.collapsed, hotplug.Key(ancestor))
+			}
+			m.cursor = hotplug.Key(device)
+			return
+		}
+	}
+	m.setStatus(fmt.Sprintf("No device matches %q", query))
+}
+
+// View draws the browser into a width by height screen.
+func (m *Model) View(width, height int) string {
+	if width < 40 || height < 6 {
+		return "Terminal too small"
+	}
+	m.height = height - 2
+
+	rows := m.rows()
+	index := m.cursorIndex(rows)
+	if len(rows) > 0 {
+		m.cursor, m.index = rows[index].key, index
+	}
+	if index < m.offset {
+		m.offset = index
+	}
+	if index >= m.offset+m.height {
+		m.offset = index - m.height + 1
+	}
+	m.offset = max(0, min(m.offset, len(rows)-m.height))
+
+	leftWidth := width * 11 / 20
+	rightWidth := width - leftWidth - 1
+
+	var details []string
+	if len(rows) > 0 {
+		details = m.details(rows[index])
+	}
+
+	var b strings.Builder
+	b.WriteString(styleInverse + pad(m.title(), width) + styleReset + "\n")
+	now := m.now()
+	for line := 0; line < m.height; line++ {
+		left := pad("", leftWidth)
+		if i := m.offset + line; i < len(rows) {
+			left = m.styleRow(rows[i], i == index, now, leftWidth)
+		}
+		right := ""
+		if line < len(details) {
+			right = truncate(details[line], rightWidth)
+		}
+		if line == 0 && right != "" {
+			right = styleBold + right + styleReset
+		}
+		b.WriteString(left + styleDim + "│" + styleReset + right + "\n")
+	}
+	b.WriteString(m.footer(width))
+	return b.String()
+}
+
+func (m *Model) title() string {
+	title := fmt.Sprintf(" usbtree  %d devices", len(m.topology.All()))
+	if m.filter != "" {
+		title += fmt.Sprintf("  filter: %s", m.filter)
+	}
+	if m.search != "" {
+		title += fmt.Sprintf("  search: %s", m.search)
+	}
+	return title
+}
+
+func (m *Model) footer(width int) string {
+	switch {
+	case m.mode == modeSearch:
+		return truncate("/"+m.input, width)
+	case m.mode == modeFilter:
+		return truncate("filter: "+m.input, width)
+	case m.err != nil:
+		return styleRed + truncate(m.err.Error(), width) + styleReset
+	case m.status != "" && m.now().Before(m.statusTill):
+		return truncate(m.status, width)
+	default:
+		return styleDim + truncate("↑↓ move  ←→ collapse/expand  / search  n/N next/prev  f filter  esc clear  q quit", width) + styleReset
+	}
+}
+
+func (m *Model) styleRow(r row, selected bool, now time.Time, width int) string {
+	marker := ""
+	if r.children {
+		marker = "▾ "
+		if m.collapsed[r.key] {
+			marker = "▸ "
+		}
+	}
+	label := tree.Label(r.device)
+	if r.ghost {
+		label += " (removed)"
+	}
+	text := pad(truncate(r.prefix+marker+label, width), width)
+
+	style := ""
+	switch h, ok := m.highlights[r.key]; {
+	case r.ghost:
+		style = styleRed
+	case ok && now.Before(h.until) && h.action == hotplug.ActionAdd:
+		style = styleGreen
+	case ok && now.Before(h.until):
+		style = styleYellow
+	}
+	if selected {
+		style += styleInverse
+	}
+	if style == "" {
+		return text
+	}
+	return style + text + styleReset
+}
+
+// details lists the lines of the detail pane for a row.
+func (m *Model) details(r row) []string {
+	device := r.device
+	lines := []string{device.GetDisplayName(), ""}
+	field := func(label, value string) {
+		if value != "" {
+			lines = append(lines, fmt.Sprintf(" %-16s %s", label, value))
+		}
+	}
+
+	if r.ghost {
+		field("State", "removed")
+	}
+	field("ID", device.GetIDString())
+	field("Vendor", device.VendorName)
+	field("Product", device.ProductName)
+	field("Class", device.Class)
+	field("Port path", device.PortPath)
+	field("Position", fmt.Sprintf("Bus %d, Port %d, Address %d", device.Bus, device.Port, device.Address))
+	if device.Ports > 0 {
+		field("Ports", fmt.Sprint(device.Ports))
+	}
+	field("Controller", device.ControllerDriver)
+	if d := device.Descriptor; d != nil {
+		field("USB version", d.USBVersion)
+		field("Device version", d.DeviceVersion)
+		field("Device class", fmt.Sprintf("%02x:%02x:%02x", d.Class, d.SubClass, d.Protocol))
+		if d.MaxPacketSize != 0 {
+			field("Max packet size", fmt.Sprint(d.MaxPacketSize))
+		}
+	}
+	for _, detail := range tree.Details(device) {
+		field(detail.Label, detail.Value)
+	}
+
+	if m.devNodes != nil && !r.ghost {
+		for i, node := range m.devNodes(device) {
+			label := ""
+			if i == 0 {
+				label = "Device nodes"
+			}
+			lines = append(lines, fmt.Sprintf(" %-16s %s", label, node))
+		}
+	}
+	return lines
+}
+
+// truncate shortens s to width runes, marking the cut with an ellipsis.
+func truncate(s string, width int) string {
+	runes := []rune(s)
+	if len(runes) <= width {
+		return s
+	}
+	if width <= 0 {
+		return ""
+	}
+	return string(runes[:width-1]) + "…"
+}
+
+// pad fills s with spaces to width runes.
+func pad(s string, width int) string {
+	if n := len([]rune(s)); n < width {
+		return s + strings.Repeat(" ", width-n)
+	}
+	return s
+}
@@ -0,0 +1,189 @@
+package tui
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stegmannb/usbtree/internal/hotplug"
+	"github.com/stegmannb/usbtree/internal/models"
+)
+
+var ansiRe = regexp.MustCompile(`\x1b\[[0-9;?]*[a-zA-Z]`)
+
+func testTopology() *models.Topology {
+	ftdi := &models.USBDevice{VendorID: 0x0403, ProductID: 0x6001, VendorName: "FTDI", ProductName: "FT232R USB UART",
+		Serial: "A50285BI", Bus: 1, Port: 3, Address: 4, PortPath: "1-2.3"}
+	mouse := &models.USBDevice{VendorID: 0x046d, ProductID: 0xc52b, VendorName: "Logitech, Inc.", ProductName: "Unifying Receiver",
+		Bus: 1, Port: 1, Address: 3, PortPath: "1-2.1"}
+	hub := &models.USBDevice{VendorID: 0x05e3, ProductID: 0x0610, ProductName: "USB2.0 Hub", Class: "Hub",
+		Bus: 1, Port: 2, Address: 2, PortPath: "1-2", Children: []*models.USBDevice{mouse, ftdi}}
+	root := &models.USBDevice{VendorID: 0x1d6b, ProductID: 0x0002, ProductName: "xHCI Host Controller", Class: "Hub",
+		Bus: 1, Address: 1, PortPath: "usb1", Children: []*models.USBDevice{hub}}
+	return models.NewTopology([]*models.USBDevice{root})
+}
+
+func newTestModel() *Model {
+	m := NewModel(func(*models.USBDevice) []string { return []string{"/dev/ttyUSB0"} })
+	m.Update(hotplug.Update{Topology: testTopology()})
+	return m
+}
+
+func keys(rows []row) []string {
+	var result []string
+	for _, r := range rows {
+		result = append(result, r.key)
+	}
+	return result
+}
+
+func press(m *Model, keys ...string) {
+	for _, key := range keys {
+		m.HandleKey(key)
+	}
+}
+
+func TestModel_Navigation(t *testing.T) {
+	m := newTestModel()
+	if got := strings.Join(keys(m.rows()), " "); got != "usb1 1-2 1-2.1 1-2.3" {
+		t.Fatalf("Unexpected rows %q", got)
+	}
+
+	press(m, "down", "down", "j")
+	if m.Selected().PortPath != "1-2.3" {
+		t.Errorf("Expected cursor on 1-2.3, got %s", m.Selected().PortPath)
+	}
+	press(m, "down")
+	if m.Selected().PortPath != "1-2.3" {
+		t.Errorf("Expected cursor to stop at the last row, got %s", m.Selected().PortPath)
+	}
+
+	// Left moves to the parent hub, then collapses it
+	press(m, "left", "left")
+	if got := strings.Join(keys(m.rows()), " "); got != "usb1 1-2" {
+		t.Errorf("Expected hub to collapse, got %q", got)
+	}
+	press(m, "right", "right")
+	if m.Selected().PortPath != "1-2.1" {
+		t.Errorf("Expected right to expand and enter the hub, got %s", m.Selected().PortPath)
+	}
+	press(m, "g")
+	if m.Selected().PortPath != "usb1" {
+		t.Errorf("Expected cursor at the top, got %s", m.Selected().PortPath)
+	}
+	if !m.HandleKey("q") {
+		t.Error("Expected q to quit")
+	}
+}
+
+func TestModel_Filter(t *testing.T) {
+	m := newTestModel()
+	press(m, "f", "f", "t", "d", "i", "enter")
+	if got := strings.Join(keys(m.rows()), " "); got != "usb1 1-2 1-2.3" {
+		t.Errorf("Expected the match and its hubs, got %q", got)
+	}
+	press(m, "esc")
+	if len(m.rows()) != 4 {
+		t.Errorf("Expected esc to clear the filter, got %v", keys(m.rows()))
+	}
+
+	press(m, "f", "0", "4", "6", "d", ":", "enter")
+	if got := strings.Join(keys(m.rows()), " "); got != "usb1 1-2 1-2.1" {
+		t.Errorf("Expected vendor selector to filter, got %q", got)
+	}
+}
+
+func TestModel_Search(t *testing.T) {
+	m := newTestModel()
+	press(m, "left")
+	if len(m.rows()) != 1 {
+		t.Fatalf("Expected the root hub to collapse, got %v", keys(m.rows()))
+	}
+
+	// The cursor follows the search as it is typed
+	press(m, "/", "1", "-", "2", ".", "enter")
+	if m.Selected().PortPath != "1-2.1" {
+		t.Errorf("Expected search to expand the hubs and find 1-2.1, got %s", m.Selected().PortPath)
+	}
+	press(m, "n")
+	if m.Selected().PortPath != "1-2.3" {
+		t.Errorf("Expected next match to be 1-2.3, got %s", m.Selected().PortPath)
+	}
+	press(m, "n")
+	if m.Selected().PortPath != "1-2.1" {
+		t.Errorf("Expected search to wrap around to 1-2.1, got %s", m.Selected().PortPath)
+	}
+	press(m, "N")
+	if m.Selected().PortPath != "1-2.3" {
+		t.Errorf("Expected previous match to wrap around to 1-2.3, got %s", m.Selected().PortPath)
+	}
+}
+
+func TestModel_Hotplug(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	m := newTestModel()
+	m.now = func() time.Time { return now }
+
+	before := testTopology()
+	after := testTopology()
+	hub := after.FindByPortPath("1-2")
+	hub.Children = hub.Children[:1]
+	after = models.NewTopology(after.Devices)
+
+	m.Update(hotplug.Update{Topology: after, Events: hotplug.Diff(before, after, now)})
+	rows := m.rows()
+	if got := strings.Join(keys(rows), " "); got != "usb1 1-2 1-2.1 1-2.3" || !rows[3].ghost {
+		t.Fatalf("Expected the removed device to stay as a ghost, got %q", got)
+	}
+	view := m.View(100, 10)
+	if !strings.Contains(view, styleRed) || !strings.Contains(view, "(removed)") || !strings.Contains(view, "detached FT232R USB UART") {
+		t.Errorf("Expected the removal to be shown:\n%s", view)
+	}
+
+	now = now.Add(highlightDuration + time.Second)
+	m.Tick()
+	if len(m.rows()) != 3 {
+		t.Errorf("Expected the ghost to expire, got %v", keys(m.rows()))
+	}
+
+	m.Update(hotplug.Update{Topology: before, Events: hotplug.Diff(after, before, now)})
+	if h, ok := m.highlights["1-2.3"]; !ok || h.action != hotplug.ActionAdd {
+		t.Errorf("Expected the attached device to be highlighted, got %v", m.highlights)
+	}
+}
+
+func TestModel_View(t *testing.T) {
+	m := newTestModel()
+	press(m, "G")
+
+	view := m.View(100, 12)
+	lines := strings.Split(view, "\n")
+	if len(lines) != 12 {
+		t.Fatalf("Expected 12 lines, got %d", len(lines))
+	}
+	for i, line := range lines {
+		if width := len([]rune(ansiRe.ReplaceAllString(line, ""))); width > 100 {
+			t.Errorf("Line %d is %d wide", i, width)
+		}
+	}
+
+	plain := ansiRe.ReplaceAllString(view, "")
+	for _, expected := range []string{"4 devices", "└── ▾ ", "FT232R USB UART", "0403:6001", "A50285BI", "/dev/ttyUSB0", "1-2.3"} {
+		if !strings.Contains(plain, expected) {
+			t.Errorf("Expected view to contain %q:\n%s", expected, plain)
+		}
+	}
+
+	if m.View(10, 3) != "Terminal too small" {
+		t.Error("Expected a tiny terminal to be refused")
+	}
+}
+
+func TestParseKeys(t *testing.T) {
+	got := parseKeys([]byte("j\x1b[A\x1bOB\r\x7f\x03/ü\x1b[5~\x1b[99x\x1b"))
+	expected := "j up down enter backspace ctrl-c / ü pgup esc"
+	if strings.Join(got, " ") != expected {
+		t.Errorf("Expected %q, got %q", expected, strings.Join(got, " "))
+	}
+}
@@ -0,0 +1,105 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/stegmannb/usbtree/internal/hotplug"
+	"github.com/stegmannb/usbtree/internal/models"
+)
+
+// Terminal control sequences.
+const (
+	enterAltScreen = "\x1b[?1049h\x1b[?25l"
+	leaveAltScreen = "\x1b[?25h\x1b[?1049l"
+	cursorHome     = "\x1b[H"
+	clearLine      = "\x1b[K"
+	clearBelow     = "\x1b[J"
+)
+
+// Options configures Run.
+type Options struct {
+	// DevNodes lists the /dev nodes of a device for the detail pane; nil
+	// when they are unknown, for example for recorded input.
+	DevNodes func(*models.USBDevice) []string
+}
+
+// Run shows the browser on the terminal in until the user quits or ctx is
+// done, applying every update received meanwhile.
+func Run(ctx context.Context, in *os.File, out io.Writer, updates <-chan hotplug.Update, opts Options) error {
+	if !isTerminal(in) {
+		return fmt.Errorf("the interactive browser needs a terminal")
+	}
+	restore, err := makeRaw(in)
+	if err != nil {
+		return err
+	}
+	defer restore()
+
+	fmt.Fprint(out, enterAltScreen)
+	defer fmt.Fprint(out, leaveAltScreen)
+
+	resize, stopResize := notifyResize()
+	defer stopResize()
+
+	keys := make(chan []string)
+	readErr := make(chan error, 1)
+	go func() {
+		buf := make([]byte, 256)
+		for {
+			n, err := in.Read(buf)
+			if err != nil {
+				readErr <- err
+				return
+			}
+			select {
+			case keys <- parseKeys(buf[:n]):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	model := NewModel(opts.DevNodes)
+	draw := func() {
+		width, height, err := getSize(in)
+		if err != nil {
+			width, height = 80, 24
+		}
+		lines := strings.Split(model.View(width, height), "\n")
+		fmt.Fprint(out, cursorHome+strings.Join(lines, clearLine+"\r\n")+clearLine+clearBelow)
+	}
+	draw()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-readErr:
+			return fmt.Errorf("failed to read from terminal: %w", err)
+		case pressed := <-keys:
+			for _, key := range pressed {
+				if model.HandleKey(key) {
+					return nil
+				}
+			}
+		case update, ok := <-updates:
+			if !ok {
+				updates = nil
+				continue
+			}
+			model.Update(update)
+		case <-ticker.C:
+			model.Tick()
+		case <-resize:
+		}
+		draw()
+	}
+}
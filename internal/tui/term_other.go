@@ -0,0 +1,25 @@
+//go:build !linux && !darwin
+
+package tui
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+)
+
+func makeRaw(f *os.File) (func(), error) {
+	return nil, fmt.Errorf("the interactive browser is not supported on %s", runtime.GOOS)
+}
+
+func isTerminal(f *os.File) bool {
+	return false
+}
+
+func getSize(f *os.File) (int, int, error) {
+	return 0, 0, fmt.Errorf("the interactive browser is not supported on %s", runtime.GOOS)
+}
+
+func notifyResize() (<-chan os.Signal, func()) {
+	return nil, func() {}
+}
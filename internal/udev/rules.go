@@ -0,0 +1,320 @@
+package udev
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/stegmannb/usbtree/internal/models"
+)
+
+// RuleOptions controls the assignments added to a generated rule.
+type RuleOptions struct {
+	Subsystem          string // "usb" (default) or e.g. "tty" to target the device node of a driver
+	MatchPort          bool   // add a KERNELS== match on the port path
+	Symlink            string
+	Mode               string
+	Group              string
+	Uaccess            bool
+	IgnoreModemManager bool
+}
+
+// Validate rejects option values that would break out of their quotes or
+// line in a rules file.
+func (o RuleOptions) Validate() error {
+	for _, option := range []struct{ name, value string }{
+		{"subsystem", o.Subsystem}, {"symlink", o.Symlink}, {"mode", o.Mode}, {"group", o.Group},
+	} {
+		if strings.ContainsAny(option.value, "\"\r\n") {
+			return fmt.Errorf("invalid %s %q: quotes and line breaks are not allowed", option.name, option.value)
+		}
+	}
+	return nil
+}
+
+// GenerateRule returns a udev rule matching the device by vendor, product
+// and serial number, preceded by a comment naming the device. The output is
+// suitable for a file in /etc/udev/rules.d.
+func GenerateRule(device *models.USBDevice, opts RuleOptions) (string, error) {
+	if err := opts.Validate(); err != nil {
+		return "", err
+	}
+
+	subsystem := opts.Subsystem
+	if subsystem == "" {
+		subsystem = "usb"
+	}
+
+	keys := []string{fmt.Sprintf(`SUBSYSTEM=="%s"`, subsystem)}
+	if subsystem == "usb" {
+		keys = append(keys, `ENV{DEVTYPE}=="usb_device"`)
+	}
+	keys = append(keys,
+		fmt.Sprintf(`ATTRS{idVendor}=="%04x"`, device.VendorID),
+		fmt.Sprintf(`ATTRS{idProduct}=="%04x"`, device.ProductID))
+	if device.Serial != "" {
+		keys = append(keys, fmt.Sprintf(`ATTRS{serial}=="%s"`, escape(escapeGlob(device.Serial))))
+	}
+	if opts.MatchPort && device.PortPath != "" {
+		keys = append(keys, fmt.Sprintf(`KERNELS=="%s"`, device.PortPath))
+	}
+
+	if opts.Symlink != "" {
+		keys = append(keys, fmt.Sprintf(`SYMLINK+="%s"`, opts.Symlink))
+	}
+	if opts.Mode != "" {
+		keys = append(keys, fmt.Sprintf(`MODE="%s"`, opts.Mode))
+	}
+	if opts.Group != "" {
+		keys = append(keys, fmt.Sprintf(`GROUP="%s"`, opts.Group))
+	}
+	if opts.Uaccess {
+		keys = append(keys, `TAG+="uaccess"`)
+	}
+	if opts.IgnoreModemManager {
+		keys = append(keys, `ENV{ID_MM_DEVICE_IGNORE}="1"`)
+	}
+
+	comment := fmt.Sprintf("# %s [%s]", device.GetDisplayName(), device.GetIDString())
+	if device.PortPath != "" {
+		comment += " at " + device.PortPath
+	}
+
+	return comment + "\n" + strings.Join(keys, ", ") + "\n", nil
+}
+
+func escape(value string) string {
+	return strings.ReplaceAll(value, `"`, `\"`)
+}
+
+// globEscaper quotes the characters udev reads as glob patterns or
+// alternatives in match values, so a serial number such as "A*1" or "A|B"
+// matches only itself.
+var globEscaper = strings.NewReplacer(`\`, `\\`, `*`, `\*`, `?`, `\?`, `[`, `\[`, `|`, `\|`)
+
+func escapeGlob(value string) string {
+	return globEscaper.Replace(value)
+}
+
+// Key is a single KEY{attr}=="value" expression of a rule.
+type Key struct {
+	Name     string // e.g. ATTRS
+	Attr     string // e.g. idVendor, empty for keys without an attribute
+	Operator string
+	Value    string
+}
+
+// Rule is one logical line of a rules file.
+type Rule struct {
+	Line int
+	Text string
+	Keys []Key
+}
+
+// ParseRules reads a udev rules file. Comments and blank lines are skipped
+// and lines ending in a backslash are joined with the next one.
+func ParseRules(r io.Reader) ([]*Rule, error) {
+	var rules []*Rule
+	scanner := bufio.NewScanner(r)
+
+	lineNumber := 0
+	var pending strings.Builder
+	startLine := 0
+
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+
+		if pending.Len() == 0 {
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			startLine = lineNumber
+		}
+
+		if strings.HasSuffix(line, "\\") {
+			pending.WriteString(strings.TrimSuffix(line, "\\"))
+			pending.WriteString(" ")
+			continue
+		}
+		pending.WriteString(line)
+
+		rule, err := parseRule(pending.String())
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", startLine, err)
+		}
+		rule.Line = startLine
+		rules = append(rules, rule)
+		pending.Reset()
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return rules, nil
+}
+
+func parseRule(text string) (*Rule, error) {
+	rule := &Rule{Text: text}
+	rest := text
+
+	for {
+		rest = strings.TrimLeft(rest, " \t,")
+		if rest == "" {
+			return rule, nil
+		}
+
+		opIndex := strings.IndexAny(rest, "=!+-:")
+		if opIndex <= 0 {
+			return nil, fmt.Errorf("invalid key in %q", rest)
+		}
+		name := strings.TrimSpace(rest[:opIndex])
+		rest = rest[opIndex:]
+
+		var operator string
+		for _, op := range []string{"==", "!=", "+=", "-=", ":=", "="} {
+			if strings.HasPrefix(rest, op) {
+				operator = op
+				break
+			}
+		}
+		if operator == "" {
+			return nil, fmt.Errorf("missing operator after %s", name)
+		}
+		rest = strings.TrimLeft(rest[len(operator):], " \t")
+
+		if !strings.HasPrefix(rest, `"`) {
+			return nil, fmt.Errorf("value of %s is not quoted", name)
+		}
+		end := closingQuote(rest)
+		if end < 0 {
+			return nil, fmt.Errorf("unterminated value for %s", name)
+		}
+		value := strings.ReplaceAll(rest[1:end], `\"`, `"`)
+		rest = rest[end+1:]
+
+		key := Key{Name: name, Operator: operator, Value: value}
+		if open := strings.Index(name, "{"); open > 0 && strings.HasSuffix(name, "}") {
+			key.Name = name[:open]
+			key.Attr = name[open+1 : len(name)-1]
+		}
+		rule.Keys = append(rule.Keys, key)
+	}
+}
+
+func closingQuote(s string) int {
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			return i
+		}
+	}
+	return -1
+}
+
+// IsUSBRule reports whether the rule matches on any USB device attribute
+// usbtree knows about, i.e. whether checking it against the tree makes sense.
+func (r *Rule) IsUSBRule() bool {
+	for _, key := range r.Keys {
+		if _, ok := deviceValue(key, &models.USBDevice{}); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Matches reports whether every match key the device can be checked against
+// is satisfied. Keys usbtree cannot evaluate, such as ENV, KERNEL or
+// DRIVERS, are ignored.
+func (r *Rule) Matches(device *models.USBDevice) bool {
+	checked := false
+	for _, key := range r.Keys {
+		if key.Operator != "==" && key.Operator != "!=" {
+			continue
+		}
+		value, ok := deviceValue(key, device)
+		if !ok {
+			continue
+		}
+		checked = true
+		if matchPattern(key.Value, value) != (key.Operator == "==") {
+			return false
+		}
+	}
+	return checked
+}
+
+// deviceValue returns the device's value for the attribute a key matches on.
+func deviceValue(key Key, device *models.USBDevice) (string, bool) {
+	switch key.Name {
+	case "ATTR", "ATTRS":
+		switch key.Attr {
+		case "idVendor":
+			return fmt.Sprintf("%04x", device.VendorID), true
+		case "idProduct":
+			return fmt.Sprintf("%04x", device.ProductID), true
+		case "serial":
+			return device.Serial, true
+		case "manufacturer":
+			return device.VendorName, true
+		case "product":
+			return device.ProductName, true
+		}
+	case "KERNELS":
+		// Unlike KERNEL, which names the event's own device such as
+		// ttyUSB0, KERNELS matches the USB device above it by port path
+		return device.PortPath, true
+	}
+	return "", false
+}
+
+// matchPattern implements udev's shell-style globs, including |-separated
+// alternatives.
+func matchPattern(pattern, value string) bool {
+	for _, alternative := range alternatives(pattern) {
+		if matched, err := path.Match(alternative, value); err == nil && matched {
+			return true
+		}
+	}
+	return false
+}
+
+// alternatives splits a pattern at the | characters not escaped with a
+// backslash.
+func alternatives(pattern string) []string {
+	var parts []string
+	start := 0
+	for i := 0; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '\\':
+			i++
+		case '|':
+			parts = append(parts, pattern[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, pattern[start:])
+}
+
+// RuleMatch lists the devices of the tree matched by a rule.
+type RuleMatch struct {
+	Rule    *Rule
+	Devices []*models.USBDevice
+}
+
+// CheckRules evaluates every USB-related rule against all devices of the tree.
+func CheckRules(rules []*Rule, topology *models.Topology) []RuleMatch {
+	var results []RuleMatch
+	for _, rule := range rules {
+		if !rule.IsUSBRule() {
+			continue
+		}
+		results = append(results, RuleMatch{Rule: rule, Devices: topology.Find(rule.Matches)})
+	}
+	return results
+}
@@ -0,0 +1,191 @@
+package udev
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stegmannb/usbtree/internal/models"
+)
+
+func testTree() []*models.USBDevice {
+	root := &models.USBDevice{VendorID: 0x1d6b, ProductID: 0x0002, ProductName: "2.0 root hub", PortPath: "usb1"}
+	ftdi := &models.USBDevice{VendorID: 0x0403, ProductID: 0x6001, ProductName: "FT232R USB UART", Serial: "A50285BI", PortPath: "1-2"}
+	cp2102 := &models.USBDevice{VendorID: 0x10c4, ProductID: 0xea60, ProductName: "CP2102 USB to UART Bridge Controller", Serial: "0001", PortPath: "1-3"}
+	root.AddChild(ftdi)
+	root.AddChild(cp2102)
+	return []*models.USBDevice{root}
+}
+
+func TestGenerateRule(t *testing.T) {
+	device := testTree()[0].Children[0]
+
+	rule, err := GenerateRule(device, RuleOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := "# FT232R USB UART [0403:6001] at 1-2\n" +
+		`SUBSYSTEM=="usb", ENV{DEVTYPE}=="usb_device", ATTRS{idVendor}=="0403", ATTRS{idProduct}=="6001", ATTRS{serial}=="A50285BI"` + "\n"
+	if rule != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, rule)
+	}
+
+	rule, err = GenerateRule(device, RuleOptions{
+		Subsystem:          "tty",
+		MatchPort:          true,
+		Symlink:            "dut3-console",
+		Mode:               "0660",
+		Group:              "dialout",
+		Uaccess:            true,
+		IgnoreModemManager: true,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected = "# FT232R USB UART [0403:6001] at 1-2\n" +
+		`SUBSYSTEM=="tty", ATTRS{idVendor}=="0403", ATTRS{idProduct}=="6001", ATTRS{serial}=="A50285BI", KERNELS=="1-2", ` +
+		`SYMLINK+="dut3-console", MODE="0660", GROUP="dialout", TAG+="uaccess", ENV{ID_MM_DEVICE_IGNORE}="1"` + "\n"
+	if rule != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, rule)
+	}
+
+	for _, opts := range []RuleOptions{
+		{Symlink: "jtag\", GROUP=\"root"},
+		{Mode: "0666\nSUBSYSTEM==\"usb\""},
+		{Group: "plugdev\r"},
+		{Subsystem: `tty"`},
+	} {
+		if _, err := GenerateRule(device, opts); err == nil {
+			t.Errorf("Expected an error for %+v", opts)
+		}
+	}
+}
+
+func TestGenerateRule_RoundTrip(t *testing.T) {
+	devices := testTree()
+	device := devices[0].Children[1]
+
+	rule, err := GenerateRule(device, RuleOptions{MatchPort: true, Symlink: "jtag"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	rules, err := ParseRules(strings.NewReader(rule))
+	if err != nil {
+		t.Fatalf("Failed to parse generated rule: %v", err)
+	}
+	if len(rules) != 1 {
+		t.Fatalf("Expected 1 rule, got %d", len(rules))
+	}
+
+	results := CheckRules(rules, models.NewTopology(devices))
+	if len(results) != 1 || len(results[0].Devices) != 1 || results[0].Devices[0] != device {
+		t.Errorf("Expected generated rule to match exactly its own device, got %+v", results)
+	}
+
+	// Glob characters in the serial number match only themselves
+	tests := map[string]struct{ escaped, twin string }{
+		"A*1?[x]": {`A\*1\?\[x]`, "A11x"},
+		"A|B":     {`A\|B`, "B"},
+	}
+	for serial, tt := range tests {
+		devices := testTree()
+		device := devices[0].Children[1]
+		device.Serial = serial
+		rule, err := GenerateRule(device, RuleOptions{})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !strings.Contains(rule, `ATTRS{serial}=="`+tt.escaped+`"`) {
+			t.Errorf("Expected the serial number %s to be escaped, got %s", serial, rule)
+		}
+		rules, err := ParseRules(strings.NewReader(rule))
+		if err != nil {
+			t.Fatalf("Failed to parse generated rule: %v", err)
+		}
+		twin := &models.USBDevice{VendorID: device.VendorID, ProductID: device.ProductID, Serial: tt.twin, PortPath: "1-4"}
+		devices[0].AddChild(twin)
+		results := CheckRules(rules, models.NewTopology(devices))
+		if len(results) != 1 || len(results[0].Devices) != 1 || results[0].Devices[0] != device {
+			t.Errorf("Expected the rule for %s to match exactly its own device, got %+v", serial, results)
+		}
+	}
+}
+
+func TestParseRules(t *testing.T) {
+	input := `# Lab adapters
+
+SUBSYSTEM=="tty", ATTRS{idVendor}=="0403", \
+    ATTRS{idProduct}=="6001", SYMLINK+="ftdi"
+ACTION=="add", ATTRS{serial}=="with \"quote\"", MODE="0666"
+`
+	rules, err := ParseRules(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(rules) != 2 {
+		t.Fatalf("Expected 2 rules, got %d", len(rules))
+	}
+
+	if rules[0].Line != 3 {
+		t.Errorf("Expected first rule on line 3, got %d", rules[0].Line)
+	}
+	expectedKeys := []Key{
+		{Name: "SUBSYSTEM", Operator: "==", Value: "tty"},
+		{Name: "ATTRS", Attr: "idVendor", Operator: "==", Value: "0403"},
+		{Name: "ATTRS", Attr: "idProduct", Operator: "==", Value: "6001"},
+		{Name: "SYMLINK", Operator: "+=", Value: "ftdi"},
+	}
+	if len(rules[0].Keys) != len(expectedKeys) {
+		t.Fatalf("Expected %d keys, got %+v", len(expectedKeys), rules[0].Keys)
+	}
+	for i, key := range expectedKeys {
+		if rules[0].Keys[i] != key {
+			t.Errorf("Key %d: expected %+v, got %+v", i, key, rules[0].Keys[i])
+		}
+	}
+
+	if rules[1].Line != 5 || rules[1].Keys[1].Value != `with "quote"` {
+		t.Errorf("Unexpected second rule: %+v", rules[1])
+	}
+
+	if _, err := ParseRules(strings.NewReader(`ATTRS{idVendor}=="0403`)); err == nil {
+		t.Error("Expected error for unterminated value")
+	}
+	if _, err := ParseRules(strings.NewReader(`ATTRS{idVendor}==0403`)); err == nil {
+		t.Error("Expected error for unquoted value")
+	}
+}
+
+func TestCheckRules(t *testing.T) {
+	input := `SUBSYSTEM=="tty", ATTRS{idVendor}=="0403|10c4", SYMLINK+="serial%n"
+SUBSYSTEM=="usb", KERNELS=="1-*", ATTRS{serial}!="0001", MODE="0666"
+SUBSYSTEM=="usb", ATTRS{idVendor}=="dead", MODE="0666"
+SUBSYSTEM=="tty", KERNEL=="ttyUSB*", ATTRS{idVendor}=="0403", GROUP="dialout"
+ACTION=="add", RUN+="/bin/true"
+SUBSYSTEM=="tty", KERNEL=="ttyUSB*", MODE="0666"
+`
+	rules, err := ParseRules(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	results := CheckRules(rules, models.NewTopology(testTree()))
+	if len(results) != 4 {
+		t.Fatalf("Expected 4 USB rules to be checked, got %d", len(results))
+	}
+
+	expected := [][]string{
+		{"1-2", "1-3"},
+		{"1-2"},
+		nil,
+		{"1-2"},
+	}
+	for i, result := range results {
+		var ports []string
+		for _, device := range result.Devices {
+			ports = append(ports, device.PortPath)
+		}
+		if strings.Join(ports, ",") != strings.Join(expected[i], ",") {
+			t.Errorf("Rule on line %d: expected %v, got %v", result.Rule.Line, expected[i], ports)
+		}
+	}
+}
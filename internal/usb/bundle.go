@@ -0,0 +1,151 @@
+package usb
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/stegmannb/usbtree/internal/models"
+)
+
+// maxBundleFileSize bounds single files when extracting a bundle; sysfs
+// attributes are tiny and the largest command output is lsusb -v.
+const maxBundleFileSize = 16 << 20
+
+// readBundle replays a capture bundle by unpacking it into a temporary
+// fixture root.
+func readBundle(r io.Reader) ([]*models.USBDevice, error) {
+	dir, err := os.MkdirTemp("", "usbtree-bundle-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	if err := ExtractBundle(r, dir); err != nil {
+		return nil, err
+	}
+
+	return NewFixtureDetector(dir).GetDevices()
+}
+
+// InputSysfs returns the sysfs copy of saved input that has one: a fixture
+// root, or a capture bundle unpacked into a temporary directory that cleanup
+// removes. Other input has no sysfs, and InputSysfs returns nil.
+func InputSysfs(path, format string) (sysfs *Sysfs, cleanup func(), err error) {
+	cleanup = func() {}
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		return NewSysfs(filepath.Join(path, FixtureSysfsDir)), cleanup, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, cleanup, err
+	}
+	if format == "" || format == InputAuto {
+		format = DetectInputFormat(data)
+	}
+	if format != InputBundle {
+		return nil, cleanup, nil
+	}
+
+	dir, err := os.MkdirTemp("", "usbtree-bundle-")
+	if err != nil {
+		return nil, cleanup, err
+	}
+	if err := ExtractBundle(bytes.NewReader(data), dir); err != nil {
+		os.RemoveAll(dir)
+		return nil, cleanup, err
+	}
+	return NewSysfs(filepath.Join(dir, FixtureSysfsDir)), func() { os.RemoveAll(dir) }, nil
+}
+
+// ExtractBundle unpacks a capture bundle (a gzipped tar of a fixture root)
+// into dir. Entries escaping dir or passing through symlinks are rejected,
+// as are symlinks other than the relative driver links of sysfs, which are
+// only ever read, never followed.
+func ExtractBundle(r io.Reader, dir string) error {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return fmt.Errorf("not a capture bundle: %w", err)
+	}
+	defer gz.Close()
+
+	archive := tar.NewReader(gz)
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read bundle: %w", err)
+		}
+
+		name := filepath.Clean(filepath.FromSlash(header.Name))
+		if filepath.IsAbs(name) || name == ".." || strings.HasPrefix(name, ".."+string(filepath.Separator)) {
+			return fmt.Errorf("invalid path %q in bundle", header.Name)
+		}
+		target := filepath.Join(dir, name)
+		if err := checkNoSymlinks(dir, filepath.Dir(name)); err != nil {
+			return err
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if header.Size > maxBundleFileSize {
+				return fmt.Errorf("file %q in bundle is too large", header.Name)
+			}
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			data, err := io.ReadAll(io.LimitReader(archive, maxBundleFileSize))
+			if err != nil {
+				return fmt.Errorf("failed to read %q from bundle: %w", header.Name, err)
+			}
+			if err := os.WriteFile(target, data, 0644); err != nil {
+				return err
+			}
+		case tar.TypeSymlink:
+			if filepath.Base(name) != "driver" || filepath.IsAbs(header.Linkname) {
+				return fmt.Errorf("invalid symlink %q -> %q in bundle", header.Name, header.Linkname)
+			}
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			if err := os.Symlink(header.Linkname, target); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// checkNoSymlinks makes sure no directory between root and root/rel is a
+// symlink, so extracted files cannot end up outside of root.
+func checkNoSymlinks(root, rel string) error {
+	path := root
+	for _, part := range strings.Split(rel, string(filepath.Separator)) {
+		if part == "." || part == "" {
+			continue
+		}
+		path = filepath.Join(path, part)
+		info, err := os.Lstat(path)
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			return fmt.Errorf("bundle entry below symlink %s", path)
+		}
+	}
+	return nil
+}
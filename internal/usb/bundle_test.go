@@ -0,0 +1,126 @@
+package usb
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type bundleEntry struct {
+	name     string
+	data     string
+	linkname string
+}
+
+func makeBundle(t *testing.T, entries []bundleEntry) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	archive := tar.NewWriter(gz)
+
+	for _, e := range entries {
+		header := &tar.Header{Name: e.name, Mode: 0644, Typeflag: tar.TypeReg, Size: int64(len(e.data))}
+		if e.linkname != "" {
+			header = &tar.Header{Name: e.name, Mode: 0777, Typeflag: tar.TypeSymlink, Linkname: e.linkname}
+		}
+		if err := archive.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := archive.Write([]byte(e.data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := archive.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestExtractBundle(t *testing.T) {
+	lsusb, err := os.ReadFile(filepath.Join("testdata", "lsusb.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	bundle := makeBundle(t, []bundleEntry{
+		{name: FixtureLsusb, data: string(lsusb)},
+		{name: FixtureSysfsDir + "/1-2/power/control", data: "on\n"},
+		{name: FixtureSysfsDir + "/1-2/driver", linkname: "../../../../bus/usb/drivers/usb"},
+	})
+
+	if format := DetectInputFormat(bundle); format != InputBundle {
+		t.Errorf("Expected bundle format, got %s", format)
+	}
+
+	dir := t.TempDir()
+	if err := ExtractBundle(bytes.NewReader(bundle), dir); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "sys", "bus", "usb", "devices", "1-2", "power", "control")); err != nil {
+		t.Errorf("Expected sysfs attribute to be extracted: %v", err)
+	}
+
+	devices, err := ParseInput(bytes.NewReader(bundle), InputAuto)
+	if err != nil {
+		t.Fatalf("Failed to replay bundle: %v", err)
+	}
+	if len(devices) == 0 {
+		t.Error("Expected devices from replayed bundle")
+	}
+}
+
+func TestExtractBundle_Unsafe(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []bundleEntry
+	}{
+		{"parent directory", []bundleEntry{{name: "../escape", data: "x"}}},
+		{"nested parent directory", []bundleEntry{{name: "sys/../../escape", data: "x"}}},
+		{"absolute path", []bundleEntry{{name: "/tmp/escape", data: "x"}}},
+		{"through symlink", []bundleEntry{
+			{name: "link", linkname: "/tmp"},
+			{name: "link/escape", data: "x"},
+		}},
+		{"symlink to host file", []bundleEntry{{name: "sys/bus/usb/devices/1-2/serial", linkname: "/etc/shadow"}}},
+		{"symlink out of the root", []bundleEntry{{name: "lsusb.txt", linkname: "../../etc/shadow"}}},
+		{"absolute driver link", []bundleEntry{{name: "sys/bus/usb/devices/1-2/driver", linkname: "/etc"}}},
+		{"through driver link", []bundleEntry{
+			{name: "sys/bus/usb/devices/1-2/driver", linkname: "../../../../bus/usb/drivers/usb"},
+			{name: "sys/bus/usb/devices/1-2/driver/escape", data: "x"},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ExtractBundle(bytes.NewReader(makeBundle(t, tt.entries)), t.TempDir())
+			if err == nil {
+				t.Error("Expected error for unsafe bundle entry")
+			}
+		})
+	}
+}
+
+func TestInputSysfs(t *testing.T) {
+	dir := t.TempDir()
+	sysfs, cleanup, err := InputSysfs(dir, InputAuto)
+	if err != nil || sysfs == nil || sysfs.Root != filepath.Join(dir, FixtureSysfsDir) {
+		t.Errorf("Expected the sysfs copy of a fixture root, got %+v, %v", sysfs, err)
+	}
+	cleanup()
+
+	sysfs, cleanup, err = InputSysfs(filepath.Join("testdata", "lsusb.txt"), InputAuto)
+	if err != nil || sysfs != nil {
+		t.Errorf("Expected no sysfs for lsusb output, got %+v, %v", sysfs, err)
+	}
+	cleanup()
+
+	if _, _, err := InputSysfs(filepath.Join("testdata", "missing.txt"), InputAuto); err == nil {
+		t.Error("Expected error for missing input")
+	}
+}
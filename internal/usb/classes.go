@@ -0,0 +1,57 @@
+package usb
+
+import "strings"
+
+// usbClass describes a USB-IF base class code with the short name usbtree
+// displays and the long name lsusb prints.
+type usbClass struct {
+	code      uint8
+	name      string
+	lsusbName string
+}
+
+var usbClasses = []usbClass{
+	{0x01, "Audio", "Audio"},
+	{0x02, "Communications", "Communications"},
+	{0x03, "HID", "Human Interface Device"},
+	{0x05, "Physical", "Physical Interface Device"},
+	{0x06, "Image", "Imaging"},
+	{0x07, "Printer", "Printer"},
+	{0x08, "Mass Storage", "Mass Storage"},
+	{0x09, "Hub", "Hub"},
+	{0x0a, "CDC Data", "CDC Data"},
+	{0x0b, "Smart Card", "Chip/SmartCard"},
+	{0x0d, "Content Security", "Content Security"},
+	{0x0e, "Video", "Video"},
+	{0x0f, "Healthcare", "Personal Healthcare"},
+	{0x10, "Audio/Video", "Audio/Video"},
+	{0x11, "Billboard", "Billboard"},
+	{0x12, "USB-C Bridge", "Type-C Bridge"},
+	{0xdc, "Diagnostic", "Diagnostic"},
+	{0xe0, "Wireless", "Wireless"},
+	{0xef, "Miscellaneous", "Miscellaneous Device"},
+	{0xfe, "Application Specific", "Application Specific Interface"},
+	{0xff, "Vendor Specific", "Vendor Specific Class"},
+}
+
+// className returns the short class name for a class code, or "Device" for
+// class 0 and unknown codes.
+func className(code uint8) string {
+	for _, class := range usbClasses {
+		if class.code == code {
+			return class.name
+		}
+	}
+	return "Device"
+}
+
+// classCodeByLsusbName maps a class name as printed by lsusb back to its code.
+func classCodeByLsusbName(name string) (uint8, bool) {
+	name = strings.TrimSpace(name)
+	for _, class := range usbClasses {
+		if strings.EqualFold(class.lsusbName, name) {
+			return class.code, true
+		}
+	}
+	return 0, false
+}
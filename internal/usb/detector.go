@@ -1,11 +1,39 @@
 package usb
 
-import "github.com/stegmannb/usbtree/internal/models"
+import (
+	"context"
+
+	"github.com/stegmannb/usbtree/internal/models"
+)
 
 type Detector interface {
 	GetDevices() ([]*models.USBDevice, error)
+	// Backend names the source of the devices, e.g. lsusb.
+	Backend() string
 }
 
 func NewDetector() Detector {
-	return newPlatformDetector()
+	return NewDetectorContext(context.Background())
+}
+
+// NewDetectorContext returns the detector of this platform. The tools it
+// runs, such as lsusb, are killed when ctx is done.
+func NewDetectorContext(ctx context.Context) Detector {
+	return sortedDetector{newPlatformDetector(ctx)}
+}
+
+// sortedDetector orders the devices of a detector by bus and port path, so
+// the command, the API server and the library list them alike whatever the
+// source.
+type sortedDetector struct {
+	Detector
+}
+
+func (d sortedDetector) GetDevices() ([]*models.USBDevice, error) {
+	devices, err := d.Detector.GetDevices()
+	if err != nil {
+		return nil, err
+	}
+	models.SortByPort(devices)
+	return devices, nil
 }
\ No newline at end of file
@@ -0,0 +1,30 @@
+//go:build linux
+
+package usb
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestNewDetectorContext_Cancel(t *testing.T) {
+	// An lsusb that hangs
+	bin := t.TempDir()
+	if err := os.WriteFile(filepath.Join(bin, "lsusb"), []byte("#!/bin/sh\nexec sleep 30\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := NewDetectorContext(ctx).GetDevices(); err == nil {
+		t.Error("Expected an error when lsusb is killed")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected lsusb to be killed when the context is done, took %v", elapsed)
+	}
+}
@@ -17,6 +17,10 @@ func (m *MockDetector) GetDevices() ([]*models.USBDevice, error) {
 	return m.devices, m.err
 }
 
+func (m *MockDetector) Backend() string {
+	return "mock"
+}
+
 func TestNewDetector(t *testing.T) {
 	detector := NewDetector()
 	if detector == nil {
@@ -0,0 +1,67 @@
+package usb
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/stegmannb/usbtree/internal/models"
+)
+
+// Layout of a fixture root as written by usbtree capture: a copy of the USB
+// part of sysfs next to the saved output of the tools the detectors run.
+const (
+	FixtureSysfsDir       = "sys/bus/usb/devices"
+	FixtureLsusb          = "commands/lsusb.txt"
+	FixtureLsusbTree      = "commands/lsusb-t.txt"
+	FixtureLsusbVerbose   = "commands/lsusb-v.txt"
+	FixtureSystemProfiler = "commands/system_profiler.json"
+)
+
+type fixtureDetector struct {
+	root string
+}
+
+// NewFixtureDetector returns a Detector replaying a fixture root, so a
+// captured machine can be rendered exactly as the detectors saw it.
+func NewFixtureDetector(root string) Detector {
+	return &fixtureDetector{root: root}
+}
+
+func (d *fixtureDetector) Backend() string {
+	return "fixture"
+}
+
+func (d *fixtureDetector) GetDevices() ([]*models.USBDevice, error) {
+	if data, err := os.ReadFile(filepath.Join(d.root, FixtureSystemProfiler)); err == nil {
+		return parseSystemProfiler(bytes.NewReader(data))
+	}
+
+	sysfs := NewSysfs(filepath.Join(d.root, FixtureSysfsDir))
+	if _, err := os.Stat(filepath.Join(d.root, FixtureLsusb)); err == nil {
+		return newLsusbDetector(d.readCommand, sysfs).GetDevices()
+	}
+
+	// Captured on a system without lsusb
+	if _, err := os.Stat(sysfs.Root); err == nil {
+		return NewSysfsDetector(sysfs).GetDevices()
+	}
+	return nil, fmt.Errorf("fixture %s contains no lsusb output, sysfs copy or system_profiler output", d.root)
+}
+
+// readCommand stands in for running lsusb with the given arguments.
+func (d *fixtureDetector) readCommand(args ...string) ([]byte, error) {
+	file := FixtureLsusb
+	if len(args) > 0 {
+		switch args[0] {
+		case "-t":
+			file = FixtureLsusbTree
+		case "-v":
+			file = FixtureLsusbVerbose
+		default:
+			return nil, fmt.Errorf("no captured output for lsusb %s", args[0])
+		}
+	}
+	return os.ReadFile(filepath.Join(d.root, file))
+}
@@ -0,0 +1,151 @@
+package usb
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+
+	"github.com/stegmannb/usbtree/internal/models"
+)
+
+// Input formats accepted by ParseInput.
+const (
+	InputAuto           = "auto"
+	InputJSON           = "json"
+	InputLsusb          = "lsusb"
+	InputLsusbTree      = "lsusb-tree"
+	InputLsusbVerbose   = "lsusb-v"
+	InputSystemProfiler = "system-profiler"
+	InputBundle         = "bundle"
+)
+
+// InputFormats lists the formats that can be given explicitly.
+var InputFormats = []string{InputAuto, InputJSON, InputLsusb, InputLsusbTree, InputLsusbVerbose, InputSystemProfiler, InputBundle}
+
+var verboseDescriptorRe = regexp.MustCompile(`(?m)^Device Descriptor:`)
+
+// DetectInputFormat guesses the format of saved tool output from its content.
+func DetectInputFormat(data []byte) string {
+	trimmed := bytes.TrimSpace(data)
+	switch {
+	case bytes.HasPrefix(data, []byte{0x1f, 0x8b}):
+		return InputBundle
+	case bytes.HasPrefix(trimmed, []byte("{")) && bytes.Contains(trimmed, []byte("SPUSBDataType")):
+		return InputSystemProfiler
+	case bytes.HasPrefix(trimmed, []byte("[")), bytes.HasPrefix(trimmed, []byte("{")):
+		return InputJSON
+	case bytes.HasPrefix(trimmed, []byte("/:")):
+		return InputLsusbTree
+	case verboseDescriptorRe.Match(trimmed):
+		return InputLsusbVerbose
+	default:
+		return InputLsusb
+	}
+}
+
+// ParseInput builds the device tree from saved output of usbtree --json,
+// lsusb, lsusb -t, lsusb -v or system_profiler SPUSBDataType -json, or
+// from a bundle written by usbtree capture.
+func ParseInput(r io.Reader, format string) ([]*models.USBDevice, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read input: %w", err)
+	}
+
+	if format == "" || format == InputAuto {
+		format = DetectInputFormat(data)
+	}
+
+	switch format {
+	case InputJSON:
+		return parseJSON(data)
+	case InputLsusb:
+		devices, err := parseLsusbOutput(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		return mergeHierarchy(devices, nil), nil
+	case InputLsusbTree:
+		hierarchy, err := parseLsusbTree(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		return mergeHierarchy(devicesFromTree(hierarchy), hierarchy), nil
+	case InputLsusbVerbose:
+		devices, err := parseLsusbVerbose(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		return mergeHierarchy(devices, nil), nil
+	case InputSystemProfiler:
+		return parseSystemProfiler(bytes.NewReader(data))
+	case InputBundle:
+		return readBundle(bytes.NewReader(data))
+	default:
+		return nil, fmt.Errorf("unknown input format %q", format)
+	}
+}
+
+// parseJSON reads a nested or flat versioned report, or the bare device
+// array written by earlier versions.
+func parseJSON(data []byte) ([]*models.USBDevice, error) {
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("[")) {
+		var devices []*models.USBDevice
+		if err := json.Unmarshal(data, &devices); err != nil {
+			return nil, fmt.Errorf("failed to parse JSON input: %w", err)
+		}
+		return devices, nil
+	}
+
+	// Nested reports decode as flat ones without IDs
+	var report models.FlatReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON input: %w", err)
+	}
+	if report.SchemaVersion > models.SchemaVersion {
+		return nil, fmt.Errorf("JSON input has schema version %d, this version of usbtree reads up to %d", report.SchemaVersion, models.SchemaVersion)
+	}
+
+	var devices []*models.USBDevice
+	for _, device := range report.Devices {
+		if device.ID != 0 {
+			return models.Unflatten(report.Devices)
+		}
+		if device.USBDevice != nil {
+			devices = append(devices, device.USBDevice)
+		}
+	}
+	return devices, nil
+}
+
+type fileDetector struct {
+	path   string
+	format string
+}
+
+// NewFileDetector returns a Detector that reads devices from saved output
+// instead of the local machine. A directory is replayed as a fixture root.
+func NewFileDetector(path, format string) Detector {
+	return sortedDetector{&fileDetector{path: path, format: format}}
+}
+
+func (d *fileDetector) Backend() string {
+	return "file"
+}
+
+func (d *fileDetector) GetDevices() ([]*models.USBDevice, error) {
+	if info, err := os.Stat(d.path); err == nil && info.IsDir() {
+		return NewFixtureDetector(d.path).GetDevices()
+	}
+
+	file, err := os.Open(d.path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return ParseInput(file, d.format)
+}
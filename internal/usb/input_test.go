@@ -0,0 +1,255 @@
+package usb
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stegmannb/usbtree/internal/models"
+)
+
+func readTestdata(t *testing.T, name string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatalf("Failed to read testdata %s: %v", name, err)
+	}
+	return string(data)
+}
+
+// findByAddress returns the device with the given bus and address anywhere
+// in the tree.
+func findByAddress(devices []*models.USBDevice, bus, address int) *models.USBDevice {
+	for _, device := range devices {
+		if device.Bus == bus && device.Address == address {
+			return device
+		}
+		if found := findByAddress(device.Children, bus, address); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+func TestDetectInputFormat(t *testing.T) {
+	tests := []struct {
+		file     string
+		expected string
+	}{
+		{file: "lsusb.txt", expected: InputLsusb},
+		{file: "lsusb-t.txt", expected: InputLsusbTree},
+		{file: "lsusb-v.txt", expected: InputLsusbVerbose},
+		{file: "system_profiler.json", expected: InputSystemProfiler},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			if format := DetectInputFormat([]byte(readTestdata(t, tt.file))); format != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, format)
+			}
+		})
+	}
+
+	if format := DetectInputFormat([]byte(`[{"vendor_id": 1133}]`)); format != InputJSON {
+		t.Errorf("Expected %s for usbtree JSON, got %s", InputJSON, format)
+	}
+}
+
+func TestParseInput_Lsusb(t *testing.T) {
+	devices, err := ParseInput(strings.NewReader(readTestdata(t, "lsusb.txt")), InputLsusb)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(devices) != 2 {
+		t.Fatalf("Expected 2 root hubs, got %d", len(devices))
+	}
+
+	root := findByAddress(devices, 1, 1)
+	if root == nil || len(root.Children) != 3 {
+		t.Fatalf("Expected all bus 1 devices below its root hub, got %+v", root)
+	}
+
+	receiver := findByAddress(devices, 1, 3)
+	if receiver.VendorID != 0x046d || receiver.ProductID != 0xc52b {
+		t.Errorf("Unexpected receiver IDs: %s", receiver.GetIDString())
+	}
+}
+
+func TestParseInput_LsusbTree(t *testing.T) {
+	devices, err := ParseInput(strings.NewReader(readTestdata(t, "lsusb-t.txt")), InputLsusbTree)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(devices) != 2 {
+		t.Fatalf("Expected 2 root hubs, got %d", len(devices))
+	}
+
+	hub := findByAddress(devices, 1, 2)
+	if hub == nil || hub.PortPath != "1-2" || hub.Class != "Hub" || len(hub.Children) != 2 {
+		t.Fatalf("Unexpected hub: %+v", hub)
+	}
+
+	receiver := findByAddress(devices, 1, 3)
+	if receiver.PortPath != "1-2.1" || receiver.Speed != "Full (12 Mbps)" || receiver.Class != "HID" {
+		t.Errorf("Unexpected receiver: %+v", receiver)
+	}
+	if len(receiver.Interfaces) != 3 || receiver.Interfaces[2].Number != 2 || receiver.Interfaces[0].Driver != "usbhid" {
+		t.Errorf("Expected 3 usbhid interfaces, got %+v", receiver.Interfaces)
+	}
+
+	ftdi := findByAddress(devices, 1, 4)
+	if ftdi.PortPath != "1-2.3" || len(ftdi.Interfaces) != 1 || ftdi.Interfaces[0].Class != 0xff {
+		t.Errorf("Unexpected serial adapter: %+v", ftdi)
+	}
+}
+
+func TestParseInput_LsusbVerbose(t *testing.T) {
+	devices, err := ParseInput(strings.NewReader(readTestdata(t, "lsusb-v.txt")), InputLsusbVerbose)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(devices) != 1 || len(devices[0].Children) != 2 {
+		t.Fatalf("Expected the root hub with 2 children, got %d roots", len(devices))
+	}
+
+	root := devices[0]
+	if root.Class != "Hub" || root.Speed != "High (480 Mbps)" || root.ProductName != "2.0 root hub" {
+		t.Errorf("Unexpected root hub: %+v", root)
+	}
+
+	ftdi := findByAddress(devices, 1, 4)
+	if ftdi.VendorName != "Future Technology Devices International, Ltd" || ftdi.ProductName != "FT232 Serial (UART) IC" {
+		t.Errorf("Unexpected names: %q / %q", ftdi.VendorName, ftdi.ProductName)
+	}
+	if ftdi.Serial != "A50285BI" || ftdi.MaxPower != "90mA" || ftdi.Class != "Vendor Specific" {
+		t.Errorf("Unexpected serial adapter: %+v", ftdi)
+	}
+
+	receiver := findByAddress(devices, 1, 3)
+	if receiver.Serial != "" || receiver.Class != "HID" {
+		t.Errorf("Unexpected receiver: %+v", receiver)
+	}
+	expected := []models.Interface{
+		{Number: 0, Class: 0x03, SubClass: 0x01, Protocol: 0x01},
+		{Number: 1, Class: 0x03, SubClass: 0x01, Protocol: 0x02},
+		{Number: 2, Class: 0x03},
+	}
+	if len(receiver.Interfaces) != len(expected) {
+		t.Fatalf("Expected %d interfaces, got %+v", len(expected), receiver.Interfaces)
+	}
+	for i := range expected {
+		if receiver.Interfaces[i] != expected[i] {
+			t.Errorf("Interface %d: expected %+v, got %+v", i, expected[i], receiver.Interfaces[i])
+		}
+	}
+}
+
+func TestParseInput_SystemProfiler(t *testing.T) {
+	devices, err := ParseInput(strings.NewReader(readTestdata(t, "system_profiler.json")), InputAuto)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(devices) != 1 {
+		t.Fatalf("Expected 1 controller, got %d", len(devices))
+	}
+
+	root := devices[0]
+	if root.ProductName != "USB 3.1 Root Hub" || len(root.Children) != 2 {
+		t.Fatalf("Unexpected root hub: %+v", root)
+	}
+
+	hub := root.Children[0]
+	if hub.VendorID != 0x05e3 || hub.ProductID != 0x0610 || hub.Class != "Hub" || len(hub.Children) != 1 {
+		t.Errorf("Unexpected hub: %+v", hub)
+	}
+
+	ftdi := root.Children[1]
+	if ftdi.GetIDString() != "0403:6001" || ftdi.Serial != "A50285BI" || ftdi.Speed != "Full (12 Mbps)" || ftdi.LocationID != "0x01200000" {
+		t.Errorf("Unexpected serial adapter: %+v", ftdi)
+	}
+}
+
+func TestParseInput_JSON(t *testing.T) {
+	input := `[{"vendor_id": 7531, "product_id": 2, "product_name": "Root Hub", "bus": 1, "address": 1,
+		"children": [{"vendor_id": 1027, "product_id": 24577, "port_path": "1-2", "serial": "A50285BI"}]}]`
+
+	devices, err := ParseInput(strings.NewReader(input), InputAuto)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(devices) != 1 || len(devices[0].Children) != 1 {
+		t.Fatalf("Expected 1 root with 1 child, got %+v", devices)
+	}
+	if child := devices[0].Children[0]; child.GetIDString() != "0403:6001" || child.PortPath != "1-2" {
+		t.Errorf("Unexpected child: %+v", child)
+	}
+
+	report := `{"schema_version": 1, "devices": [{"vendor_id": 7531, "product_id": 2,
+		"children": [{"vendor_id": 1027, "product_id": 24577, "port_path": "1-2"}]}]}`
+	devices, err = ParseInput(strings.NewReader(report), InputAuto)
+	if err != nil {
+		t.Fatalf("Unexpected error for report: %v", err)
+	}
+	if len(devices) != 1 || devices[0].Children[0].PortPath != "1-2" {
+		t.Errorf("Unexpected devices from report: %+v", devices)
+	}
+	flat := `{"schema_version": 1, "devices": [{"id": 1, "parent_id": null, "vendor_id": 7531, "product_id": 2},
+		{"id": 2, "parent_id": 1, "vendor_id": 1027, "product_id": 24577, "port_path": "1-2"}]}`
+	devices, err = ParseInput(strings.NewReader(flat), InputAuto)
+	if err != nil {
+		t.Fatalf("Unexpected error for flat report: %v", err)
+	}
+	if len(devices) != 1 || len(devices[0].Children) != 1 || devices[0].Children[0].PortPath != "1-2" {
+		t.Errorf("Unexpected devices from flat report: %+v", devices)
+	}
+
+	if _, err := ParseInput(strings.NewReader(`{"schema_version": 99, "devices": []}`), InputJSON); err == nil {
+		t.Error("Expected error for newer schema version")
+	}
+
+	if _, err := ParseInput(strings.NewReader("[{"), InputJSON); err == nil {
+		t.Error("Expected error for malformed JSON")
+	}
+	if _, err := ParseInput(strings.NewReader(""), "usbview"); err == nil {
+		t.Error("Expected error for unknown format")
+	}
+}
+
+func TestNewFileDetector(t *testing.T) {
+	detector := NewFileDetector(filepath.Join("testdata", "lsusb-t.txt"), InputAuto)
+
+	devices, err := detector.GetDevices()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(devices) != 2 {
+		t.Errorf("Expected 2 root hubs, got %d", len(devices))
+	}
+
+	if _, err := NewFileDetector(filepath.Join("testdata", "missing.txt"), InputAuto).GetDevices(); err == nil {
+		t.Error("Expected error for missing file")
+	}
+}
+
+func TestNewFileDetector_Sorted(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "devices.json")
+	input := `[{"bus": 2, "port_path": "usb2"}, {"bus": 1, "port_path": "usb1",
+		"children": [{"bus": 1, "port_path": "1-10"}, {"bus": 1, "port_path": "1-9"}]}]`
+	if err := os.WriteFile(path, []byte(input), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	devices, err := NewFileDetector(path, InputJSON).GetDevices()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if devices[0].PortPath != "usb1" || devices[0].Children[0].PortPath != "1-9" {
+		t.Errorf("Expected devices by bus and port path, got %s first", devices[0].PortPath)
+	}
+}
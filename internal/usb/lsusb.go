@@ -0,0 +1,487 @@
+package usb
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/stegmannb/usbtree/internal/models"
+)
+
+// lsusbDetector builds the tree from lsusb and lsusb -t output and fills in
+// what only sysfs knows. Running lsusb is left to a function so captured
+// output can be replayed.
+type lsusbDetector struct {
+	run   func(args ...string) ([]byte, error)
+	sysfs *Sysfs
+}
+
+func newLsusbDetector(run func(args ...string) ([]byte, error), sysfs *Sysfs) *lsusbDetector {
+	return &lsusbDetector{run: run, sysfs: sysfs}
+}
+
+func (d *lsusbDetector) Backend() string {
+	return "lsusb"
+}
+
+func (d *lsusbDetector) GetDevices() ([]*models.USBDevice, error) {
+	// First get basic device info from lsusb
+	output, err := d.run()
+	if err != nil {
+		return nil, fmt.Errorf("failed to run lsusb: %w", err)
+	}
+	devices, err := parseLsusbOutput(bytes.NewReader(output))
+	if err != nil {
+		return nil, err
+	}
+
+	// Then get hierarchy from lsusb -t
+	output, err = d.run("-t")
+	if err != nil {
+		// If tree parsing fails, return flat list
+		return devices, nil
+	}
+	hierarchy, err := parseLsusbTree(bytes.NewReader(output))
+	if err != nil {
+		return devices, nil
+	}
+
+	// Merge hierarchy info into devices
+	devices = mergeHierarchy(devices, hierarchy)
+
+	// Fill in power management state and interfaces from sysfs where available
+	d.sysfs.enrichDevices(devices)
+
+	return devices, nil
+}
+
+func parseLsusbOutput(r io.Reader) ([]*models.USBDevice, error) {
+	output, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read lsusb output: %w", err)
+	}
+
+	deviceMap := make(map[string]*models.USBDevice)
+
+	// Parse lsusb output
+	// Format: Bus XXX Device YYY: ID VVVV:PPPP Manufacturer Product
+	re := regexp.MustCompile(`Bus (\d{3}) Device (\d{3}): ID ([0-9a-f]{4}):([0-9a-f]{4})\s*(.*)$`)
+	lines := strings.Split(string(output), "\n")
+
+	for _, line := range lines {
+		if line == "" {
+			continue
+		}
+
+		matches := re.FindStringSubmatch(line)
+		if len(matches) < 6 {
+			continue
+		}
+
+		bus, _ := strconv.Atoi(matches[1])
+		address, _ := strconv.Atoi(matches[2])
+		vendorID, _ := strconv.ParseUint(matches[3], 16, 16)
+		productID, _ := strconv.ParseUint(matches[4], 16, 16)
+		description := strings.TrimSpace(matches[5])
+
+		// Parse manufacturer and product from description
+		var vendorName, productName string
+		if description != "" {
+			// Handle special cases where vendor name contains spaces
+			if strings.HasPrefix(description, "Linux Foundation") {
+				vendorName = "Linux Foundation"
+				productName = strings.TrimPrefix(description, "Linux Foundation ")
+			} else if strings.HasPrefix(description, "VIA Labs, Inc.") {
+				vendorName = "VIA Labs, Inc."
+				productName = strings.TrimPrefix(description, "VIA Labs, Inc. ")
+			} else if strings.HasPrefix(description, "Terminus Technology Inc.") {
+				vendorName = "Terminus Technology Inc."
+				productName = strings.TrimPrefix(description, "Terminus Technology Inc. ")
+			} else if strings.HasPrefix(description, "Anker Innovations Limited.") {
+				vendorName = "Anker Innovations Limited."
+				productName = strings.TrimPrefix(description, "Anker Innovations Limited. ")
+			} else if strings.HasPrefix(description, "Valve Software") {
+				vendorName = "Valve Software"
+				productName = strings.TrimPrefix(description, "Valve Software ")
+			} else if strings.HasPrefix(description, "ASIX Electronics Corp.") {
+				vendorName = "ASIX Electronics Corp."
+				productName = strings.TrimPrefix(description, "ASIX Electronics Corp. ")
+			} else if strings.HasPrefix(description, "Intel Corp.") {
+				vendorName = "Intel Corp."
+				productName = strings.TrimPrefix(description, "Intel Corp. ")
+			} else if strings.HasPrefix(description, "Micro Star International") {
+				vendorName = "Micro Star International"
+				productName = strings.TrimPrefix(description, "Micro Star International ")
+			} else if strings.HasPrefix(description, "Genesys Logic, Inc.") {
+				vendorName = "Genesys Logic, Inc."
+				productName = strings.TrimPrefix(description, "Genesys Logic, Inc. ")
+			} else if strings.HasPrefix(description, "SteelSeries ApS") {
+				vendorName = "SteelSeries ApS"
+				productName = strings.TrimPrefix(description, "SteelSeries ApS ")
+			} else if strings.HasPrefix(description, "KYE Systems Corp.") {
+				vendorName = "KYE Systems Corp."
+				productName = strings.TrimPrefix(description, "KYE Systems Corp. ")
+			} else {
+				parts := strings.SplitN(description, " ", 2)
+				if len(parts) > 0 {
+					vendorName = parts[0]
+				}
+				if len(parts) > 1 {
+					productName = parts[1]
+				}
+			}
+		}
+
+		usbDevice := &models.USBDevice{
+			VendorID:    uint16(vendorID),
+			ProductID:   uint16(productID),
+			Bus:         bus,
+			Address:     address,
+			Port:        0, // Will be filled from tree
+			VendorName:  vendorName,
+			ProductName: productName,
+			Speed:       "Unknown",
+		}
+
+		// Determine class based on known patterns
+		productLower := strings.ToLower(productName)
+		if strings.Contains(productLower, "hub") {
+			usbDevice.Class = "Hub"
+		} else if strings.Contains(productLower, "keyboard") || strings.Contains(productLower, "mouse") {
+			usbDevice.Class = "HID"
+		} else if strings.Contains(productLower, "camera") {
+			usbDevice.Class = "Video"
+		} else if strings.Contains(productLower, "audio") || strings.Contains(productLower, "headset") || strings.Contains(productLower, "arctis") {
+			usbDevice.Class = "Audio"
+		} else if strings.Contains(productLower, "ethernet") || strings.Contains(productLower, "ax88179") {
+			usbDevice.Class = "Communications"
+		} else if strings.Contains(productLower, "bluetooth") || strings.Contains(productLower, "ax200") {
+			usbDevice.Class = "Wireless"
+		} else if strings.Contains(productLower, "controller") {
+			usbDevice.Class = "HID"
+		} else if strings.Contains(productLower, "jtag") || strings.Contains(productLower, "serial") {
+			usbDevice.Class = "Communications"
+		} else {
+			usbDevice.Class = "Device"
+		}
+
+		// Set speed for root hubs
+		if vendorID == 0x1d6b {
+			setRootHubSpeed(usbDevice)
+		}
+
+		deviceKey := fmt.Sprintf("%d-%d", bus, address)
+		deviceMap[deviceKey] = usbDevice
+	}
+
+	// Convert map to slice, ordered by bus and address
+	var result []*models.USBDevice
+	for _, device := range deviceMap {
+		result = append(result, device)
+	}
+	slices.SortFunc(result, models.ComparePorts)
+
+	return result, nil
+}
+
+// setRootHubSpeed derives the speed of a Linux Foundation root hub from its
+// product ID.
+func setRootHubSpeed(device *models.USBDevice) {
+	if device.ProductID == 0x0002 {
+		device.Speed = "High (480 Mbps)"
+	} else if device.ProductID == 0x0003 {
+		device.Speed = "Super (5 Gbps)"
+	}
+}
+
+type treeNode struct {
+	bus      int
+	port     int
+	dev      int
+	speed    string
+	parent   *treeNode
+	children []*treeNode
+
+	// One entry per interface line of the device
+	interfaces []models.Interface
+
+	// From the Driver= field: the host controller driver of root hubs and
+	// the port count of hubs (hub/4p)
+	driver string
+	ports  int
+}
+
+// portPath returns the kernel's name for the device: usbN for root hubs,
+// N-P for devices on a root port and N-P.P... further down the tree.
+func (n *treeNode) portPath() string {
+	if n.parent == nil {
+		return fmt.Sprintf("usb%d", n.bus)
+	}
+	if n.parent.parent == nil {
+		return fmt.Sprintf("%d-%d", n.bus, n.port)
+	}
+	return fmt.Sprintf("%s.%d", n.parent.portPath(), n.port)
+}
+
+func parseLsusbTree(r io.Reader) (map[string]*treeNode, error) {
+	output, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read lsusb -t output: %w", err)
+	}
+
+	nodes := make(map[string]*treeNode)
+	var currentBusRoot *treeNode
+	var parentStack []*treeNode
+
+	lines := strings.Split(string(output), "\n")
+	for _, line := range lines {
+		if line == "" {
+			continue
+		}
+
+		// Count indentation level
+		indent := 0
+		for i := 0; i < len(line); i++ {
+			if line[i] == ' ' {
+				indent++
+			} else {
+				break
+			}
+		}
+		level := indent / 4
+
+		// Parse root hub line
+		if strings.HasPrefix(strings.TrimSpace(line), "/:") {
+			// Format: /:  Bus 001.Port 001: Dev 001, Class=root_hub, Driver=xhci_hcd/6p, 480M
+			busRe := regexp.MustCompile(`Bus (\d+)\.Port (\d+): Dev (\d+).*?([\d.]+M)?$`)
+			matches := busRe.FindStringSubmatch(line)
+			if len(matches) >= 4 {
+				bus, _ := strconv.Atoi(matches[1])
+				port, _ := strconv.Atoi(matches[2])
+				dev, _ := strconv.Atoi(matches[3])
+				speed := ""
+				if len(matches) > 4 {
+					speed = matches[4]
+				}
+
+				node := &treeNode{
+					bus:   bus,
+					port:  port,
+					dev:   dev,
+					speed: speed,
+				}
+				node.driver, node.ports = parseTreeDriver(line)
+				currentBusRoot = node
+				parentStack = []*treeNode{node}
+				nodeKey := fmt.Sprintf("%d-%d", bus, dev)
+				nodes[nodeKey] = node
+			}
+		} else if strings.Contains(line, "Port") {
+			// Format: |__ Port 004: Dev 003, If 0, Class=Wireless, Driver=btusb, 12M
+			portRe := regexp.MustCompile(`Port (\d+): Dev (\d+).*?([\d.]+M)?$`)
+			matches := portRe.FindStringSubmatch(line)
+			if len(matches) >= 3 && currentBusRoot != nil {
+				port, _ := strconv.Atoi(matches[1])
+				dev, _ := strconv.Atoi(matches[2])
+				speed := ""
+				if len(matches) > 3 {
+					speed = matches[3]
+				}
+
+				// Adjust parent stack based on indentation
+				for len(parentStack) > level {
+					parentStack = parentStack[:len(parentStack)-1]
+				}
+
+				var parent *treeNode
+				if len(parentStack) > 0 {
+					parent = parentStack[len(parentStack)-1]
+				}
+
+				node := &treeNode{
+					bus:    currentBusRoot.bus,
+					port:   port,
+					dev:    dev,
+					speed:  speed,
+					parent: parent,
+				}
+
+				if parent != nil {
+					parent.children = append(parent.children, node)
+				}
+
+				nodeKey := fmt.Sprintf("%d-%d", currentBusRoot.bus, dev)
+				if _, exists := nodes[nodeKey]; !exists {
+					nodes[nodeKey] = node
+					if level >= len(parentStack) {
+						parentStack = append(parentStack, node)
+					} else {
+						parentStack[level] = node
+					}
+				}
+
+				if iface, ok := parseTreeInterface(line); ok {
+					nodes[nodeKey].interfaces = append(nodes[nodeKey].interfaces, iface)
+				}
+				if _, ports := parseTreeDriver(line); ports > 0 {
+					nodes[nodeKey].ports = ports
+				}
+			}
+		}
+	}
+
+	return nodes, nil
+}
+
+var (
+	treeInterfaceRe = regexp.MustCompile(`If (\d+), Class=([^,]*), Driver=([^,]*)`)
+	treeDriverRe    = regexp.MustCompile(`Driver=([^,/]*)(?:/(\d+)p)?`)
+)
+
+// parseTreeDriver returns the driver of an lsusb -t line and the port count
+// lsusb appends to the drivers of hubs.
+func parseTreeDriver(line string) (string, int) {
+	matches := treeDriverRe.FindStringSubmatch(line)
+	if matches == nil {
+		return "", 0
+	}
+	ports, _ := strconv.Atoi(matches[2])
+	return strings.TrimSpace(matches[1]), ports
+}
+
+// parseTreeInterface extracts the interface described by an lsusb -t line.
+// Only the class is known from the tree, not subclass and protocol.
+func parseTreeInterface(line string) (models.Interface, bool) {
+	matches := treeInterfaceRe.FindStringSubmatch(line)
+	if matches == nil {
+		return models.Interface{}, false
+	}
+
+	number, _ := strconv.Atoi(matches[1])
+	class, _ := classCodeByLsusbName(matches[2])
+	// Hub drivers carry their port count: hub/4p
+	driver, _, _ := strings.Cut(strings.TrimSpace(matches[3]), "/")
+	if driver == "[none]" {
+		driver = ""
+	}
+
+	return models.Interface{Number: number, Class: class, Driver: driver}, true
+}
+
+// devicesFromTree creates devices from lsusb -t output alone, which only
+// knows the topology and the interfaces but no IDs or names.
+func devicesFromTree(hierarchy map[string]*treeNode) []*models.USBDevice {
+	var devices []*models.USBDevice
+	for _, node := range hierarchy {
+		device := &models.USBDevice{
+			Bus:     node.bus,
+			Address: node.dev,
+			Speed:   "Unknown",
+			Class:   "Device",
+		}
+		if node.parent == nil {
+			device.ProductName = "Root Hub"
+			device.Class = "Hub"
+		} else if len(node.interfaces) > 0 {
+			device.Class = className(node.interfaces[0].Class)
+		}
+		devices = append(devices, device)
+	}
+	slices.SortFunc(devices, models.ComparePorts)
+	return devices
+}
+
+func mergeHierarchy(devices []*models.USBDevice, hierarchy map[string]*treeNode) []*models.USBDevice {
+	deviceMap := make(map[string]*models.USBDevice)
+	rootDevices := make(map[string]*models.USBDevice)
+
+	// Create a map of devices by bus-address
+	for _, device := range devices {
+		key := fmt.Sprintf("%d-%d", device.Bus, device.Address)
+		deviceMap[key] = device
+
+		// Update port and speed from hierarchy if available
+		if node, exists := hierarchy[key]; exists {
+			device.Port = node.port
+			device.PortPath = node.portPath()
+			if node.speed != "" {
+				device.Speed = convertSpeed(node.speed)
+			}
+			if len(device.Interfaces) == 0 {
+				device.Interfaces = node.interfaces
+			}
+			if node.ports > 0 {
+				device.Ports = node.ports
+			}
+			if node.parent == nil && node.driver != "" {
+				device.ControllerDriver = node.driver
+			}
+		}
+
+		// Identify root hubs
+		if device.Address == 1 {
+			rootKey := fmt.Sprintf("bus-%d", device.Bus)
+			rootDevices[rootKey] = device
+		}
+	}
+
+	// Build device hierarchy based on tree structure
+	for key, node := range hierarchy {
+		device, exists := deviceMap[key]
+		if !exists {
+			continue
+		}
+
+		if node.parent != nil {
+			parentKey := fmt.Sprintf("%d-%d", node.parent.bus, node.parent.dev)
+			if parent, parentExists := deviceMap[parentKey]; parentExists {
+				parent.AddChild(device)
+			}
+		}
+	}
+
+	// Without any hierarchy, attach all other devices to their bus root
+	if len(hierarchy) == 0 {
+		for _, device := range devices {
+			if device.Address != 1 {
+				busKey := fmt.Sprintf("bus-%d", device.Bus)
+				if root, exists := rootDevices[busKey]; exists {
+					root.AddChild(device)
+				}
+			}
+		}
+	}
+
+	// Return only root devices (they contain the full tree), ordered by bus
+	// and port path rather than map order
+	var result []*models.USBDevice
+	for _, device := range rootDevices {
+		result = append(result, device)
+	}
+	models.SortByPort(result)
+
+	return result
+}
+
+func convertSpeed(speed string) string {
+	switch speed {
+	case "1.5M":
+		return "Low (1.5 Mbps)"
+	case "12M":
+		return "Full (12 Mbps)"
+	case "480M":
+		return "High (480 Mbps)"
+	case "5000M":
+		return "Super (5 Gbps)"
+	case "10000M":
+		return "Super+ (10 Gbps)"
+	case "20000M":
+		return "Super+ (20 Gbps)"
+	default:
+		return speed
+	}
+}
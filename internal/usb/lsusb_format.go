@@ -0,0 +1,125 @@
+package usb
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/stegmannb/usbtree/internal/models"
+)
+
+// WriteLsusb writes devices in the format of plain lsusb: one line per
+// device, buses and addresses in descending order as lsusb lists them.
+func WriteLsusb(w io.Writer, devices []*models.USBDevice) error {
+	bw := bufio.NewWriter(w)
+	for _, device := range lsusbOrder(devices) {
+		fmt.Fprintln(bw, lsusbHeader(device))
+	}
+	return bw.Flush()
+}
+
+// WriteLsusbTree writes devices in the format of lsusb -t: one line per
+// interface, indented by hub depth.
+func WriteLsusbTree(w io.Writer, devices []*models.USBDevice) error {
+	roots := append([]*models.USBDevice(nil), devices...)
+	sort.SliceStable(roots, func(i, j int) bool {
+		return roots[i].Bus > roots[j].Bus
+	})
+
+	bw := bufio.NewWriter(w)
+	for _, root := range roots {
+		fmt.Fprintf(bw, "/:  Bus %03d.Port 001: Dev %03d, Class=root_hub, Driver=%s, %s\n",
+			root.Bus, root.Address, lsusbDriver(root.ControllerDriver, root.Ports), lsusbSpeed(root.Speed))
+		writeLsusbTreeChildren(bw, root, 1)
+	}
+	return bw.Flush()
+}
+
+func writeLsusbTreeChildren(w *bufio.Writer, device *models.USBDevice, level int) {
+	children := append([]*models.USBDevice(nil), device.Children...)
+	sort.SliceStable(children, func(i, j int) bool {
+		return children[i].Port < children[j].Port
+	})
+
+	indent := strings.Repeat(" ", level*4)
+	for _, child := range children {
+		prefix := fmt.Sprintf("%s|__ Port %03d: Dev %03d", indent, child.Port, child.Address)
+		speed := lsusbSpeed(child.Speed)
+
+		if len(child.Interfaces) == 0 {
+			fmt.Fprintf(w, "%s, %s\n", prefix, speed)
+		}
+		for _, iface := range child.Interfaces {
+			ports := 0
+			if iface.Class == 0x09 {
+				ports = child.Ports
+			}
+			fmt.Fprintf(w, "%s, If %d, Class=%s, Driver=%s, %s\n",
+				prefix, iface.Number, lsusbClassName(iface.Class), lsusbDriver(iface.Driver, ports), speed)
+		}
+
+		writeLsusbTreeChildren(w, child, level+1)
+	}
+}
+
+// lsusbOrder lists all devices of the tree by descending bus and address.
+func lsusbOrder(devices []*models.USBDevice) []*models.USBDevice {
+	var all []*models.USBDevice
+	models.NewTopology(devices).Walk(func(device *models.USBDevice) error {
+		all = append(all, device)
+		return nil
+	})
+	sort.SliceStable(all, func(i, j int) bool {
+		if all[i].Bus != all[j].Bus {
+			return all[i].Bus > all[j].Bus
+		}
+		return all[i].Address > all[j].Address
+	})
+	return all
+}
+
+func lsusbHeader(device *models.USBDevice) string {
+	var names []string
+	for _, name := range []string{device.VendorName, device.ProductName} {
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	return fmt.Sprintf("Bus %03d Device %03d: ID %04x:%04x %s",
+		device.Bus, device.Address, device.VendorID, device.ProductID, strings.Join(names, " "))
+}
+
+// lsusbDriver formats a driver name the way lsusb -t does, with the port
+// count of hubs appended.
+func lsusbDriver(driver string, ports int) string {
+	if driver == "" {
+		driver = "[none]"
+	}
+	if ports > 0 {
+		return fmt.Sprintf("%s/%dp", driver, ports)
+	}
+	return driver
+}
+
+// lsusbSpeed turns a speed as stored by convertSpeed back into lsusb -t
+// notation, e.g. 480M.
+func lsusbSpeed(speed string) string {
+	for _, notation := range []string{"1.5M", "12M", "480M", "5000M", "10000M", "20000M"} {
+		if convertSpeed(notation) == speed {
+			return notation
+		}
+	}
+	return speed
+}
+
+// lsusbClassName returns the class name lsusb prints for a class code.
+func lsusbClassName(code uint8) string {
+	for _, class := range usbClasses {
+		if class.code == code {
+			return class.lsusbName
+		}
+	}
+	return "[unknown]"
+}
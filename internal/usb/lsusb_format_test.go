@@ -0,0 +1,127 @@
+package usb
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stegmannb/usbtree/internal/models"
+)
+
+// parseTestdata reads the captured lsusb and lsusb -t output the way the
+// lsusb detector combines them.
+func parseTestdata(t *testing.T) []*models.USBDevice {
+	t.Helper()
+	devices, err := parseLsusbOutput(strings.NewReader(readTestdata(t, "lsusb.txt")))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	hierarchy, err := parseLsusbTree(strings.NewReader(readTestdata(t, "lsusb-t.txt")))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	return mergeHierarchy(devices, hierarchy)
+}
+
+func TestMergeHierarchy_Order(t *testing.T) {
+	// Map iteration differs between runs, so try a few
+	for i := 0; i < 20; i++ {
+		var order []string
+		models.NewTopology(parseTestdata(t)).Walk(func(device *models.USBDevice) error {
+			order = append(order, device.PortPath)
+			return nil
+		})
+		if got := strings.Join(order, " "); got != "usb1 1-2 1-2.1 1-2.3 usb2" {
+			t.Fatalf("Expected devices by bus and port path, got %s", got)
+		}
+	}
+}
+
+func TestWriteLsusb(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteLsusb(&buf, parseTestdata(t)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if expected := readTestdata(t, "lsusb.txt"); buf.String() != expected {
+		t.Errorf("Output differs from captured lsusb:\ngot:\n%s\nexpected:\n%s", buf.String(), expected)
+	}
+}
+
+func TestWriteLsusbTree(t *testing.T) {
+	expected := readTestdata(t, "lsusb-t.txt")
+
+	// Both with names from lsusb and from the tree alone
+	treeOnly, err := ParseInput(strings.NewReader(expected), InputLsusbTree)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for name, devices := range map[string][]*models.USBDevice{"merged": parseTestdata(t), "tree only": treeOnly} {
+		var buf bytes.Buffer
+		if err := WriteLsusbTree(&buf, devices); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if buf.String() != expected {
+			t.Errorf("%s: output differs from captured lsusb -t:\ngot:\n%s\nexpected:\n%s", name, buf.String(), expected)
+		}
+	}
+}
+
+func TestSysfsDetector(t *testing.T) {
+	fake := newFakeSysfs(t)
+	fake.addDevice("usb1", map[string]string{
+		"idVendor": "1d6b", "idProduct": "0002", "manufacturer": "Linux 6.8.0 xhci-hcd", "product": "xHCI Host Controller",
+		"busnum": "1", "devnum": "1", "speed": "480", "bDeviceClass": "09", "bDeviceProtocol": "01",
+		"version": " 2.00", "bcdDevice": "0608", "bMaxPacketSize0": "64", "bNumConfigurations": "1", "maxchild": "12",
+	})
+	fake.addDevice("1-0:1.0", map[string]string{"bInterfaceNumber": "00", "bInterfaceClass": "09"})
+	fake.addDevice("1-2", map[string]string{
+		"idVendor": "05e3", "idProduct": "0610", "product": "USB2.0 Hub", "busnum": "1", "devnum": "2",
+		"speed": "480", "bDeviceClass": "09", "maxchild": "4", "bMaxPower": "100mA",
+	})
+	fake.addDevice("1-2:1.0", map[string]string{"bInterfaceNumber": "00", "bInterfaceClass": "09"})
+	fake.addDevice("1-2.3", map[string]string{
+		"idVendor": "0403", "idProduct": "6001", "manufacturer": "FTDI", "product": "FT232R USB UART",
+		"serial": "A50285BI", "busnum": "1", "devnum": "4", "speed": "12", "bDeviceClass": "00", "bMaxPower": "90mA",
+	})
+	fake.addDevice("1-2.3:1.0", map[string]string{"bInterfaceNumber": "00", "bInterfaceClass": "ff", "bInterfaceSubClass": "ff", "bInterfaceProtocol": "ff"})
+	fake.addDevice("1-2.1", map[string]string{"idVendor": "046d", "idProduct": "c52b", "busnum": "1", "devnum": "3", "speed": "12"})
+	fake.addDevice("1-2-port1", map[string]string{"connect_type": "hotplug"})
+
+	for _, link := range [][2]string{{"1-2:1.0", "hub"}, {"1-2.3:1.0", "ftdi_sio"}} {
+		if err := os.Symlink("../../../bus/usb/drivers/"+link[1], filepath.Join(fake.root, link[0], "driver")); err != nil {
+			t.Fatalf("Failed to create driver link: %v", err)
+		}
+	}
+
+	devices, err := NewSysfsDetector(NewSysfs(fake.root)).GetDevices()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(devices) != 1 {
+		t.Fatalf("Expected 1 root hub, got %d", len(devices))
+	}
+
+	root := devices[0]
+	if root.PortPath != "usb1" || root.Class != "Hub" || root.Ports != 12 || root.Descriptor.USBVersion != "2.00" || root.Descriptor.DeviceVersion != "6.08" {
+		t.Errorf("Unexpected root hub: %+v %+v", root, root.Descriptor)
+	}
+	ftdi := findByAddress(devices, 1, 4)
+	if ftdi == nil || ftdi.PortPath != "1-2.3" || ftdi.Port != 3 || ftdi.Serial != "A50285BI" || ftdi.Class != "Vendor Specific" || ftdi.Speed != "Full (12 Mbps)" {
+		t.Fatalf("Unexpected FTDI device: %+v", ftdi)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteLsusbTree(&buf, devices); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := `/:  Bus 001.Port 001: Dev 001, Class=root_hub, Driver=[none]/12p, 480M
+    |__ Port 002: Dev 002, If 0, Class=Hub, Driver=hub/4p, 480M
+        |__ Port 001: Dev 003, 12M
+        |__ Port 003: Dev 004, If 0, Class=Vendor Specific Class, Driver=ftdi_sio, 12M
+`
+	if buf.String() != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, buf.String())
+	}
+}
@@ -0,0 +1,161 @@
+package usb
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/stegmannb/usbtree/internal/models"
+)
+
+var (
+	verboseHeaderRe = regexp.MustCompile(`^Bus (\d{3}) Device (\d{3}): ID ([0-9a-f]{4}):([0-9a-f]{4})`)
+	verboseFieldRe  = regexp.MustCompile(`^\s*(\w+)\s+(\S+)\s*(.*)$`)
+)
+
+// parseLsusbVerbose reads lsusb -v output. It contains descriptors but no
+// topology, so devices are attached to the root hub of their bus.
+func parseLsusbVerbose(r io.Reader) ([]*models.USBDevice, error) {
+	var devices []*models.USBDevice
+	var device *models.USBDevice
+	var iface *models.Interface
+
+	finishDevice := func() {
+		if device == nil {
+			return
+		}
+		if iface != nil {
+			device.Interfaces = append(device.Interfaces, *iface)
+			iface = nil
+		}
+		device.Class = deviceClassName(device.Descriptor.Class, device.Interfaces)
+		devices = append(devices, device)
+	}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if matches := verboseHeaderRe.FindStringSubmatch(line); matches != nil {
+			finishDevice()
+
+			bus, _ := strconv.Atoi(matches[1])
+			address, _ := strconv.Atoi(matches[2])
+			vendorID, _ := strconv.ParseUint(matches[3], 16, 16)
+			productID, _ := strconv.ParseUint(matches[4], 16, 16)
+
+			device = &models.USBDevice{
+				VendorID:   uint16(vendorID),
+				ProductID:  uint16(productID),
+				Bus:        bus,
+				Address:    address,
+				Speed:      "Unknown",
+				Descriptor: &models.DeviceDescriptor{},
+			}
+			continue
+		}
+
+		if device == nil {
+			continue
+		}
+
+		matches := verboseFieldRe.FindStringSubmatch(line)
+		if matches == nil {
+			continue
+		}
+		field, value, description := matches[1], matches[2], strings.TrimSpace(matches[3])
+
+		switch field {
+		case "idVendor":
+			device.VendorName = description
+		case "idProduct":
+			device.ProductName = description
+		case "iManufacturer":
+			if device.VendorName == "" {
+				device.VendorName = description
+			}
+		case "iProduct":
+			if device.ProductName == "" {
+				device.ProductName = description
+			}
+		case "iSerial":
+			device.Serial = description
+		case "bcdUSB":
+			device.Descriptor.USBVersion = value
+		case "bDeviceClass":
+			device.Descriptor.Class = parseVerboseNumber(value)
+		case "bDeviceSubClass":
+			device.Descriptor.SubClass = parseVerboseNumber(value)
+			device.SubClass = fmt.Sprintf("%02x", device.Descriptor.SubClass)
+		case "bDeviceProtocol":
+			device.Descriptor.Protocol = parseVerboseNumber(value)
+			device.Protocol = fmt.Sprintf("%02x", device.Descriptor.Protocol)
+		case "bMaxPacketSize0":
+			device.Descriptor.MaxPacketSize = parseVerboseNumber(value)
+		case "bcdDevice":
+			device.Descriptor.DeviceVersion = value
+		case "bNumConfigurations":
+			device.Descriptor.Configurations = parseVerboseNumber(value)
+		case "MaxPower":
+			device.MaxPower = value
+		case "bInterfaceNumber":
+			if iface != nil {
+				device.Interfaces = append(device.Interfaces, *iface)
+			}
+			iface = &models.Interface{Number: int(parseVerboseNumber(value))}
+		case "bInterfaceClass":
+			if iface != nil {
+				iface.Class = parseVerboseNumber(value)
+			}
+		case "bInterfaceSubClass":
+			if iface != nil {
+				iface.SubClass = parseVerboseNumber(value)
+			}
+		case "bInterfaceProtocol":
+			if iface != nil {
+				iface.Protocol = parseVerboseNumber(value)
+			}
+		}
+	}
+	finishDevice()
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read lsusb -v output: %w", err)
+	}
+
+	// Alternate settings repeat interface numbers; keep the first of each
+	for _, device := range devices {
+		device.Interfaces = uniqueInterfaces(device.Interfaces)
+		if device.VendorID == 0x1d6b {
+			setRootHubSpeed(device)
+		}
+	}
+
+	return devices, nil
+}
+
+// parseVerboseNumber parses decimal and 0x-prefixed hex values as lsusb
+// prints them.
+func parseVerboseNumber(value string) uint8 {
+	parsed, err := strconv.ParseUint(value, 0, 8)
+	if err != nil {
+		return 0
+	}
+	return uint8(parsed)
+}
+
+func uniqueInterfaces(interfaces []models.Interface) []models.Interface {
+	seen := make(map[int]bool)
+	var unique []models.Interface
+	for _, iface := range interfaces {
+		if seen[iface.Number] {
+			continue
+		}
+		seen[iface.Number] = true
+		unique = append(unique, iface)
+	}
+	return unique
+}
@@ -0,0 +1,292 @@
+package usb
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/stegmannb/usbtree/internal/models"
+)
+
+// DefaultSysfsRoot is where the kernel lists every USB device and interface
+// by its port path (usb1, 1-2, 1-2.3, 1-2.3:1.0, ...).
+const DefaultSysfsRoot = "/sys/bus/usb/devices"
+
+// Sysfs reads and writes device attributes below a sysfs root. The root is
+// configurable so tests and captured fixtures can stand in for /sys.
+type Sysfs struct {
+	Root string
+}
+
+func NewSysfs(root string) *Sysfs {
+	if root == "" {
+		root = DefaultSysfsRoot
+	}
+	return &Sysfs{Root: root}
+}
+
+func (s *Sysfs) devicePath(portPath string) string {
+	return filepath.Join(s.Root, portPath)
+}
+
+// HasDevice reports whether sysfs has a directory for the given port path.
+func (s *Sysfs) HasDevice(portPath string) bool {
+	if portPath == "" {
+		return false
+	}
+	info, err := os.Stat(s.devicePath(portPath))
+	return err == nil && info.IsDir()
+}
+
+func (s *Sysfs) readAttr(portPath, attr string) (string, error) {
+	data, err := os.ReadFile(filepath.Join(s.devicePath(portPath), attr))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+func (s *Sysfs) writeAttr(portPath, attr, value string) error {
+	path := filepath.Join(s.devicePath(portPath), attr)
+	if err := os.WriteFile(path, []byte(value), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+// ReadPowerState collects the runtime power management attributes of a
+// device. Attributes the kernel does not expose are left at their zero value.
+func (s *Sysfs) ReadPowerState(portPath string) (*models.PowerState, error) {
+	if !s.HasDevice(portPath) {
+		return nil, fmt.Errorf("no sysfs entry for device %s", portPath)
+	}
+
+	power := &models.PowerState{}
+	power.Control, _ = s.readAttr(portPath, "power/control")
+	power.RuntimeStatus, _ = s.readAttr(portPath, "power/runtime_status")
+	power.Wakeup, _ = s.readAttr(portPath, "power/wakeup")
+
+	if value, err := s.readAttr(portPath, "power/autosuspend_delay_ms"); err == nil {
+		power.AutosuspendDelayMs, _ = strconv.Atoi(value)
+	}
+	if value, err := s.readAttr(portPath, "power/runtime_active_time"); err == nil {
+		power.ActiveTimeMs, _ = strconv.ParseInt(value, 10, 64)
+	}
+	if value, err := s.readAttr(portPath, "power/runtime_suspended_time"); err == nil {
+		power.SuspendedTimeMs, _ = strconv.ParseInt(value, 10, 64)
+	}
+	if value, err := s.readAttr(portPath, "power/persist"); err == nil {
+		power.Persist = value == "1"
+	}
+
+	return power, nil
+}
+
+// ReadAttr returns a device attribute such as product or serial with
+// surrounding whitespace removed.
+func (s *Sysfs) ReadAttr(portPath, attr string) (string, error) {
+	return s.readAttr(portPath, attr)
+}
+
+// ReadDescriptors returns the raw descriptor blob of the device: the device
+// descriptor followed by all configuration descriptors.
+func (s *Sysfs) ReadDescriptors(portPath string) ([]byte, error) {
+	return os.ReadFile(filepath.Join(s.devicePath(portPath), "descriptors"))
+}
+
+// ReadInterfaces lists the interfaces of the device's active configuration,
+// ordered by interface number.
+func (s *Sysfs) ReadInterfaces(portPath string) ([]models.Interface, error) {
+	if !s.HasDevice(portPath) {
+		return nil, fmt.Errorf("no sysfs entry for device %s", portPath)
+	}
+
+	entries, err := s.interfaceDirs(portPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var interfaces []models.Interface
+	for _, entry := range entries {
+		name := filepath.Base(entry)
+		iface := models.Interface{
+			Number:   int(s.readHexAttr(name, "bInterfaceNumber")),
+			Class:    s.readHexAttr(name, "bInterfaceClass"),
+			SubClass: s.readHexAttr(name, "bInterfaceSubClass"),
+			Protocol: s.readHexAttr(name, "bInterfaceProtocol"),
+		}
+		if driver, err := os.Readlink(filepath.Join(entry, "driver")); err == nil {
+			iface.Driver = filepath.Base(driver)
+		}
+		interfaces = append(interfaces, iface)
+	}
+
+	sort.Slice(interfaces, func(i, j int) bool {
+		return interfaces[i].Number < interfaces[j].Number
+	})
+
+	return interfaces, nil
+}
+
+// interfaceDirs returns the sysfs directories of the device's interfaces.
+func (s *Sysfs) interfaceDirs(portPath string) ([]string, error) {
+	// Interfaces of root hubs are named after port 0 of their bus (1-0:1.0)
+	prefix := portPath
+	if bus, found := strings.CutPrefix(portPath, "usb"); found {
+		prefix = bus + "-0"
+	}
+	return filepath.Glob(filepath.Join(s.Root, prefix+":*"))
+}
+
+// MaxDevNodeDepth bounds the search for device nodes below an interface,
+// e.g. 1-2:1.0/host0/target0:0:0/0:0:0:0/block/sda.
+const MaxDevNodeDepth = 6
+
+// DevNodes lists the /dev nodes of a device and of the class devices bound
+// to its interfaces, such as /dev/bus/usb/001/004, /dev/ttyUSB0 or
+// /dev/input/event5, as named by the DEVNAME of their uevents.
+func (s *Sysfs) DevNodes(portPath string) []string {
+	if !s.HasDevice(portPath) {
+		return nil
+	}
+
+	var nodes []string
+	if node := s.devName(s.devicePath(portPath)); node != "" {
+		nodes = append(nodes, node)
+	}
+
+	dirs, _ := s.interfaceDirs(portPath)
+	for _, dir := range dirs {
+		// The entries below /sys/bus/usb/devices are symlinks, which WalkDir
+		// would not descend into
+		if resolved, err := filepath.EvalSymlinks(dir); err == nil {
+			dir = resolved
+		}
+		filepath.WalkDir(dir, func(path string, entry os.DirEntry, err error) error {
+			if err != nil || !entry.IsDir() {
+				return nil
+			}
+			rel, _ := filepath.Rel(dir, path)
+			if rel != "." && strings.Count(rel, string(filepath.Separator)) >= MaxDevNodeDepth {
+				return filepath.SkipDir
+			}
+			if node := s.devName(path); node != "" {
+				nodes = append(nodes, node)
+			}
+			return nil
+		})
+	}
+
+	sort.Strings(nodes)
+	return slices.Compact(nodes)
+}
+
+// devName returns the device node announced in the uevent file of a sysfs
+// directory, if any.
+func (s *Sysfs) devName(dir string) string {
+	data, err := os.ReadFile(filepath.Join(dir, "uevent"))
+	if err != nil {
+		return ""
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if name, found := strings.CutPrefix(line, "DEVNAME="); found && name != "" {
+			return "/dev/" + name
+		}
+	}
+	return ""
+}
+
+// OverCurrentCounts returns how often each port of a hub reported an
+// over-current condition since boot, by port number, as counted in the
+// port directories below the hub's interface (1-2:1.0/1-2-port3). Ports
+// whose kernel does not count over-currents are left out.
+func (s *Sysfs) OverCurrentCounts(portPath string) map[int]int {
+	if !s.HasDevice(portPath) {
+		return nil
+	}
+
+	dirs, _ := s.interfaceDirs(portPath)
+	counts := make(map[int]int)
+	for _, dir := range dirs {
+		ports, _ := filepath.Glob(filepath.Join(dir, portPath+"-port*"))
+		for _, port := range ports {
+			number, err := strconv.Atoi(strings.TrimPrefix(filepath.Base(port), portPath+"-port"))
+			if err != nil {
+				continue
+			}
+			data, err := os.ReadFile(filepath.Join(port, "over_current_count"))
+			if err != nil {
+				continue
+			}
+			if count, err := strconv.Atoi(strings.TrimSpace(string(data))); err == nil {
+				counts[number] = count
+			}
+		}
+	}
+	return counts
+}
+
+func (s *Sysfs) readHexAttr(name, attr string) uint8 {
+	value, err := s.readAttr(name, attr)
+	if err != nil {
+		return 0
+	}
+	parsed, _ := strconv.ParseUint(value, 16, 8)
+	return uint8(parsed)
+}
+
+// SetAutosuspend accepts "on" to let the kernel suspend the device when idle,
+// "off" to keep it powered, or a delay in milliseconds which also enables
+// autosuspend.
+func (s *Sysfs) SetAutosuspend(portPath, value string) error {
+	if !s.HasDevice(portPath) {
+		return fmt.Errorf("no sysfs entry for device %s", portPath)
+	}
+
+	switch value {
+	case "on":
+		return s.writeAttr(portPath, "power/control", "auto")
+	case "off":
+		return s.writeAttr(portPath, "power/control", "on")
+	}
+
+	delay, err := strconv.Atoi(value)
+	if err != nil || delay < 0 {
+		return fmt.Errorf("invalid autosuspend value %q: expected on, off or a delay in milliseconds", value)
+	}
+	if err := s.writeAttr(portPath, "power/autosuspend_delay_ms", strconv.Itoa(delay)); err != nil {
+		return err
+	}
+	return s.writeAttr(portPath, "power/control", "auto")
+}
+
+// SetWakeup enables or disables remote wakeup for the device.
+func (s *Sysfs) SetWakeup(portPath string, enabled bool) error {
+	if !s.HasDevice(portPath) {
+		return fmt.Errorf("no sysfs entry for device %s", portPath)
+	}
+
+	value := "disabled"
+	if enabled {
+		value = "enabled"
+	}
+	return s.writeAttr(portPath, "power/wakeup", value)
+}
+
+// enrichDevices fills in the sysfs-only attributes of every device in the
+// tree that has a known port path.
+func (s *Sysfs) enrichDevices(devices []*models.USBDevice) {
+	for _, device := range devices {
+		if power, err := s.ReadPowerState(device.PortPath); err == nil {
+			device.Power = power
+		}
+		if interfaces, err := s.ReadInterfaces(device.PortPath); err == nil {
+			device.Interfaces = interfaces
+		}
+		s.enrichDevices(device.Children)
+	}
+}
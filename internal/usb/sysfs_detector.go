@@ -0,0 +1,188 @@
+package usb
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/stegmannb/usbtree/internal/models"
+)
+
+type sysfsDetector struct {
+	sysfs *Sysfs
+}
+
+// NewSysfsDetector returns a Detector reading all devices from sysfs alone,
+// for systems without lsusb.
+func NewSysfsDetector(sysfs *Sysfs) Detector {
+	return &sysfsDetector{sysfs: sysfs}
+}
+
+func (d *sysfsDetector) Backend() string {
+	return "sysfs"
+}
+
+func (d *sysfsDetector) GetDevices() ([]*models.USBDevice, error) {
+	entries, err := os.ReadDir(d.sysfs.Root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", d.sysfs.Root, err)
+	}
+
+	// Interfaces (1-2:1.0) are read with their device
+	byPortPath := make(map[string]*models.USBDevice)
+	var portPaths []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if strings.Contains(name, ":") || !d.sysfs.HasDevice(name) {
+			continue
+		}
+		if _, err := d.sysfs.readAttr(name, "idVendor"); err != nil {
+			continue
+		}
+		byPortPath[name] = d.readDevice(name)
+		portPaths = append(portPaths, name)
+	}
+
+	// Parents before children, and siblings by port
+	sort.Slice(portPaths, func(i, j int) bool {
+		a, b := byPortPath[portPaths[i]], byPortPath[portPaths[j]]
+		if depthOf(a.PortPath) != depthOf(b.PortPath) {
+			return depthOf(a.PortPath) < depthOf(b.PortPath)
+		}
+		if a.Bus != b.Bus {
+			return a.Bus < b.Bus
+		}
+		if a.Port != b.Port {
+			return a.Port < b.Port
+		}
+		return a.PortPath < b.PortPath
+	})
+
+	var roots []*models.USBDevice
+	for _, portPath := range portPaths {
+		device := byPortPath[portPath]
+		parent, ok := byPortPath[parentPortPath(portPath, device.Bus)]
+		if !ok {
+			roots = append(roots, device)
+			continue
+		}
+		parent.AddChild(device)
+	}
+
+	return roots, nil
+}
+
+// readDevice builds a device from the attributes of its sysfs directory.
+func (d *sysfsDetector) readDevice(portPath string) *models.USBDevice {
+	attr := func(name string) string {
+		value, _ := d.sysfs.readAttr(portPath, name)
+		return value
+	}
+	hex := func(name string, bits int) uint64 {
+		value, _ := strconv.ParseUint(attr(name), 16, bits)
+		return value
+	}
+	dec := func(name string) int {
+		value, _ := strconv.Atoi(attr(name))
+		return value
+	}
+
+	descriptor := &models.DeviceDescriptor{
+		USBVersion:     attr("version"),
+		Class:          uint8(hex("bDeviceClass", 8)),
+		SubClass:       uint8(hex("bDeviceSubClass", 8)),
+		Protocol:       uint8(hex("bDeviceProtocol", 8)),
+		MaxPacketSize:  uint8(dec("bMaxPacketSize0")),
+		DeviceVersion:  bcdString(uint16(hex("bcdDevice", 16))),
+		Configurations: uint8(dec("bNumConfigurations")),
+	}
+
+	device := &models.USBDevice{
+		VendorID:    uint16(hex("idVendor", 16)),
+		ProductID:   uint16(hex("idProduct", 16)),
+		VendorName:  attr("manufacturer"),
+		ProductName: attr("product"),
+		Bus:         dec("busnum"),
+		Address:     dec("devnum"),
+		Serial:      attr("serial"),
+		Speed:       "Unknown",
+		SubClass:    fmt.Sprintf("%02x", descriptor.SubClass),
+		Protocol:    fmt.Sprintf("%02x", descriptor.Protocol),
+		MaxPower:    attr("bMaxPower"),
+		Ports:       dec("maxchild"),
+		PortPath:    portPath,
+		Descriptor:  descriptor,
+	}
+
+	if speed := attr("speed"); speed != "" {
+		device.Speed = convertSpeed(speed + "M")
+	}
+	if _, ports, found := strings.Cut(portPath, "-"); found {
+		last := ports[strings.LastIndex(ports, ".")+1:]
+		device.Port, _ = strconv.Atoi(last)
+	} else {
+		device.ControllerDriver = d.controllerDriver(portPath)
+	}
+
+	device.Power, _ = d.sysfs.ReadPowerState(portPath)
+	device.Interfaces, _ = d.sysfs.ReadInterfaces(portPath)
+	device.Class = deviceClassName(descriptor.Class, device.Interfaces)
+
+	return device
+}
+
+// controllerDriver returns the driver of the host controller a root hub
+// belongs to, which is bound to the parent of the root hub's directory.
+func (d *sysfsDetector) controllerDriver(portPath string) string {
+	path, err := filepath.EvalSymlinks(d.sysfs.devicePath(portPath))
+	if err != nil {
+		return ""
+	}
+	driver, err := os.Readlink(filepath.Join(filepath.Dir(path), "driver"))
+	if err != nil {
+		return ""
+	}
+	return filepath.Base(driver)
+}
+
+// parentPortPath returns the port path of the hub a device is connected
+// to: 1-2.3 is behind 1-2, and 1-2 behind the root hub usb1.
+func parentPortPath(portPath string, bus int) string {
+	if i := strings.LastIndex(portPath, "."); i >= 0 {
+		return portPath[:i]
+	}
+	if strings.Contains(portPath, "-") {
+		return fmt.Sprintf("usb%d", bus)
+	}
+	return ""
+}
+
+// depthOf returns the number of hubs between a port path and its bus.
+func depthOf(portPath string) int {
+	if !strings.Contains(portPath, "-") {
+		return 0
+	}
+	return strings.Count(portPath, ".") + 1
+}
+
+// bcdString formats a binary-coded decimal version the way lsusb does,
+// e.g. 0x0210 as 2.10.
+func bcdString(bcd uint16) string {
+	return fmt.Sprintf("%x.%02x", bcd>>8, bcd&0xff)
+}
+
+// deviceClassName names the class of a device: its device class, or for
+// class 0 the class of its first interface.
+func deviceClassName(class uint8, interfaces []models.Interface) string {
+	switch {
+	case class != 0:
+		return className(class)
+	case len(interfaces) > 0:
+		return className(interfaces[0].Class)
+	default:
+		return "Device"
+	}
+}
@@ -0,0 +1,265 @@
+package usb
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stegmannb/usbtree/internal/models"
+)
+
+// fakeSysfs builds a throwaway sysfs tree in a temporary directory so the
+// attribute readers and writers can be exercised without real hardware.
+type fakeSysfs struct {
+	t    *testing.T
+	root string
+}
+
+func newFakeSysfs(t *testing.T) *fakeSysfs {
+	t.Helper()
+	return &fakeSysfs{t: t, root: t.TempDir()}
+}
+
+func (f *fakeSysfs) addDevice(portPath string, attrs map[string]string) {
+	f.t.Helper()
+	for attr, value := range attrs {
+		path := filepath.Join(f.root, portPath, attr)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			f.t.Fatalf("Failed to create %s: %v", filepath.Dir(path), err)
+		}
+		if err := os.WriteFile(path, []byte(value+"\n"), 0644); err != nil {
+			f.t.Fatalf("Failed to write %s: %v", path, err)
+		}
+	}
+}
+
+func (f *fakeSysfs) attr(portPath, attr string) string {
+	f.t.Helper()
+	data, err := os.ReadFile(filepath.Join(f.root, portPath, attr))
+	if err != nil {
+		f.t.Fatalf("Failed to read %s of %s: %v", attr, portPath, err)
+	}
+	return strings.TrimSpace(string(data))
+}
+
+func powerAttrs() map[string]string {
+	return map[string]string{
+		"power/control":                "auto",
+		"power/runtime_status":         "suspended",
+		"power/autosuspend_delay_ms":   "2000",
+		"power/runtime_active_time":    "1500",
+		"power/runtime_suspended_time": "98500",
+		"power/wakeup":                 "disabled",
+		"power/persist":                "1",
+	}
+}
+
+func TestSysfs_ReadPowerState(t *testing.T) {
+	fake := newFakeSysfs(t)
+	fake.addDevice("1-2", powerAttrs())
+	fake.addDevice("usb1", map[string]string{"power/control": "on"})
+
+	sysfs := NewSysfs(fake.root)
+
+	power, err := sysfs.ReadPowerState("1-2")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := models.PowerState{
+		Control:            "auto",
+		RuntimeStatus:      "suspended",
+		AutosuspendDelayMs: 2000,
+		ActiveTimeMs:       1500,
+		SuspendedTimeMs:    98500,
+		Wakeup:             "disabled",
+		Persist:            true,
+	}
+	if *power != expected {
+		t.Errorf("Expected %+v, got %+v", expected, *power)
+	}
+
+	// Attributes the kernel does not expose stay at their zero value
+	power, err = sysfs.ReadPowerState("usb1")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if power.Control != "on" || power.Wakeup != "" || power.Persist {
+		t.Errorf("Unexpected power state for partial device: %+v", *power)
+	}
+
+	if _, err := sysfs.ReadPowerState("1-9"); err == nil {
+		t.Error("Expected error for missing device")
+	}
+}
+
+func TestSysfs_SetAutosuspend(t *testing.T) {
+	fake := newFakeSysfs(t)
+	fake.addDevice("1-2", powerAttrs())
+	sysfs := NewSysfs(fake.root)
+
+	if err := sysfs.SetAutosuspend("1-2", "off"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := fake.attr("1-2", "power/control"); got != "on" {
+		t.Errorf("Expected control 'on' after disabling autosuspend, got %q", got)
+	}
+
+	if err := sysfs.SetAutosuspend("1-2", "on"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := fake.attr("1-2", "power/control"); got != "auto" {
+		t.Errorf("Expected control 'auto' after enabling autosuspend, got %q", got)
+	}
+
+	if err := sysfs.SetAutosuspend("1-2", "500"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := fake.attr("1-2", "power/autosuspend_delay_ms"); got != "500" {
+		t.Errorf("Expected autosuspend delay 500, got %q", got)
+	}
+
+	if err := sysfs.SetAutosuspend("1-2", "sometimes"); err == nil {
+		t.Error("Expected error for invalid autosuspend value")
+	}
+	if err := sysfs.SetAutosuspend("1-2", "-1"); err == nil {
+		t.Error("Expected error for negative autosuspend delay")
+	}
+	if got := fake.attr("1-2", "power/autosuspend_delay_ms"); got != "500" {
+		t.Errorf("Expected autosuspend delay to stay 500, got %q", got)
+	}
+	if err := sysfs.SetAutosuspend("1-9", "on"); err == nil {
+		t.Error("Expected error for missing device")
+	}
+}
+
+func TestSysfs_SetWakeup(t *testing.T) {
+	fake := newFakeSysfs(t)
+	fake.addDevice("1-2", powerAttrs())
+	sysfs := NewSysfs(fake.root)
+
+	if err := sysfs.SetWakeup("1-2", true); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := fake.attr("1-2", "power/wakeup"); got != "enabled" {
+		t.Errorf("Expected wakeup 'enabled', got %q", got)
+	}
+
+	if err := sysfs.SetWakeup("1-2", false); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := fake.attr("1-2", "power/wakeup"); got != "disabled" {
+		t.Errorf("Expected wakeup 'disabled', got %q", got)
+	}
+}
+
+func TestSysfs_EnrichDevices(t *testing.T) {
+	fake := newFakeSysfs(t)
+	fake.addDevice("1-2", powerAttrs())
+
+	root := &models.USBDevice{ProductName: "Root Hub", PortPath: "usb1"}
+	child := &models.USBDevice{ProductName: "FT232R USB UART", PortPath: "1-2"}
+	root.AddChild(child)
+
+	NewSysfs(fake.root).enrichDevices([]*models.USBDevice{root})
+
+	if root.Power != nil {
+		t.Errorf("Expected no power state for device without sysfs entry, got %+v", root.Power)
+	}
+	if child.Power == nil || child.Power.RuntimeStatus != "suspended" {
+		t.Errorf("Expected power state to be read for child, got %+v", child.Power)
+	}
+}
+
+func TestSysfs_ReadInterfaces(t *testing.T) {
+	fake := newFakeSysfs(t)
+	fake.addDevice("1-2", map[string]string{"idVendor": "046d"})
+	fake.addDevice("1-2:1.1", map[string]string{
+		"bInterfaceNumber":   "01",
+		"bInterfaceClass":    "03",
+		"bInterfaceSubClass": "00",
+		"bInterfaceProtocol": "00",
+	})
+	fake.addDevice("1-2:1.0", map[string]string{
+		"bInterfaceNumber":   "00",
+		"bInterfaceClass":    "03",
+		"bInterfaceSubClass": "01",
+		"bInterfaceProtocol": "02",
+	})
+	fake.addDevice("1-2.4:1.0", map[string]string{"bInterfaceClass": "08"})
+	fake.addDevice("usb1", map[string]string{"idVendor": "1d6b"})
+	fake.addDevice("1-0:1.0", map[string]string{"bInterfaceClass": "09"})
+
+	if err := os.Symlink("../../../bus/usb/drivers/usbhid", filepath.Join(fake.root, "1-2:1.0", "driver")); err != nil {
+		t.Fatalf("Failed to create driver link: %v", err)
+	}
+
+	sysfs := NewSysfs(fake.root)
+
+	interfaces, err := sysfs.ReadInterfaces("1-2")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := []models.Interface{
+		{Number: 0, Class: 0x03, SubClass: 0x01, Protocol: 0x02, Driver: "usbhid"},
+		{Number: 1, Class: 0x03},
+	}
+	if len(interfaces) != len(expected) {
+		t.Fatalf("Expected %d interfaces, got %+v", len(expected), interfaces)
+	}
+	for i := range expected {
+		if interfaces[i] != expected[i] {
+			t.Errorf("Interface %d: expected %+v, got %+v", i, expected[i], interfaces[i])
+		}
+	}
+
+	interfaces, err = sysfs.ReadInterfaces("usb1")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(interfaces) != 1 || interfaces[0].Class != 0x09 {
+		t.Errorf("Expected the root hub's hub interface, got %+v", interfaces)
+	}
+}
+
+func TestSysfs_DevNodes(t *testing.T) {
+	fake := newFakeSysfs(t)
+	fake.addDevice("1-2", map[string]string{"uevent": "MAJOR=189\nMINOR=3\nDEVNAME=bus/usb/001/004\nDEVTYPE=usb_device"})
+	fake.addDevice("1-2:1.0", map[string]string{
+		"bInterfaceClass":            "ff",
+		"ttyUSB0/uevent":             "DEVNAME=ttyUSB0",
+		"ttyUSB0/tty/ttyUSB0/uevent": "MAJOR=188\nMINOR=0\nDEVNAME=ttyUSB0",
+		"ep_81/uevent":               "DEVTYPE=usb_endpoint",
+	})
+	fake.addDevice("1-2:1.1", map[string]string{"0003:046D:C52B.0001/input/input5/event5/uevent": "DEVNAME=input/event5"})
+
+	nodes := NewSysfs(fake.root).DevNodes("1-2")
+	expected := []string{"/dev/bus/usb/001/004", "/dev/input/event5", "/dev/ttyUSB0"}
+	if strings.Join(nodes, " ") != strings.Join(expected, " ") {
+		t.Errorf("Expected %v, got %v", expected, nodes)
+	}
+
+	if nodes := NewSysfs(fake.root).DevNodes("1-3"); nodes != nil {
+		t.Errorf("Expected no nodes for missing device, got %v", nodes)
+	}
+}
+
+func TestSysfs_OverCurrentCounts(t *testing.T) {
+	fake := newFakeSysfs(t)
+	fake.addDevice("usb1", map[string]string{"maxchild": "2"})
+	fake.addDevice("1-0:1.0", map[string]string{
+		"usb1-port1/over_current_count": "3",
+		"usb1-port2/over_current_count": "0",
+	})
+	fake.addDevice("1-2", map[string]string{"maxchild": "4"})
+	fake.addDevice("1-2:1.0", map[string]string{"1-2-port1/connect_type": "hotplug"})
+
+	sysfs := NewSysfs(fake.root)
+	if counts := sysfs.OverCurrentCounts("usb1"); len(counts) != 2 || counts[1] != 3 || counts[2] != 0 {
+		t.Errorf("Unexpected root hub counts %v", counts)
+	}
+	if counts := sysfs.OverCurrentCounts("1-2"); len(counts) != 0 {
+		t.Errorf("Expected no counts without over_current_count, got %v", counts)
+	}
+}
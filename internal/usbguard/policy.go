@@ -0,0 +1,212 @@
+package usbguard
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"github.com/stegmannb/usbtree/internal/models"
+)
+
+// AttributeSource supplies the raw device data USBGuard derives device names
+// and hashes from. *usb.Sysfs implements it.
+type AttributeSource interface {
+	ReadAttr(portPath, attr string) (string, error)
+	ReadDescriptors(portPath string) ([]byte, error)
+}
+
+// DeviceAttributes are the attributes USBGuard identifies a device by.
+type DeviceAttributes struct {
+	ID         string
+	Name       string
+	Serial     string
+	Hash       string
+	ViaPort    string
+	Interfaces []string
+}
+
+// Attributes collects the USBGuard attributes of a device. Name and serial
+// come from the device's string descriptors when the source has them and
+// fall back to the model otherwise. The hash is only available when the
+// source can provide the descriptor blob.
+func Attributes(device *models.USBDevice, src AttributeSource) DeviceAttributes {
+	attrs := DeviceAttributes{
+		ID:      device.GetIDString(),
+		Name:    device.ProductName,
+		Serial:  device.Serial,
+		ViaPort: device.PortPath,
+	}
+
+	for _, iface := range device.Interfaces {
+		attrs.Interfaces = append(attrs.Interfaces, iface.ClassTriple())
+	}
+
+	if src == nil || device.PortPath == "" {
+		return attrs
+	}
+
+	if name, err := src.ReadAttr(device.PortPath, "product"); err == nil {
+		attrs.Name = name
+	}
+	if serial, err := src.ReadAttr(device.PortPath, "serial"); err == nil {
+		attrs.Serial = serial
+	}
+	if descriptors, err := src.ReadDescriptors(device.PortPath); err == nil {
+		attrs.Hash = hashDevice(attrs, descriptors)
+	}
+
+	return attrs
+}
+
+// hashDevice computes the device hash the way USBGuard does: a SHA-256 over
+// vendor ID, product ID, name and serial followed by the descriptor data,
+// encoded as base64.
+func hashDevice(attrs DeviceAttributes, descriptors []byte) string {
+	vendor, product, _ := strings.Cut(attrs.ID, ":")
+
+	h := sha256.New()
+	h.Write([]byte(vendor))
+	h.Write([]byte(product))
+	h.Write([]byte(attrs.Name))
+	h.Write([]byte(attrs.Serial))
+	h.Write(descriptors)
+
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// Options selects the attributes written into generated rules.
+type Options struct {
+	Hash    bool
+	ViaPort bool
+}
+
+// FormatRule writes an allow rule in rules.conf syntax matching the device.
+func FormatRule(attrs DeviceAttributes, opts Options) string {
+	parts := []string{"allow", "id", attrs.ID}
+
+	if attrs.Serial != "" {
+		parts = append(parts, "serial", quote(attrs.Serial))
+	}
+	if attrs.Name != "" {
+		parts = append(parts, "name", quote(attrs.Name))
+	}
+	if opts.Hash && attrs.Hash != "" {
+		parts = append(parts, "hash", quote(attrs.Hash))
+	}
+	if opts.ViaPort && attrs.ViaPort != "" {
+		parts = append(parts, "via-port", quote(attrs.ViaPort))
+	}
+
+	switch len(attrs.Interfaces) {
+	case 0:
+	case 1:
+		parts = append(parts, "with-interface", attrs.Interfaces[0])
+	default:
+		parts = append(parts, "with-interface", "{ "+strings.Join(attrs.Interfaces, " ")+" }")
+	}
+
+	return strings.Join(parts, " ")
+}
+
+func quote(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `"`, `\"`)
+	return `"` + value + `"`
+}
+
+// GeneratePolicy returns one allow rule per device of the tree, parents
+// before their children, which is the order USBGuard expects to authorize
+// them in.
+func GeneratePolicy(topology *models.Topology, src AttributeSource, opts Options) []string {
+	var rules []string
+	for _, device := range topology.All() {
+		rules = append(rules, FormatRule(Attributes(device, src), opts))
+	}
+	return rules
+}
+
+// Decision explains what USBGuard would do with a device under a policy.
+type Decision struct {
+	Target string
+	Rule   *Rule // nil when the implicit policy applies
+
+	// Closest is the allow rule agreeing with the device on the most
+	// attributes when no rule matched, and Mismatches describes how the
+	// device differs from it.
+	Closest    *Rule
+	Mismatches []string
+
+	// Unchecked lists attributes of the deciding rule usbtree cannot verify.
+	Unchecked []string
+}
+
+// Allowed reports whether the device would be authorized.
+func (d Decision) Allowed() bool {
+	return d.Target == "allow"
+}
+
+// Explain describes the decision in a sentence.
+func (d Decision) Explain() string {
+	var explanation string
+	switch {
+	case d.Rule != nil:
+		explanation = fmt.Sprintf("%s by rule at line %d", pastTense(d.Target), d.Rule.Line)
+	default:
+		explanation = fmt.Sprintf("%s: no rule matches (implicit policy %s)", pastTense(d.Target), d.Target)
+		if d.Closest != nil {
+			explanation += fmt.Sprintf("; closest rule at line %d differs in %s",
+				d.Closest.Line, strings.Join(d.Mismatches, ", "))
+		}
+	}
+
+	if len(d.Unchecked) > 0 {
+		explanation += fmt.Sprintf(" (not verified: %s)", strings.Join(d.Unchecked, ", "))
+	}
+
+	return explanation
+}
+
+func pastTense(target string) string {
+	switch target {
+	case "allow":
+		return "allowed"
+	case "block":
+		return "blocked"
+	case "reject":
+		return "rejected"
+	default:
+		return target
+	}
+}
+
+// Decide evaluates the rules in order like USBGuard does: the first rule
+// whose attributes all match decides, otherwise the implicit policy target
+// applies.
+func Decide(rules []*Rule, attrs DeviceAttributes, implicitTarget string) Decision {
+	var closest *Rule
+	var closestMismatches []string
+	closestMatched := 0
+
+	for _, rule := range rules {
+		if !rule.decides() {
+			continue
+		}
+
+		mismatches, unchecked := rule.Evaluate(attrs)
+		if len(mismatches) == 0 {
+			return Decision{Target: rule.Target, Rule: rule, Unchecked: unchecked}
+		}
+
+		// The closest allow rule is the one agreeing on the most attributes
+		matched := len(rule.Conditions) - len(mismatches) - len(unchecked)
+		if rule.Target == "allow" && (closest == nil || matched > closestMatched ||
+			(matched == closestMatched && len(mismatches) < len(closestMismatches))) {
+			closest = rule
+			closestMismatches = mismatches
+			closestMatched = matched
+		}
+	}
+
+	return Decision{Target: implicitTarget, Closest: closest, Mismatches: closestMismatches}
+}
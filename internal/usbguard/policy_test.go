@@ -0,0 +1,253 @@
+package usbguard
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stegmannb/usbtree/internal/models"
+)
+
+// fakeSource serves string attributes and descriptor blobs from maps keyed
+// by port path.
+type fakeSource struct {
+	attrs       map[string]map[string]string
+	descriptors map[string][]byte
+}
+
+func (f *fakeSource) ReadAttr(portPath, attr string) (string, error) {
+	if value, ok := f.attrs[portPath][attr]; ok {
+		return value, nil
+	}
+	return "", fmt.Errorf("no attribute %s for %s", attr, portPath)
+}
+
+func (f *fakeSource) ReadDescriptors(portPath string) ([]byte, error) {
+	if data, ok := f.descriptors[portPath]; ok {
+		return data, nil
+	}
+	return nil, fmt.Errorf("no descriptors for %s", portPath)
+}
+
+func testTree() []*models.USBDevice {
+	root := &models.USBDevice{
+		VendorID: 0x1d6b, ProductID: 0x0002, ProductName: "2.0 root hub", PortPath: "usb1",
+		Interfaces: []models.Interface{{Class: 0x09}},
+	}
+	keyboard := &models.USBDevice{
+		VendorID: 0x046d, ProductID: 0xc52b, ProductName: "Unifying Receiver", PortPath: "1-2",
+		Interfaces: []models.Interface{
+			{Number: 0, Class: 0x03, SubClass: 0x01, Protocol: 0x01},
+			{Number: 1, Class: 0x03, SubClass: 0x01, Protocol: 0x02},
+		},
+	}
+	ftdi := &models.USBDevice{
+		VendorID: 0x0403, ProductID: 0x6001, ProductName: "FT232R USB UART", Serial: "A50285BI", PortPath: "1-3",
+		Interfaces: []models.Interface{{Class: 0xff, SubClass: 0xff, Protocol: 0xff}},
+	}
+	root.AddChild(keyboard)
+	root.AddChild(ftdi)
+	return []*models.USBDevice{root}
+}
+
+func TestAttributes(t *testing.T) {
+	device := testTree()[0].Children[1]
+
+	attrs := Attributes(device, nil)
+	if attrs.ID != "0403:6001" || attrs.Name != "FT232R USB UART" || attrs.Serial != "A50285BI" ||
+		attrs.ViaPort != "1-3" || attrs.Hash != "" {
+		t.Errorf("Unexpected attributes without source: %+v", attrs)
+	}
+	if len(attrs.Interfaces) != 1 || attrs.Interfaces[0] != "ff:ff:ff" {
+		t.Errorf("Unexpected interfaces: %v", attrs.Interfaces)
+	}
+
+	src := &fakeSource{
+		attrs:       map[string]map[string]string{"1-3": {"product": "FT232R", "serial": "A50285BI"}},
+		descriptors: map[string][]byte{"1-3": {0x12, 0x01, 0x00, 0x02}},
+	}
+	attrs = Attributes(device, src)
+	if attrs.Name != "FT232R" {
+		t.Errorf("Expected name from string descriptor, got %q", attrs.Name)
+	}
+	if attrs.Hash == "" {
+		t.Fatal("Expected a hash when descriptors are available")
+	}
+	if again := Attributes(device, src); again.Hash != attrs.Hash {
+		t.Error("Expected the hash to be stable")
+	}
+
+	src.attrs["1-3"]["serial"] = "OTHER"
+	if changed := Attributes(device, src); changed.Hash == attrs.Hash {
+		t.Error("Expected the hash to change with the serial number")
+	}
+}
+
+func TestGeneratePolicy(t *testing.T) {
+	rules := GeneratePolicy(models.NewTopology(testTree()), nil, Options{Hash: true, ViaPort: true})
+
+	expected := []string{
+		`allow id 1d6b:0002 name "2.0 root hub" via-port "usb1" with-interface 09:00:00`,
+		`allow id 046d:c52b name "Unifying Receiver" via-port "1-2" with-interface { 03:01:01 03:01:02 }`,
+		`allow id 0403:6001 serial "A50285BI" name "FT232R USB UART" via-port "1-3" with-interface ff:ff:ff`,
+	}
+	if len(rules) != len(expected) {
+		t.Fatalf("Expected %d rules, got %d:\n%s", len(expected), len(rules), strings.Join(rules, "\n"))
+	}
+	for i := range expected {
+		if rules[i] != expected[i] {
+			t.Errorf("Rule %d:\nexpected %s\ngot      %s", i, expected[i], rules[i])
+		}
+	}
+
+	rules = GeneratePolicy(models.NewTopology(testTree()), nil, Options{})
+	if strings.Contains(rules[1], "via-port") {
+		t.Errorf("Expected no via-port without the option, got %s", rules[1])
+	}
+}
+
+func TestGeneratePolicy_RoundTrip(t *testing.T) {
+	devices := testTree()
+	policy := strings.Join(GeneratePolicy(models.NewTopology(devices), nil, Options{ViaPort: true}), "\n")
+
+	rules, err := ParsePolicy(strings.NewReader(policy))
+	if err != nil {
+		t.Fatalf("Failed to parse generated policy: %v", err)
+	}
+
+	var all []*models.USBDevice
+	var collect func([]*models.USBDevice)
+	collect = func(devices []*models.USBDevice) {
+		for _, device := range devices {
+			all = append(all, device)
+			collect(device.Children)
+		}
+	}
+	collect(devices)
+
+	for i, device := range all {
+		decision := Decide(rules, Attributes(device, nil), "block")
+		if !decision.Allowed() || decision.Rule != rules[i] {
+			t.Errorf("Expected %s to be allowed by its own rule, got: %s", device.PortPath, decision.Explain())
+		}
+	}
+}
+
+func TestParsePolicy(t *testing.T) {
+	input := `# hardened workstation
+allow id 1d6b:* with-interface 09:00:*
+allow 046d:c52b with-interface all-of { 03:*:* }
+block name "Bad \"USB\"" if !rule-applied
+reject
+`
+	rules, err := ParsePolicy(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(rules) != 4 {
+		t.Fatalf("Expected 4 rules, got %d", len(rules))
+	}
+
+	if rules[0].Line != 2 || rules[0].Target != "allow" || len(rules[0].Conditions) != 2 {
+		t.Errorf("Unexpected first rule: %+v", rules[0])
+	}
+
+	second := rules[1].Conditions
+	if len(second) != 2 || second[0].Attribute != "id" || second[1].Operator != "all-of" ||
+		len(second[1].Values) != 1 || second[1].Values[0] != "03:*:*" {
+		t.Errorf("Unexpected second rule conditions: %+v", second)
+	}
+
+	third := rules[2].Conditions
+	if third[0].Values[0] != `Bad "USB"` || third[1].Attribute != "if" {
+		t.Errorf("Unexpected third rule conditions: %+v", third)
+	}
+
+	if rules[3].Target != "reject" || len(rules[3].Conditions) != 0 {
+		t.Errorf("Unexpected catch-all rule: %+v", rules[3])
+	}
+
+	for _, invalid := range []string{`permit id 0403:6001`, `allow name "unterminated`, `allow with-interface { 03:*:*`, `allow serial`} {
+		if _, err := ParsePolicy(strings.NewReader(invalid)); err == nil {
+			t.Errorf("Expected error for %q", invalid)
+		}
+	}
+}
+
+func TestDecide(t *testing.T) {
+	policy := `allow id 1d6b:*
+allow id 046d:c52b with-interface all-of { 03:*:* }
+block with-interface one-of { 08:*:* }
+allow id 0403:6001 serial "A50285BX" via-port "1-3"
+`
+	rules, err := ParsePolicy(strings.NewReader(policy))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	devices := testTree()
+	root, keyboard, ftdi := devices[0], devices[0].Children[0], devices[0].Children[1]
+
+	if decision := Decide(rules, Attributes(root, nil), "block"); !decision.Allowed() || decision.Rule.Line != 1 {
+		t.Errorf("Expected root hub to be allowed by line 1, got: %s", decision.Explain())
+	}
+	if decision := Decide(rules, Attributes(keyboard, nil), "block"); !decision.Allowed() || decision.Rule.Line != 2 {
+		t.Errorf("Expected receiver to be allowed by line 2, got: %s", decision.Explain())
+	}
+
+	decision := Decide(rules, Attributes(ftdi, nil), "block")
+	if decision.Allowed() || decision.Rule != nil {
+		t.Fatalf("Expected FTDI to fall through to the implicit policy, got: %s", decision.Explain())
+	}
+	if decision.Closest == nil || decision.Closest.Line != 4 {
+		t.Fatalf("Expected closest rule on line 4, got %+v", decision.Closest)
+	}
+	explanation := decision.Explain()
+	if !strings.Contains(explanation, "blocked: no rule matches") || !strings.Contains(explanation, `serial (rule "A50285BX", device "A50285BI")`) {
+		t.Errorf("Unexpected explanation: %s", explanation)
+	}
+
+	storage := &models.USBDevice{VendorID: 0x0781, ProductID: 0x5591, Interfaces: []models.Interface{{Class: 0x08, SubClass: 0x06, Protocol: 0x50}}}
+	if decision := Decide(rules, Attributes(storage, nil), "allow"); decision.Target != "block" || decision.Rule.Line != 3 {
+		t.Errorf("Expected mass storage to be blocked by line 3, got: %s", decision.Explain())
+	}
+
+	hashed, err := ParsePolicy(strings.NewReader(`allow id 0403:6001 hash "abc="`))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	decision = Decide(hashed, Attributes(ftdi, nil), "block")
+	if !decision.Allowed() || !strings.Contains(decision.Explain(), "not verified: hash") {
+		t.Errorf("Expected hash to be reported as unverified, got: %s", decision.Explain())
+	}
+}
+
+func TestCondition_Operators(t *testing.T) {
+	device := []string{"03:01:01", "03:01:02"}
+
+	tests := []struct {
+		operator string
+		values   []string
+		expected bool
+	}{
+		{operator: "", values: []string{"03:*:*"}, expected: true},
+		{operator: "", values: []string{"03:01:01"}, expected: false},
+		{operator: "equals", values: []string{"03:01:01", "03:01:02"}, expected: true},
+		{operator: "equals-ordered", values: []string{"03:01:02", "03:01:01"}, expected: false},
+		{operator: "all-of", values: []string{"03:01:01"}, expected: true},
+		{operator: "all-of", values: []string{"03:01:01", "08:*:*"}, expected: false},
+		{operator: "one-of", values: []string{"08:*:*", "03:01:02"}, expected: true},
+		{operator: "none-of", values: []string{"08:*:*", "e0:*:*"}, expected: true},
+		{operator: "none-of", values: []string{"03:01:*"}, expected: false},
+		{operator: "match-all", values: []string{"03:01:*", "08:*:*"}, expected: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.operator+" "+strings.Join(tt.values, " "), func(t *testing.T) {
+			condition := Condition{Attribute: "with-interface", Operator: tt.operator, Values: tt.values}
+			if result := condition.matches(device, matchPattern); result != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+}
@@ -0,0 +1,341 @@
+package usbguard
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Condition is one attribute of a rule, e.g. with-interface one-of { 03:*:* }.
+type Condition struct {
+	Attribute string
+	Operator  string // empty when the rule did not name a set operator
+	Values    []string
+}
+
+// Rule is one line of a USBGuard rules.conf.
+type Rule struct {
+	Line       int
+	Text       string
+	Target     string
+	Conditions []Condition
+}
+
+var targets = map[string]bool{
+	"allow":  true,
+	"block":  true,
+	"reject": true,
+	"match":  true,
+	"device": true,
+}
+
+var setOperators = map[string]bool{
+	"all-of":         true,
+	"one-of":         true,
+	"none-of":        true,
+	"equals":         true,
+	"equals-ordered": true,
+	"match-all":      true,
+}
+
+// ParsePolicy reads a USBGuard rules file.
+func ParsePolicy(r io.Reader) ([]*Rule, error) {
+	var rules []*Rule
+	scanner := bufio.NewScanner(r)
+
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		rule, err := parseRule(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+		rule.Line = lineNumber
+		rules = append(rules, rule)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return rules, nil
+}
+
+func parseRule(text string) (*Rule, error) {
+	tokens, err := tokenize(text)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(tokens) == 0 || !targets[tokens[0]] {
+		return nil, fmt.Errorf("rule must start with a target (allow, block, reject, match or device)")
+	}
+
+	rule := &Rule{Text: text, Target: tokens[0]}
+	tokens = tokens[1:]
+
+	// A bare "allow 0403:6001" is shorthand for "allow id 0403:6001"
+	if len(tokens) > 0 && strings.Contains(tokens[0], ":") && !strings.HasPrefix(tokens[0], `"`) {
+		tokens = append([]string{"id"}, tokens...)
+	}
+
+	for len(tokens) > 0 {
+		condition := Condition{Attribute: tokens[0]}
+		tokens = tokens[1:]
+
+		// Conditions are free-form expressions usbtree does not evaluate
+		if condition.Attribute == "if" {
+			condition.Values = tokens
+			rule.Conditions = append(rule.Conditions, condition)
+			break
+		}
+
+		if len(tokens) > 0 && setOperators[tokens[0]] {
+			condition.Operator = tokens[0]
+			tokens = tokens[1:]
+		}
+
+		if len(tokens) == 0 {
+			return nil, fmt.Errorf("missing value for %s", condition.Attribute)
+		}
+
+		if tokens[0] == "{" {
+			end := -1
+			for i, token := range tokens {
+				if token == "}" {
+					end = i
+					break
+				}
+			}
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated set for %s", condition.Attribute)
+			}
+			for _, token := range tokens[1:end] {
+				condition.Values = append(condition.Values, unquote(token))
+			}
+			tokens = tokens[end+1:]
+		} else {
+			condition.Values = []string{unquote(tokens[0])}
+			tokens = tokens[1:]
+		}
+
+		rule.Conditions = append(rule.Conditions, condition)
+	}
+
+	return rule, nil
+}
+
+// tokenize splits a rule into words, quoted strings (kept with their quotes)
+// and braces.
+func tokenize(text string) ([]string, error) {
+	var tokens []string
+	for i := 0; i < len(text); {
+		switch c := text[i]; {
+		case c == ' ' || c == '\t':
+			i++
+		case c == '{' || c == '}':
+			tokens = append(tokens, string(c))
+			i++
+		case c == '"':
+			end := i + 1
+			for end < len(text) && text[end] != '"' {
+				if text[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(text) {
+				return nil, fmt.Errorf("unterminated string")
+			}
+			tokens = append(tokens, text[i:end+1])
+			i = end + 1
+		default:
+			end := i
+			for end < len(text) && !strings.ContainsRune(" \t{}\"", rune(text[end])) {
+				end++
+			}
+			tokens = append(tokens, text[i:end])
+			i = end
+		}
+	}
+	return tokens, nil
+}
+
+func unquote(token string) string {
+	if len(token) < 2 || token[0] != '"' {
+		return token
+	}
+	var b strings.Builder
+	for i := 1; i < len(token)-1; i++ {
+		if token[i] == '\\' && i+1 < len(token)-1 {
+			i++
+		}
+		b.WriteByte(token[i])
+	}
+	return b.String()
+}
+
+// decides reports whether a matching rule determines the device's fate;
+// match and device rules only attach actions.
+func (r *Rule) decides() bool {
+	return r.Target == "allow" || r.Target == "block" || r.Target == "reject"
+}
+
+// Evaluate compares the device against every condition of the rule. It
+// returns a description of each mismatching attribute and the attributes
+// that cannot be checked from the device tree.
+func (r *Rule) Evaluate(attrs DeviceAttributes) (mismatches, unchecked []string) {
+	for _, condition := range r.Conditions {
+		var values []string
+		match := matchExact
+
+		switch condition.Attribute {
+		case "id":
+			values, match = []string{attrs.ID}, matchPattern
+		case "name":
+			values = []string{attrs.Name}
+		case "serial":
+			values = []string{attrs.Serial}
+		case "via-port":
+			values = []string{attrs.ViaPort}
+		case "with-interface":
+			values, match = attrs.Interfaces, matchPattern
+		case "hash":
+			if attrs.Hash == "" {
+				unchecked = append(unchecked, condition.Attribute)
+				continue
+			}
+			values = []string{attrs.Hash}
+		case "label":
+			continue
+		default:
+			unchecked = append(unchecked, condition.Attribute)
+			continue
+		}
+
+		if !condition.matches(values, match) {
+			mismatches = append(mismatches, fmt.Sprintf("%s (rule %s, device %s)",
+				condition.Attribute, condition.describe(), describeValues(values)))
+		}
+	}
+	return mismatches, unchecked
+}
+
+func (c Condition) describe() string {
+	values := describeValues(c.Values)
+	if c.Operator != "" {
+		return c.Operator + " " + values
+	}
+	return values
+}
+
+func describeValues(values []string) string {
+	quoted := make([]string, len(values))
+	for i, value := range values {
+		quoted[i] = fmt.Sprintf("%q", value)
+	}
+	if len(quoted) == 1 {
+		return quoted[0]
+	}
+	return "{ " + strings.Join(quoted, " ") + " }"
+}
+
+// matches applies the condition's set operator to the device's values.
+// Without an operator the sets must be equal.
+func (c Condition) matches(device []string, match func(pattern, value string) bool) bool {
+	anyMatch := func(pattern string) bool {
+		for _, value := range device {
+			if match(pattern, value) {
+				return true
+			}
+		}
+		return false
+	}
+	anyPattern := func(value string) bool {
+		for _, pattern := range c.Values {
+			if match(pattern, value) {
+				return true
+			}
+		}
+		return false
+	}
+
+	switch c.Operator {
+	case "all-of":
+		for _, pattern := range c.Values {
+			if !anyMatch(pattern) {
+				return false
+			}
+		}
+		return true
+	case "one-of":
+		for _, pattern := range c.Values {
+			if anyMatch(pattern) {
+				return true
+			}
+		}
+		return false
+	case "none-of":
+		for _, pattern := range c.Values {
+			if anyMatch(pattern) {
+				return false
+			}
+		}
+		return true
+	case "match-all":
+		for _, value := range device {
+			if !anyPattern(value) {
+				return false
+			}
+		}
+		return true
+	case "equals-ordered":
+		if len(device) != len(c.Values) {
+			return false
+		}
+		for i := range device {
+			if !match(c.Values[i], device[i]) {
+				return false
+			}
+		}
+		return true
+	default:
+		for _, pattern := range c.Values {
+			if !anyMatch(pattern) {
+				return false
+			}
+		}
+		for _, value := range device {
+			if !anyPattern(value) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+func matchExact(pattern, value string) bool {
+	return pattern == value
+}
+
+// matchPattern compares colon-separated IDs (0403:6001, 03:01:*) where any
+// component may be the wildcard *.
+func matchPattern(pattern, value string) bool {
+	patternParts := strings.Split(pattern, ":")
+	valueParts := strings.Split(value, ":")
+	if len(patternParts) != len(valueParts) {
+		return false
+	}
+	for i := range patternParts {
+		if patternParts[i] != "*" && !strings.EqualFold(patternParts[i], valueParts[i]) {
+			return false
+		}
+	}
+	return true
+}
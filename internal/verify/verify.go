@@ -0,0 +1,360 @@
+// Package verify compares a device tree against an expected topology
+// described in a YAML or JSON spec, so test stations can assert that the
+// right devices are on the right ports.
+package verify
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/stegmannb/usbtree/internal/models"
+	"gopkg.in/yaml.v3"
+)
+
+// Spec is the expected topology.
+type Spec struct {
+	// Strict also reports connected devices the spec does not list. Root
+	// hubs are never reported.
+	Strict  bool          `yaml:"strict" json:"strict"`
+	Devices []Expectation `yaml:"devices" json:"devices"`
+}
+
+// Expectation describes one expected device. Every field that is set must
+// match; values may contain the wildcards * and ?.
+type Expectation struct {
+	// Port is the port path, e.g. 1-2.3, at the top level of the spec, and
+	// the port number on the parent hub for children. Without it the
+	// device may be anywhere.
+	Port string `yaml:"port" json:"port,omitempty"`
+	// ID is vendor:product, e.g. 0403:6001 or 0403:*.
+	ID     string `yaml:"id" json:"id,omitempty"`
+	Serial string `yaml:"serial" json:"serial,omitempty"`
+	// Name matches the label, the product or the vendor name, ignoring
+	// case.
+	Name    string `yaml:"name" json:"name,omitempty"`
+	Vendor  string `yaml:"vendor" json:"vendor,omitempty"`
+	Product string `yaml:"product" json:"product,omitempty"`
+	Class   string `yaml:"class" json:"class,omitempty"`
+	// Speed is a speed name such as Full or High, or a rate such as 12M.
+	Speed string `yaml:"speed" json:"speed,omitempty"`
+	// Driver matches the driver of any interface.
+	Driver string `yaml:"driver" json:"driver,omitempty"`
+	// Optional devices are reported but not required.
+	Optional bool          `yaml:"optional" json:"optional,omitempty"`
+	Children []Expectation `yaml:"children" json:"children,omitempty"`
+}
+
+// ParseSpec reads a spec in YAML or JSON, rejecting unknown fields so typos
+// do not silently match everything.
+func ParseSpec(r io.Reader) (*Spec, error) {
+	decoder := yaml.NewDecoder(r)
+	decoder.KnownFields(true)
+
+	var spec Spec
+	if err := decoder.Decode(&spec); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	if len(spec.Devices) == 0 {
+		return nil, fmt.Errorf("spec lists no devices")
+	}
+	if err := validate(spec.Devices, "devices"); err != nil {
+		return nil, err
+	}
+	return &spec, nil
+}
+
+func validate(expectations []Expectation, prefix string) error {
+	for i, e := range expectations {
+		at := fmt.Sprintf("%s[%d]", prefix, i)
+		if e.String() == "" {
+			return fmt.Errorf("%s: no attributes to match", at)
+		}
+		for _, pattern := range []string{e.Port, e.ID, e.Serial, e.Name, e.Vendor, e.Product, e.Class, e.Speed, e.Driver} {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("%s: invalid pattern %q", at, pattern)
+			}
+		}
+		if err := validate(e.Children, at+".children"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// String lists the attributes the expectation matches on.
+func (e Expectation) String() string {
+	var parts []string
+	for _, field := range []struct{ name, value string }{
+		{"port", e.Port}, {"id", e.ID}, {"serial", e.Serial}, {"name", e.Name},
+		{"vendor", e.Vendor}, {"product", e.Product}, {"class", e.Class},
+		{"speed", e.Speed}, {"driver", e.Driver},
+	} {
+		if field.value != "" {
+			parts = append(parts, field.name+"="+field.value)
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// fixed reports whether the expectation names exactly one position.
+func (e Expectation) fixed() bool {
+	return e.Port != "" && !strings.ContainsAny(e.Port, "*?[")
+}
+
+// atPort reports whether the device is at the position of the expectation,
+// by port path at the top level and by port number below a parent.
+func (e Expectation) atPort(device *models.USBDevice, child bool) bool {
+	if e.Port == "" {
+		return true
+	}
+	if child {
+		return glob(e.Port, strconv.Itoa(device.Port))
+	}
+	return glob(e.Port, device.PortPath)
+}
+
+// glob matches a value against a pattern with the wildcards of path.Match.
+func glob(pattern, value string) bool {
+	matched, _ := path.Match(pattern, value)
+	return matched
+}
+
+// globFold is glob ignoring case.
+func globFold(pattern, value string) bool {
+	return glob(strings.ToLower(pattern), strings.ToLower(value))
+}
+
+// Difference is an attribute of a device that does not match.
+type Difference struct {
+	Field    string `json:"field"`
+	Expected string `json:"expected"`
+	Actual   string `json:"actual"`
+}
+
+// differences compares every attribute but the port with the device.
+func (e Expectation) differences(device *models.USBDevice) []Difference {
+	var diffs []Difference
+	check := func(field, expected, actual string, match bool) {
+		if expected != "" && !match {
+			diffs = append(diffs, Difference{Field: field, Expected: expected, Actual: actual})
+		}
+	}
+
+	check("id", e.ID, device.GetIDString(), globFold(e.ID, device.GetIDString()))
+	check("serial", e.Serial, device.Serial, glob(e.Serial, device.Serial))
+	check("name", e.Name, device.GetDisplayName(),
+		globFold(e.Name, device.ProductName) || globFold(e.Name, device.VendorName) || globFold(e.Name, device.Label))
+	check("vendor", e.Vendor, device.VendorName, globFold(e.Vendor, device.VendorName))
+	check("product", e.Product, device.ProductName, globFold(e.Product, device.ProductName))
+	check("class", e.Class, device.Class, globFold(e.Class, device.Class))
+	check("speed", e.Speed, device.Speed, speedMatches(e.Speed, device))
+
+	var drivers []string
+	driverMatch := false
+	for _, iface := range device.Interfaces {
+		if iface.Driver != "" {
+			drivers = append(drivers, iface.Driver)
+			driverMatch = driverMatch || glob(e.Driver, iface.Driver)
+		}
+	}
+	check("driver", e.Driver, strings.Join(drivers, ","), driverMatch)
+
+	return diffs
+}
+
+// speedMatches compares a speed name such as Full, or a rate such as 12M,
+// with the speed of the device.
+func speedMatches(expected string, device *models.USBDevice) bool {
+	if mbps, err := models.ParseSpeed(expected); err == nil {
+		return device.SpeedMbps() == mbps
+	}
+	name, _, _ := strings.Cut(device.Speed, " ")
+	return globFold(expected, name) || globFold(expected, device.Speed)
+}
+
+// Status is the outcome for one expected or connected device.
+type Status string
+
+const (
+	// StatusOK is an expected device that is connected as described.
+	StatusOK Status = "ok"
+	// StatusDifferent is a device at the expected port that does not
+	// match the other attributes.
+	StatusDifferent Status = "different"
+	// StatusMissing is a required device that is not connected.
+	StatusMissing Status = "missing"
+	// StatusOptional is an optional device that is not connected.
+	StatusOptional Status = "optional"
+	// StatusUnexpected is a connected device a strict spec does not list.
+	StatusUnexpected Status = "unexpected"
+)
+
+// Failed reports whether the status fails verification.
+func (s Status) Failed() bool {
+	return s == StatusDifferent || s == StatusMissing || s == StatusUnexpected
+}
+
+// DeviceRef identifies a connected device in a report.
+type DeviceRef struct {
+	PortPath string `json:"port_path,omitempty"`
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+}
+
+func newDeviceRef(device *models.USBDevice) *DeviceRef {
+	return &DeviceRef{PortPath: device.PortPath, ID: device.GetIDString(), Name: device.GetDisplayName()}
+}
+
+// Entry is the outcome for one expectation of the spec, or for one
+// unexpected device.
+type Entry struct {
+	Status Status `json:"status"`
+	// Spec locates the expectation, e.g. devices[0].children[1].
+	Spec        string       `json:"spec,omitempty"`
+	Expected    string       `json:"expected,omitempty"`
+	Device      *DeviceRef   `json:"device,omitempty"`
+	Differences []Difference `json:"differences,omitempty"`
+}
+
+// Report is the result of Verify, in the order of the spec followed by the
+// unexpected devices.
+type Report struct {
+	OK      bool    `json:"ok"`
+	Entries []Entry `json:"entries"`
+}
+
+// Count returns how many entries have the status.
+func (r *Report) Count(status Status) int {
+	count := 0
+	for _, entry := range r.Entries {
+		if entry.Status == status {
+			count++
+		}
+	}
+	return count
+}
+
+// verifier assigns connected devices to expectations. Every device
+// fulfils at most one expectation.
+type verifier struct {
+	used    map[*models.USBDevice]bool
+	entries []Entry
+}
+
+// Verify compares the topology with the spec. Expectations are matched in
+// the order of the spec, so specific ones should come before wildcards.
+func Verify(spec *Spec, topology *models.Topology) *Report {
+	v := &verifier{used: make(map[*models.USBDevice]bool)}
+	v.expect(spec.Devices, "devices", topology.All(), false)
+
+	if spec.Strict {
+		for _, device := range topology.All() {
+			if !v.used[device] && device.Depth > 0 {
+				v.entries = append(v.entries, Entry{Status: StatusUnexpected, Device: newDeviceRef(device)})
+			}
+		}
+	}
+
+	report := &Report{OK: true, Entries: v.entries}
+	for _, entry := range report.Entries {
+		if entry.Status.Failed() {
+			report.OK = false
+		}
+	}
+	return report
+}
+
+// expect matches expectations against a pool of devices: all devices at the
+// top level, the children of the parent's device below it.
+func (v *verifier) expect(expectations []Expectation, prefix string, pool []*models.USBDevice, child bool) {
+	for i, e := range expectations {
+		at := fmt.Sprintf("%s[%d]", prefix, i)
+		device := v.pick(e, pool, child)
+
+		entry := Entry{Spec: at, Expected: e.String()}
+		switch {
+		case device == nil && e.Optional:
+			entry.Status = StatusOptional
+		case device == nil:
+			entry.Status = StatusMissing
+		default:
+			v.used[device] = true
+			entry.Device = newDeviceRef(device)
+			entry.Differences = e.differences(device)
+			entry.Status = StatusOK
+			if len(entry.Differences) > 0 {
+				entry.Status = StatusDifferent
+			}
+		}
+		v.entries = append(v.entries, entry)
+
+		if device != nil {
+			v.expect(e.Children, at+".children", device.Children, true)
+		} else {
+			v.skip(e.Children, at+".children")
+		}
+	}
+}
+
+// skip reports the children of an expectation without a device as missing.
+func (v *verifier) skip(expectations []Expectation, prefix string) {
+	for i, e := range expectations {
+		at := fmt.Sprintf("%s[%d]", prefix, i)
+		status := StatusMissing
+		if e.Optional {
+			status = StatusOptional
+		}
+		v.entries = append(v.entries, Entry{Status: status, Spec: at, Expected: e.String()})
+		v.skip(e.Children, at+".children")
+	}
+}
+
+// pick returns the device for an expectation. A fixed port names the device
+// even if its other attributes differ, so the report can tell what is
+// plugged in instead. Otherwise the device must match all attributes, and
+// of several candidates the one whose children fit the spec best is taken.
+func (v *verifier) pick(e Expectation, pool []*models.USBDevice, child bool) *models.USBDevice {
+	var candidates []*models.USBDevice
+	for _, device := range pool {
+		if v.used[device] || !e.atPort(device, child) {
+			continue
+		}
+		if e.fixed() {
+			return device
+		}
+		if len(e.differences(device)) == 0 {
+			candidates = append(candidates, device)
+		}
+	}
+	if len(candidates) <= 1 || len(e.Children) == 0 {
+		if len(candidates) == 0 {
+			return nil
+		}
+		return candidates[0]
+	}
+
+	best, fewest := candidates[0], -1
+	for _, candidate := range candidates {
+		trial := &verifier{used: make(map[*models.USBDevice]bool)}
+		for device := range v.used {
+			trial.used[device] = true
+		}
+		trial.used[candidate] = true
+		trial.expect(e.Children, "", candidate.Children, true)
+
+		failed := 0
+		for _, entry := range trial.entries {
+			if entry.Status.Failed() {
+				failed++
+			}
+		}
+		if fewest < 0 || failed < fewest {
+			best, fewest = candidate, failed
+		}
+	}
+	return best
+}
@@ -0,0 +1,151 @@
+package verify
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stegmannb/usbtree/internal/models"
+)
+
+const stationSpec = `
+strict: true
+devices:
+  - port: 1-2
+    class: Hub
+    children:
+      - port: 1
+        id: 10c4:ea60
+        speed: Full
+      - port: 2
+        id: 0403:6001
+        optional: true
+  - id: "0403:*"
+    serial: A50285BI
+  - name: "*receiver*"
+    optional: true
+`
+
+func station(devices ...*models.USBDevice) *models.Topology {
+	hub := &models.USBDevice{VendorID: 0x1a40, ProductID: 0x0101, Class: "Hub", PortPath: "1-2", Port: 2,
+		Children: []*models.USBDevice{
+			{VendorID: 0x10c4, ProductID: 0xea60, PortPath: "1-2.1", Port: 1, Speed: "Full (12 Mbps)"},
+		}}
+	root := &models.USBDevice{VendorID: 0x1d6b, ProductID: 0x0002, Class: "Hub", PortPath: "usb1",
+		Children: append([]*models.USBDevice{hub}, devices...)}
+	return models.NewTopology([]*models.USBDevice{root})
+}
+
+func parse(t *testing.T, spec string) *Spec {
+	t.Helper()
+	parsed, err := ParseSpec(strings.NewReader(spec))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return parsed
+}
+
+func statuses(report *Report) []string {
+	var result []string
+	for _, entry := range report.Entries {
+		result = append(result, entry.Spec+" "+string(entry.Status))
+	}
+	return result
+}
+
+func TestVerify(t *testing.T) {
+	ftdi := &models.USBDevice{VendorID: 0x0403, ProductID: 0x6015, Serial: "A50285BI", PortPath: "1-3", Port: 3}
+	report := Verify(parse(t, stationSpec), station(ftdi))
+
+	expected := []string{
+		"devices[0] ok",
+		"devices[0].children[0] ok",
+		"devices[0].children[1] optional",
+		"devices[1] ok",
+		"devices[2] optional",
+	}
+	if got := statuses(report); strings.Join(got, ";") != strings.Join(expected, ";") {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+	if !report.OK {
+		t.Error("Expected the station to verify")
+	}
+	if device := report.Entries[3].Device; device == nil || device.PortPath != "1-3" {
+		t.Errorf("Expected the FTDI at 1-3, got %+v", device)
+	}
+}
+
+func TestVerifyMismatches(t *testing.T) {
+	topology := station(&models.USBDevice{VendorID: 0x046d, ProductID: 0xc52b, PortPath: "1-4", Port: 4})
+	// The CP2102 enumerated at High speed
+	topology.FindByPortPath("1-2.1").Speed = "High (480 Mbps)"
+
+	report := Verify(parse(t, stationSpec), topology)
+	expected := []string{
+		"devices[0] ok",
+		"devices[0].children[0] different",
+		"devices[0].children[1] optional",
+		"devices[1] missing",
+		"devices[2] optional",
+		" unexpected",
+	}
+	if got := statuses(report); strings.Join(got, ";") != strings.Join(expected, ";") {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+	if report.OK {
+		t.Error("Expected verification to fail")
+	}
+
+	diffs := report.Entries[1].Differences
+	if len(diffs) != 1 || diffs[0].Field != "speed" || diffs[0].Actual != "High (480 Mbps)" {
+		t.Errorf("Expected a speed difference, got %+v", diffs)
+	}
+	if report.Count(StatusUnexpected) != 1 || report.Entries[5].Device.PortPath != "1-4" {
+		t.Errorf("Expected the receiver at 1-4 to be unexpected, got %+v", report.Entries[5])
+	}
+}
+
+func TestVerifyPicksBestCandidate(t *testing.T) {
+	// Two identical hubs; only the second has the expected adapter
+	hub := func(portPath string, port int, children ...*models.USBDevice) *models.USBDevice {
+		return &models.USBDevice{VendorID: 0x1a40, ProductID: 0x0101, Class: "Hub", PortPath: portPath, Port: port, Children: children}
+	}
+	root := &models.USBDevice{PortPath: "usb1", Class: "Hub", Children: []*models.USBDevice{
+		hub("1-1", 1),
+		hub("1-2", 2, &models.USBDevice{VendorID: 0x10c4, ProductID: 0xea60, PortPath: "1-2.3", Port: 3}),
+	}}
+	spec := parse(t, `
+devices:
+  - id: 1a40:0101
+    children:
+      - port: 3
+        id: 10c4:ea60
+`)
+	report := Verify(spec, models.NewTopology([]*models.USBDevice{root}))
+	if !report.OK || report.Entries[0].Device.PortPath != "1-2" {
+		t.Errorf("Expected the hub at 1-2 to be picked, got %+v", report.Entries)
+	}
+}
+
+func TestParseSpecErrors(t *testing.T) {
+	tests := map[string]string{
+		"unknown field": "devices:\n  - vendorid: 0403\n",
+		"no devices":    "strict: true\n",
+		"empty device":  "devices:\n  - optional: true\n",
+		"bad pattern":   "devices:\n  - name: \"[\"\n",
+	}
+	for name, spec := range tests {
+		if _, err := ParseSpec(strings.NewReader(spec)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestParseSpecJSON(t *testing.T) {
+	spec := parse(t, `{"devices": [{"port": "1-2", "children": [{"port": 1, "speed": "12M"}]}]}`)
+	if spec.Devices[0].Children[0].Port != "1" {
+		t.Errorf("Expected port 1, got %q", spec.Devices[0].Children[0].Port)
+	}
+	if report := Verify(spec, station()); !report.OK {
+		t.Errorf("Expected the station to verify, got %v", statuses(report))
+	}
+}
@@ -0,0 +1,127 @@
+// Package wait blocks until a device reaches a state, so scripts can wait
+// for a device to be plugged in, bound to its driver or enumerated at full
+// speed before using it.
+package wait
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/stegmannb/usbtree/internal/hotplug"
+	"github.com/stegmannb/usbtree/internal/models"
+)
+
+// State is what a Condition requires of the device.
+type State string
+
+const (
+	// StatePresent holds once a matching device is connected.
+	StatePresent State = "present"
+	// StateAbsent holds while no matching device is connected.
+	StateAbsent State = "absent"
+	// StateBound holds once a matching device has a driver bound to each
+	// of its interfaces.
+	StateBound State = "bound"
+	// StateSpeed holds once a matching device is connected at least at
+	// Condition.MinMbps.
+	StateSpeed State = "speed"
+)
+
+// Condition is a state to wait for, as given to usbtree wait --for.
+type Condition struct {
+	State   State
+	MinMbps float64
+}
+
+// ParseCondition parses present, absent, bound or speed>=N, where N is a
+// bit rate such as 480M, 5G or 480Mbps, in Mbit/s without a unit.
+func ParseCondition(s string) (Condition, error) {
+	s = strings.TrimSpace(s)
+	switch State(s) {
+	case StatePresent, StateAbsent, StateBound:
+		return Condition{State: State(s)}, nil
+	}
+
+	rate, found := strings.CutPrefix(s, "speed>=")
+	if !found {
+		return Condition{}, fmt.Errorf("invalid condition %q: expected present, absent, bound or speed>=N", s)
+	}
+	mbps, err := models.ParseSpeed(rate)
+	if err != nil {
+		return Condition{}, fmt.Errorf("invalid condition %q: %w", s, err)
+	}
+	return Condition{State: StateSpeed, MinMbps: mbps}, nil
+}
+
+func (c Condition) String() string {
+	if c.State == StateSpeed {
+		return fmt.Sprintf("speed>=%gM", c.MinMbps)
+	}
+	return string(c.State)
+}
+
+// Bound reports whether the device has interfaces and a driver bound to
+// every one of them.
+func Bound(device *models.USBDevice) bool {
+	if len(device.Interfaces) == 0 {
+		return false
+	}
+	for _, iface := range device.Interfaces {
+		if iface.Driver == "" {
+			return false
+		}
+	}
+	return true
+}
+
+// Check reports whether the condition holds in the topology for the
+// devices sel matches, and returns the first device it holds for. For
+// StateAbsent the device is always nil.
+func (c Condition) Check(topology *models.Topology, sel models.Selector) (*models.USBDevice, bool) {
+	matches := topology.Find(sel.Matches)
+	if c.State == StateAbsent {
+		return nil, len(matches) == 0
+	}
+	for _, device := range matches {
+		switch c.State {
+		case StatePresent:
+			return device, true
+		case StateBound:
+			if Bound(device) {
+				return device, true
+			}
+		case StateSpeed:
+			if device.SpeedMbps() >= c.MinMbps {
+				return device, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// Until checks the condition against every update from hotplug.Watch until
+// it holds, and returns the device it holds for. Failed enumerations are
+// skipped, as the next one may succeed. It returns the error of ctx if ctx
+// is done first.
+func Until(ctx context.Context, updates <-chan hotplug.Update, sel models.Selector, cond Condition) (*models.USBDevice, error) {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case update, ok := <-updates:
+			if !ok {
+				if err := ctx.Err(); err != nil {
+					return nil, err
+				}
+				return nil, fmt.Errorf("device updates ended")
+			}
+			if update.Err != nil {
+				continue
+			}
+			if device, ok := cond.Check(update.Topology, sel); ok {
+				return device, nil
+			}
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package wait
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stegmannb/usbtree/internal/hotplug"
+	"github.com/stegmannb/usbtree/internal/models"
+)
+
+// adapter is the device the tests wait for, connected at speed, with an
+// interface for each driver, "" for one no driver is bound to.
+func adapter(speed string, drivers ...string) *models.USBDevice {
+	device := &models.USBDevice{VendorID: 0x0403, ProductID: 0x6001, PortPath: "1-2", Speed: speed}
+	for i, driver := range drivers {
+		device.Interfaces = append(device.Interfaces, models.Interface{Number: i, Driver: driver})
+	}
+	return device
+}
+
+// connected returns the topology with only the given devices connected.
+func connected(devices ...*models.USBDevice) *models.Topology {
+	return models.NewTopology(devices)
+}
+
+func TestParseCondition(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected Condition
+	}{
+		{"present", Condition{State: StatePresent}},
+		{"absent", Condition{State: StateAbsent}},
+		{"bound", Condition{State: StateBound}},
+		{"speed>=480M", Condition{State: StateSpeed, MinMbps: 480}},
+		{"speed>=480Mbps", Condition{State: StateSpeed, MinMbps: 480}},
+		{"speed>=5G", Condition{State: StateSpeed, MinMbps: 5000}},
+		{"speed>=12", Condition{State: StateSpeed, MinMbps: 12}},
+	}
+	for _, tt := range tests {
+		got, err := ParseCondition(tt.input)
+		if err != nil {
+			t.Errorf("ParseCondition(%q): %v", tt.input, err)
+			continue
+		}
+		if got != tt.expected {
+			t.Errorf("ParseCondition(%q) = %+v, expected %+v", tt.input, got, tt.expected)
+		}
+	}
+
+	for _, input := range []string{"", "attached", "speed>480M", "speed>=fast", "speed>=0"} {
+		if _, err := ParseCondition(input); err == nil {
+			t.Errorf("Expected an error for %q", input)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	sel, _ := models.ParseSelector("0403:6001")
+	tests := []struct {
+		name     string
+		cond     string
+		topology *models.Topology
+		expected bool
+	}{
+		{"present", "present", connected(adapter("", "")), true},
+		{"not present", "present", connected(), false},
+		{"absent", "absent", connected(), true},
+		{"not absent", "absent", connected(adapter("", "")), false},
+		{"bound", "bound", connected(adapter("", "ftdi_sio")), true},
+		{"unbound", "bound", connected(adapter("", "")), false},
+		{"partly bound", "bound", connected(adapter("", "cdc_acm", "")), false},
+		{"no interfaces", "bound", connected(adapter("")), false},
+		{"fast enough", "speed>=12M", connected(adapter("Full (12 Mbps)")), true},
+		{"too slow", "speed>=480M", connected(adapter("Full (12 Mbps)")), false},
+		{"unknown speed", "speed>=1M", connected(adapter("")), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cond, err := ParseCondition(tt.cond)
+			if err != nil {
+				t.Fatal(err)
+			}
+			device, ok := cond.Check(tt.topology, sel)
+			if ok != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, ok)
+			}
+			if ok && cond.State != StateAbsent && device == nil {
+				t.Error("Expected the matching device")
+			}
+		})
+	}
+}
+
+func TestUntil(t *testing.T) {
+	sel, _ := models.ParseSelector("1-2")
+	cond := Condition{State: StateBound}
+	updates := make(chan hotplug.Update, 4)
+	updates <- hotplug.Update{Topology: connected()}
+	updates <- hotplug.Update{Err: errors.New("lsusb failed")}
+	updates <- hotplug.Update{Topology: connected(adapter("Full (12 Mbps)", ""))}
+	updates <- hotplug.Update{Topology: connected(adapter("Full (12 Mbps)", "ftdi_sio"))}
+
+	device, err := Until(context.Background(), updates, sel, cond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if device == nil || device.Interfaces[0].Driver != "ftdi_sio" {
+		t.Errorf("Expected the bound device, got %+v", device)
+	}
+}
+
+func TestUntilTimeout(t *testing.T) {
+	sel, _ := models.ParseSelector("1-2")
+	updates := make(chan hotplug.Update, 1)
+	updates <- hotplug.Update{Topology: connected()}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := Until(ctx, updates, sel, Condition{State: StatePresent}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the deadline to be exceeded, got %v", err)
+	}
+}
@@ -0,0 +1,212 @@
+package usbtree
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/stegmannb/usbtree/internal/api"
+	"github.com/stegmannb/usbtree/internal/hotplug"
+)
+
+type (
+	// Event is a device attached, detached or changed, as streamed by
+	// Client.Events.
+	Event = hotplug.Event
+	// EventAction is what happened to the device of an Event.
+	EventAction = hotplug.Action
+	// Health is the state of a usbtree serve daemon.
+	Health = api.Health
+)
+
+// Event actions.
+const (
+	EventAdd    = hotplug.ActionAdd
+	EventRemove = hotplug.ActionRemove
+	EventChange = hotplug.ActionChange
+)
+
+// ErrNoDevice is returned by Client.Device when no device is connected at
+// the port path.
+var ErrNoDevice = errors.New("no device at port path")
+
+// Client queries the HTTP API of usbtree serve --listen.
+type Client struct {
+	base string
+	http *http.Client
+}
+
+// NewClient returns a client for a daemon listening on address, given as
+// to --listen: unix:/run/usbtree.sock or host:port. An http:// URL is
+// accepted as well.
+func NewClient(address string) (*Client, error) {
+	if strings.HasPrefix(address, "http://") || strings.HasPrefix(address, "https://") {
+		if _, err := url.Parse(address); err != nil {
+			return nil, fmt.Errorf("invalid address %q: %w", address, err)
+		}
+		return &Client{base: strings.TrimSuffix(address, "/"), http: &http.Client{}}, nil
+	}
+
+	network, addr := api.SplitAddress(address)
+	if addr == "" {
+		return nil, fmt.Errorf("invalid address %q", address)
+	}
+	if network == "tcp" {
+		return &Client{base: "http://" + addr, http: &http.Client{}}, nil
+	}
+
+	// Requests name a placeholder host and are all dialed to the socket
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, "unix", addr)
+		},
+	}
+	return &Client{base: "http://usbtree", http: &http.Client{Transport: transport}}, nil
+}
+
+// get sends a GET request and returns the response if its status is one of
+// accepted, closing it otherwise.
+func (c *Client) get(ctx context.Context, path string, accepted ...int) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.base+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query usbtree daemon: %w", err)
+	}
+	for _, status := range accepted {
+		if resp.StatusCode == status {
+			return resp, nil
+		}
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		Error string `json:"error"`
+	}
+	if json.NewDecoder(resp.Body).Decode(&body) == nil && body.Error != "" {
+		return nil, fmt.Errorf("usbtree daemon: %s", body.Error)
+	}
+	return nil, fmt.Errorf("usbtree daemon: %s", resp.Status)
+}
+
+func (c *Client) getJSON(ctx context.Context, path string, v any, accepted ...int) error {
+	resp, err := c.get(ctx, path, accepted...)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to decode response of %s: %w", path, err)
+	}
+	return nil
+}
+
+// Report returns the devices as the versioned report of usbtree --json.
+func (c *Client) Report(ctx context.Context) (*Report, error) {
+	var report Report
+	if err := c.getJSON(ctx, "/devices", &report, http.StatusOK); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// Devices returns the device tree of the daemon.
+func (c *Client) Devices(ctx context.Context) (*Topology, error) {
+	report, err := c.Report(ctx)
+	if err != nil {
+		return nil, err
+	}
+	topology := NewTopology(report.Devices)
+	topology.Backend = report.Backend
+	return topology, nil
+}
+
+// Device returns the device at a port path such as 1-2.3, with the devices
+// connected to it, or ErrNoDevice.
+func (c *Client) Device(ctx context.Context, portPath string) (*Device, error) {
+	resp, err := c.get(ctx, "/devices/"+url.PathEscape(portPath), http.StatusOK, http.StatusNotFound)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("%w %s", ErrNoDevice, portPath)
+	}
+
+	var device Device
+	if err := json.NewDecoder(resp.Body).Decode(&device); err != nil {
+		return nil, fmt.Errorf("failed to decode device %s: %w", portPath, err)
+	}
+	// Link the children to the device
+	NewTopology([]*Device{&device})
+	return &device, nil
+}
+
+// Health returns the state of the daemon. A daemon that is starting or
+// failed to enumerate is reported in Health.Status, not as an error.
+func (c *Client) Health(ctx context.Context) (*Health, error) {
+	var health Health
+	if err := c.getJSON(ctx, "/health", &health, http.StatusOK, http.StatusServiceUnavailable); err != nil {
+		return nil, err
+	}
+	return &health, nil
+}
+
+// Events calls fn for every hotplug event the daemon reports until ctx is
+// done, fn returns an error or the daemon closes the stream. It returns nil
+// when ctx is done and an error otherwise.
+func (c *Client) Events(ctx context.Context, fn func(Event) error) error {
+	resp, err := c.get(ctx, "/events", http.StatusOK)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	err = readEvents(resp.Body, fn)
+	if ctx.Err() != nil {
+		return nil
+	}
+	return err
+}
+
+// readEvents parses a server-sent event stream, passing the data of every
+// event to fn.
+func readEvents(r io.Reader, fn func(Event) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+
+	var data []string
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			if len(data) == 0 {
+				continue
+			}
+			var event Event
+			if err := json.Unmarshal([]byte(strings.Join(data, "\n")), &event); err != nil {
+				return fmt.Errorf("failed to decode event: %w", err)
+			}
+			data = nil
+			if err := fn(event); err != nil {
+				return err
+			}
+		case strings.HasPrefix(line, "data:"):
+			data = append(data, strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read events: %w", err)
+	}
+	return io.ErrUnexpectedEOF
+}
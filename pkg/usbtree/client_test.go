@@ -0,0 +1,100 @@
+package usbtree
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stegmannb/usbtree/internal/api"
+	"github.com/stegmannb/usbtree/internal/hotplug"
+)
+
+func TestClient(t *testing.T) {
+	topology := newTestTopology()
+	topology.Backend = "test"
+	server := api.NewServer("")
+	server.Update(hotplug.Update{Time: time.Now(), Topology: topology})
+
+	address := "unix:" + filepath.Join(t.TempDir(), "usbtree.sock")
+	listener, err := api.Listen(address)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	httpServer := &http.Server{Handler: server.Handler()}
+	go httpServer.Serve(listener)
+	defer httpServer.Close()
+
+	client, err := NewClient(address)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	ctx := context.Background()
+
+	devices, err := client.Devices(ctx)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	ftdi := devices.FindBySerial("A50285BI")
+	if devices.Backend != "test" || len(ftdi) != 1 || ftdi[0].Parent == nil {
+		t.Fatalf("Unexpected devices %+v", devices)
+	}
+
+	device, err := client.Device(ctx, ftdi[0].PortPath)
+	if err != nil || device.Serial != "A50285BI" {
+		t.Errorf("Unexpected device %+v, error %v", device, err)
+	}
+	if _, err := client.Device(ctx, "9-9"); !errors.Is(err, ErrNoDevice) {
+		t.Errorf("Expected ErrNoDevice, got %v", err)
+	}
+
+	health, err := client.Health(ctx)
+	if err != nil || health.Status != "ok" {
+		t.Errorf("Unexpected health %+v, error %v", health, err)
+	}
+
+	// Events arrive until the context is done
+	events, cancel := context.WithCancel(ctx)
+	received := make(chan Event, 1)
+	done := make(chan error, 1)
+	go func() {
+		done <- client.Events(events, func(event Event) error {
+			select {
+			case received <- event:
+			default:
+			}
+			return nil
+		})
+	}()
+	after := newTestTopology()
+	for {
+		// Events sent before the stream subscribed are not replayed, so
+		// keep sending until one arrives
+		server.Update(hotplug.Update{Time: time.Now(), Topology: after, Events: hotplug.Diff(nil, after, time.Now())[:1]})
+		select {
+		case event := <-received:
+			if event.Action != EventAdd {
+				t.Errorf("Unexpected event %+v", event)
+			}
+			cancel()
+			if err := <-done; err != nil {
+				t.Errorf("Expected nil after cancel, got %v", err)
+			}
+			return
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
+func TestNewClient(t *testing.T) {
+	for _, address := range []string{"localhost:8080", "http://localhost:8080/", "unix:/run/usbtree.sock"} {
+		if _, err := NewClient(address); err != nil {
+			t.Errorf("NewClient(%q): unexpected error %v", address, err)
+		}
+	}
+	if _, err := NewClient("unix:"); err == nil {
+		t.Error("Expected error for empty socket path")
+	}
+}
@@ -0,0 +1,113 @@
+package usbtree
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/stegmannb/usbtree/internal/models"
+	"github.com/stegmannb/usbtree/internal/render"
+	"github.com/stegmannb/usbtree/internal/tree"
+	"github.com/stegmannb/usbtree/internal/usb"
+)
+
+// TreeOptions controls RenderTree.
+type TreeOptions struct {
+	// Verbose adds serial, speed, power and interface details.
+	Verbose bool
+	// Color enables ANSI colors.
+	Color bool
+}
+
+// RenderTree writes the tree view printed by the usbtree command to w.
+func RenderTree(w io.Writer, t *Topology, opts TreeOptions) error {
+	printer := tree.NewPrinter(opts.Verbose)
+	printer.SetColor(opts.Color)
+
+	// The printer does not report write errors, so they are caught here
+	ew := &errWriter{w: w}
+	printer.Fprint(ew, t.Devices)
+	return ew.err
+}
+
+// RenderJSON writes the topology as an indented Report to w.
+func RenderJSON(w io.Writer, t *Topology, info ReportInfo) error {
+	return encodeJSON(w, Report{ReportInfo: info, Devices: nonNil(t.Devices)})
+}
+
+// RenderJSONFlat writes the topology as an indented FlatReport to w.
+func RenderJSONFlat(w io.Writer, t *Topology, info ReportInfo) error {
+	return encodeJSON(w, FlatReport{ReportInfo: info, Devices: nonNil(models.Flatten(t))})
+}
+
+// Report formats accepted by RenderReport.
+const (
+	FormatJSON = render.FormatJSON
+	FormatYAML = render.FormatYAML
+	FormatXML  = render.FormatXML
+	FormatTOML = render.FormatTOML
+	FormatHTML = render.FormatHTML
+	FormatSVG  = render.FormatSVG
+)
+
+// RenderReport writes the topology as a Report, or a FlatReport when flat
+// is set, in one of the Format constants. All formats but HTML and SVG use
+// the JSON field names and nesting; HTML is a self-contained page with the
+// device tree, device details, power totals and health checks, and SVG a
+// topology diagram. Both ignore flat, and SVG also info.
+func RenderReport(w io.Writer, format string, t *Topology, info ReportInfo, flat bool) error {
+	switch format {
+	case FormatHTML:
+		return render.WriteHTML(w, info, t)
+	case FormatSVG:
+		return render.WriteSVG(w, t)
+	}
+	if flat {
+		return render.Write(w, format, FlatReport{ReportInfo: info, Devices: nonNil(models.Flatten(t))})
+	}
+	return render.Write(w, format, Report{ReportInfo: info, Devices: nonNil(t.Devices)})
+}
+
+func encodeJSON(w io.Writer, v any) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+// nonNil makes empty device lists encode as [] rather than null.
+func nonNil[T any](devices []T) []T {
+	if devices == nil {
+		return []T{}
+	}
+	return devices
+}
+
+// JSONSchema returns the JSON Schema of Report, or of FlatReport when flat
+// is set.
+func JSONSchema(flat bool) ([]byte, error) {
+	return models.ReportSchema(flat)
+}
+
+// DecodeJSON reads a topology written by RenderJSON, RenderJSONFlat or
+// usbtree --json.
+func DecodeJSON(r io.Reader) (*Topology, error) {
+	devices, err := usb.ParseInput(r, usb.InputJSON)
+	if err != nil {
+		return nil, err
+	}
+	return NewTopology(devices), nil
+}
+
+// errWriter remembers the first write error and drops all later writes.
+type errWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (ew *errWriter) Write(p []byte) (int, error) {
+	if ew.err != nil {
+		return 0, ew.err
+	}
+	n, err := ew.w.Write(p)
+	ew.err = err
+	return n, err
+}
@@ -0,0 +1,21 @@
+package usbtree
+
+import "github.com/stegmannb/usbtree/internal/models"
+
+type (
+	// Topology owns a device tree and links every device to its parent.
+	// Lookups return devices in depth-first order, parents first.
+	Topology = models.Topology
+	// WalkFunc is called for every device visited by Topology.Walk.
+	WalkFunc = models.WalkFunc
+)
+
+// SkipChildren is returned by a WalkFunc to skip the children of the
+// current device.
+var SkipChildren = models.SkipChildren
+
+// NewTopology takes ownership of the tree below the given root hubs and
+// sets Parent and Depth of every device in it.
+func NewTopology(devices []*Device) *Topology {
+	return models.NewTopology(devices)
+}
@@ -0,0 +1,119 @@
+package usbtree
+
+import (
+	"errors"
+	"testing"
+)
+
+func newTestTopology() *Topology {
+	return NewTopology([]*Device{
+		{VendorID: 0x1d6b, ProductID: 0x0003, PortPath: "usb2"},
+		{
+			VendorID:  0x1d6b,
+			ProductID: 0x0002,
+			PortPath:  "usb1",
+			Children: []*Device{
+				{
+					VendorID:    0x05e3,
+					ProductID:   0x0610,
+					ProductName: "Hub",
+					PortPath:    "1-2",
+					Children: []*Device{
+						{VendorID: 0x046d, ProductID: 0xc52b, ProductName: "Unifying Receiver", PortPath: "1-2.1"},
+						{VendorID: 0x0403, ProductID: 0x6001, ProductName: "FT232R", PortPath: "1-2.3", Serial: "A50285BI"},
+					},
+				},
+				{VendorID: 0x0403, ProductID: 0x6001, ProductName: "FT232R", PortPath: "1-4", Serial: "FTB6SPL3"},
+			},
+		},
+	})
+}
+
+func TestTopology_Parent(t *testing.T) {
+	topology := newTestTopology()
+
+	ftdi := topology.FindByPortPath("1-2.3")
+	if ftdi == nil {
+		t.Fatal("Expected device at 1-2.3")
+	}
+
+	hub := topology.Parent(ftdi)
+	if hub == nil || hub.PortPath != "1-2" {
+		t.Fatalf("Expected hub 1-2 as parent, got %+v", hub)
+	}
+	if root := topology.Parent(hub); root == nil || root.PortPath != "usb1" {
+		t.Errorf("Expected usb1 as parent of the hub, got %+v", root)
+	}
+	if parent := topology.Parent(topology.Devices[0]); parent != nil {
+		t.Errorf("Expected no parent for root hub, got %+v", parent)
+	}
+}
+
+func TestTopology_Walk(t *testing.T) {
+	topology := newTestTopology()
+
+	var visited []string
+	var depths []int
+	topology.Walk(func(device *Device) error {
+		visited = append(visited, device.PortPath)
+		depths = append(depths, device.Depth)
+		return nil
+	})
+
+	expected := []string{"usb2", "usb1", "1-2", "1-2.1", "1-2.3", "1-4"}
+	if len(visited) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, visited)
+	}
+	for i := range expected {
+		if visited[i] != expected[i] {
+			t.Errorf("Expected %v, got %v", expected, visited)
+			break
+		}
+	}
+	if depths[4] != 2 {
+		t.Errorf("Expected depth 2 for 1-2.3, got %d", depths[4])
+	}
+
+	visited = nil
+	topology.Walk(func(device *Device) error {
+		visited = append(visited, device.PortPath)
+		if device.PortPath == "1-2" {
+			return SkipChildren
+		}
+		return nil
+	})
+	if len(visited) != 4 {
+		t.Errorf("Expected the hub's children to be skipped, got %v", visited)
+	}
+
+	stop := errors.New("stop")
+	if err := topology.Walk(func(device *Device) error { return stop }); err != stop {
+		t.Errorf("Expected Walk to return the callback's error, got %v", err)
+	}
+}
+
+func TestTopology_Find(t *testing.T) {
+	topology := newTestTopology()
+
+	if found := topology.FindByID(0x0403, 0x6001); len(found) != 2 {
+		t.Errorf("Expected 2 devices for 0403:6001, got %d", len(found))
+	}
+	if found := topology.FindBySerial("FTB6SPL3"); len(found) != 1 || found[0].PortPath != "1-4" {
+		t.Errorf("Unexpected devices for serial: %+v", found)
+	}
+	if device := topology.FindByPortPath("3-1"); device != nil {
+		t.Errorf("Expected no device at 3-1, got %+v", device)
+	}
+
+	found, err := topology.Select("name=receiver")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(found) != 1 || found[0].PortPath != "1-2.1" {
+		t.Errorf("Unexpected devices for name selector: %+v", found)
+	}
+
+	if _, err := topology.Select("color=red"); err == nil {
+		t.Error("Expected error for invalid selector")
+	}
+}
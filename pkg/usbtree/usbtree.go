@@ -0,0 +1,107 @@
+// Package usbtree enumerates the USB devices of this machine, or reads them
+// from saved tool output, and renders them the way the usbtree command does.
+//
+// The device types are the ones written by usbtree --json. Their JSON form
+// is versioned by SchemaVersion.
+//
+// # Compatibility
+//
+// Device, Topology and the report types are those the usbtree command works
+// with. Kept stable are the fields written to JSON, which only change with
+// SchemaVersion, the Parent and Depth links set by NewTopology, and the
+// methods of Device and Topology. The fields left out of the JSON for the
+// views of the command, Device.Via and Device.LabelColor, are not covered:
+// they are unset on every device this package returns and may change or go
+// away in any release.
+package usbtree
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/stegmannb/usbtree/internal/models"
+	"github.com/stegmannb/usbtree/internal/usb"
+)
+
+type (
+	// Device is a USB device with its children. Its Via and LabelColor
+	// fields are internal to the usbtree command and not part of the
+	// stable API; see the package documentation.
+	Device = models.USBDevice
+	// Interface is one interface of a device's active configuration.
+	Interface = models.Interface
+	// PowerState is the runtime power management state of a device (Linux).
+	PowerState = models.PowerState
+	// Report is the versioned JSON document written by RenderJSON.
+	Report = models.Report
+	// FlatReport is the versioned JSON document written by RenderJSONFlat.
+	FlatReport = models.FlatReport
+	// FlatDevice is a device of a FlatReport, linked to its parent by ID.
+	FlatDevice = models.FlatDevice
+	// ReportInfo describes where and when a report was generated.
+	ReportInfo = models.ReportInfo
+)
+
+// SchemaVersion is the version of the JSON written by RenderJSON. It is
+// bumped whenever a field is renamed or removed or changes its meaning.
+const SchemaVersion = models.SchemaVersion
+
+// Input formats for Options.InputFormat.
+const (
+	InputAuto           = usb.InputAuto
+	InputJSON           = usb.InputJSON
+	InputLsusb          = usb.InputLsusb
+	InputLsusbTree      = usb.InputLsusbTree
+	InputLsusbVerbose   = usb.InputLsusbVerbose
+	InputSystemProfiler = usb.InputSystemProfiler
+	InputBundle         = usb.InputBundle
+)
+
+// Options controls where Enumerate reads devices from.
+type Options struct {
+	// Input is a file with saved output of usbtree --json, lsusb, lsusb -t,
+	// lsusb -v or system_profiler, a capture bundle, or an unpacked bundle
+	// directory. When empty, the devices of this machine are enumerated.
+	Input string
+	// InputFormat is the format of Input; empty means InputAuto.
+	InputFormat string
+}
+
+// NewReportInfo describes a report generated now by the given backend on
+// the given host, usually NewReportInfo(topology.Backend, hostname).
+func NewReportInfo(backend, hostname string) ReportInfo {
+	return models.NewReportInfo(backend, hostname)
+}
+
+// Enumerate reads the device tree. Detection runs external tools such as
+// lsusb; when ctx is done first, they are killed and Enumerate returns
+// ctx.Err().
+func Enumerate(ctx context.Context, opts Options) (*Topology, error) {
+	detector := usb.NewDetectorContext(ctx)
+	if opts.Input != "" {
+		format := opts.InputFormat
+		if format == "" {
+			format = usb.InputAuto
+		}
+		if !slices.Contains(usb.InputFormats, format) {
+			return nil, fmt.Errorf("unknown input format %q", format)
+		}
+		detector = usb.NewFileDetector(opts.Input, format)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	devices, err := detector.GetDevices()
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return nil, ctxErr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get USB devices: %w", err)
+	}
+	topology := NewTopology(devices)
+	topology.Backend = detector.Backend()
+	return topology, nil
+}
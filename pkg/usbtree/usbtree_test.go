@@ -0,0 +1,155 @@
+package usbtree
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var testInput = filepath.Join("..", "..", "internal", "usb", "testdata", "lsusb-t.txt")
+
+func TestEnumerate(t *testing.T) {
+	topology, err := Enumerate(context.Background(), Options{Input: testInput})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(topology.Devices) != 2 {
+		t.Fatalf("Expected 2 root hubs, got %d", len(topology.Devices))
+	}
+
+	ftdi := topology.FindByPortPath("1-2.3")
+	if ftdi == nil || len(ftdi.Interfaces) != 1 || ftdi.Interfaces[0].Driver != "ftdi_sio" {
+		t.Fatalf("Expected device at 1-2.3, got %+v", ftdi)
+	}
+	if hub := topology.Parent(ftdi); hub == nil || hub.PortPath != "1-2" {
+		t.Errorf("Expected hub 1-2 as parent, got %+v", hub)
+	}
+	if topology.Backend != "file" {
+		t.Errorf("Expected file backend, got %q", topology.Backend)
+	}
+	// The fields outside the stable API stay unset
+	for _, device := range topology.All() {
+		if device.Via != nil || device.LabelColor != "" {
+			t.Errorf("Expected no view fields on %s", device.PortPath)
+		}
+	}
+}
+
+func TestEnumerate_Errors(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := Enumerate(ctx, Options{Input: testInput}); err != context.Canceled {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+
+	if _, err := Enumerate(context.Background(), Options{Input: testInput, InputFormat: "usbview"}); err == nil {
+		t.Error("Expected error for unknown input format")
+	}
+	if _, err := Enumerate(context.Background(), Options{Input: "missing.txt"}); err == nil {
+		t.Error("Expected error for missing input")
+	}
+}
+
+func TestRenderTree(t *testing.T) {
+	var buf bytes.Buffer
+	if err := RenderTree(&buf, newTestTopology(), TreeOptions{Verbose: true}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	output := buf.String()
+	for _, want := range []string{"    ├── Hub [05e3:0610]", "    │   └── FT232R [0403:6001]", "Serial: A50285BI"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected %q in output:\n%s", want, output)
+		}
+	}
+	if strings.Contains(output, "\x1b[") {
+		t.Error("Expected no colors without TreeOptions.Color")
+	}
+}
+
+func TestRenderJSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := RenderJSON(&buf, newTestTopology(), NewReportInfo("test", "lab")); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var report Report
+	if err := json.Unmarshal(buf.Bytes(), &report); err != nil {
+		t.Fatalf("Invalid JSON: %v", err)
+	}
+	if report.SchemaVersion != SchemaVersion || report.Backend != "test" || report.Hostname != "lab" || len(report.Devices) != 2 {
+		t.Errorf("Unexpected report: %+v", report)
+	}
+
+	topology, err := DecodeJSON(&buf)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	ftdi := topology.FindByPortPath("1-2.3")
+	if ftdi == nil || ftdi.Serial != "A50285BI" || topology.Parent(ftdi) == nil {
+		t.Errorf("Expected decoded topology with parent links, got %+v", ftdi)
+	}
+}
+
+func TestRenderJSONFlat(t *testing.T) {
+	var buf bytes.Buffer
+	if err := RenderJSONFlat(&buf, newTestTopology(), NewReportInfo("test", "")); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var report FlatReport
+	if err := json.Unmarshal(buf.Bytes(), &report); err != nil {
+		t.Fatalf("Invalid JSON: %v", err)
+	}
+	if len(report.Devices) != 6 || report.Devices[2].PortPath != "1-2" || *report.Devices[2].ParentID != 2 {
+		t.Errorf("Unexpected flat report: %+v", report.Devices)
+	}
+
+	topology, err := DecodeJSON(&buf)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if ftdi := topology.FindByPortPath("1-2.3"); ftdi == nil || ftdi.Parent == nil || ftdi.Parent.PortPath != "1-2" {
+		t.Errorf("Expected decoded flat topology with parent links, got %+v", ftdi)
+	}
+
+	buf.Reset()
+	RenderJSON(&buf, NewTopology(nil), NewReportInfo("test", ""))
+	if !strings.Contains(buf.String(), `"devices": []`) {
+		t.Errorf("Expected empty device list, got %s", buf.String())
+	}
+}
+
+func TestRenderReport(t *testing.T) {
+	var buf bytes.Buffer
+	if err := RenderReport(&buf, FormatYAML, newTestTopology(), NewReportInfo("test", ""), false); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(buf.String(), "schema_version: 1\n") || !strings.Contains(buf.String(), "serial: A50285BI") {
+		t.Errorf("Unexpected YAML:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	if err := RenderReport(&buf, FormatXML, newTestTopology(), NewReportInfo("test", ""), true); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(buf.String(), "<parent_id>2</parent_id>") {
+		t.Errorf("Unexpected flat XML:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	if err := RenderReport(&buf, FormatHTML, newTestTopology(), NewReportInfo("test", ""), false); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(buf.String(), "<!DOCTYPE html>") || !strings.Contains(buf.String(), "A50285BI") {
+		t.Errorf("Unexpected HTML:\n%s", buf.String())
+	}
+
+	if err := RenderReport(&buf, "csv", newTestTopology(), NewReportInfo("test", ""), false); err == nil {
+		t.Error("Expected error for unknown format")
+	}
+}